@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/clients/ai/providers"
+	"github.com/maxaizer/hh-parser/internal/config"
+	"github.com/maxaizer/hh-parser/internal/evaluation"
+	"github.com/maxaizer/hh-parser/internal/services"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.GetAI()
+
+	datasetPath := flag.String("dataset", "", "path to labeled dataset (JSON)")
+	provider := flag.String("provider", cfg.AiProvider, "AI provider")
+	baseURL := flag.String("base-url", cfg.AiBaseURL, "base URL for OpenAI-compatible provider")
+	model := flag.String("model", cfg.AiModel, "AI model")
+	matchPrompt := flag.String("match-prompt", cfg.AiMatchPromptPath, "path to match prompt template")
+	batchPrompt := flag.String("batch-prompt", cfg.AiBatchPromptPath, "path to batch match prompt template")
+	flag.Parse()
+
+	if *datasetPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cases, err := evaluation.LoadDataset(*datasetPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	prompts, err := services.LoadPromptTemplates(*matchPrompt, *batchPrompt)
+	if err != nil {
+		log.Fatalf("can't load AI prompts: %v", err)
+	}
+
+	aiClient, err := providers.NewRegistry().NewClient(ctx, ai.ProviderConfig{
+		Provider:             *provider,
+		BaseURL:              *baseURL,
+		APIKey:               cfg.AIKey,
+		Model:                *model,
+		MaxRequestsPerMinute: cfg.AiMaxRequestsPerMinute,
+		MaxRequestsPerDay:    cfg.AiMaxRequestsPerDay,
+	})
+	if err != nil {
+		log.Fatalf("can't create AI client: %v", err)
+	}
+
+	log.Infof("evaluating %d cases with %s/%s, prompt %s", len(cases), *provider, *model, prompts.Match.Version())
+
+	report := evaluation.Evaluate(ctx, services.NewAIService(aiClient, prompts), cases)
+	if err = report.Write(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"github.com/asaskevich/EventBus"
 	"github.com/maxaizer/hh-parser/internal/bot"
-	"github.com/maxaizer/hh-parser/internal/clients/gemini"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/clients/ai/providers"
 	"github.com/maxaizer/hh-parser/internal/clients/hh"
 	"github.com/maxaizer/hh-parser/internal/config"
 	"github.com/maxaizer/hh-parser/internal/logger"
@@ -14,6 +15,8 @@ import (
 	log "github.com/sirupsen/logrus"
 	"os/signal"
 	"syscall"
+	"time"
+	_ "time/tzdata"
 )
 
 func setupLogger(cfg *config.Config) {
@@ -28,26 +31,131 @@ func setupLogger(cfg *config.Config) {
 	})
 }
 
-func runAnalyzer(ctx context.Context, cfg *config.Config, vacancies *repositories.Vacancies,
-	searches *repositories.Searches, bus EventBus.Bus) {
+func newAIClient(ctx context.Context, cfg *config.Config, quota *repositories.AIQuota) ai.Client {
 
-	aiClient, err := gemini.NewClient(ctx, cfg.AIKey, cfg.AiModel)
+	quotaLocation, err := time.LoadLocation(cfg.AiQuotaTimezone)
+	if err != nil {
+		log.Fatalf("invalid AI quota timezone: %v", err)
+	}
+
+	aiConfigs := []ai.ProviderConfig{{
+		Provider:             cfg.AiProvider,
+		BaseURL:              cfg.AiBaseURL,
+		APIKey:               cfg.AIKey,
+		Model:                cfg.AiModel,
+		EmbeddingModel:       cfg.AiEmbeddingModel,
+		MaxRequestsPerMinute: cfg.AiMaxRequestsPerMinute,
+		MaxRequestsPerDay:    cfg.AiMaxRequestsPerDay,
+		QuotaStore:           quota,
+		QuotaLocation:        quotaLocation,
+	}}
+	for _, fallback := range cfg.AiFallbacks {
+		apiKey := fallback.APIKey
+		if apiKey == "" && fallback.Provider == cfg.AiProvider {
+			apiKey = cfg.AIKey
+		}
+		aiConfigs = append(aiConfigs, ai.ProviderConfig{
+			Provider:             fallback.Provider,
+			BaseURL:              fallback.BaseURL,
+			APIKey:               apiKey,
+			Model:                fallback.Model,
+			MaxRequestsPerMinute: fallback.MaxRequestsPerMinute,
+			MaxRequestsPerDay:    fallback.MaxRequestsPerDay,
+			QuotaStore:           quota,
+			QuotaLocation:        quotaLocation,
+		})
+	}
+
+	aiClient, err := providers.NewRegistry().NewFallbackClient(ctx, aiConfigs)
 	if err != nil {
 		log.Fatalf("can't create AI service: %v", err)
 	}
-	aiClient.SetMinuteRateLimit(cfg.AiMaxRequestsPerMinute)
-	aiClient.SetDayRateLimit(cfg.AiMaxRequestsPerDay)
+	return aiClient
+}
+
+func newAIService(cfg *config.Config, aiClient ai.Client, feedback *repositories.Feedback,
+	usage *repositories.Usage) *services.AIService {
+
+	prompts, err := services.LoadPromptTemplates(cfg.AiMatchPromptPath, cfg.AiBatchPromptPath)
+	if err != nil {
+		log.Fatalf("can't load AI prompts: %v", err)
+	}
+	log.Infof("using AI prompts %s and %s", prompts.Match.Version(), prompts.BatchMatch.Version())
+
+	aiService := services.NewAIService(aiClient, prompts)
+	aiService.WithFeedbackExamples(feedback, cfg.AiFeedbackExamples)
+	aiService.WithUsageRecorder(usage)
+
+	if cfg.AiWishClarification {
+		clarifyPrompt, err := services.LoadClarifyWishPromptTemplate(cfg.AiClarifyPromptPath)
+		if err != nil {
+			log.Fatalf("can't load AI prompts: %v", err)
+		}
+		aiService.WithWishClarification(clarifyPrompt)
+	}
+	return aiService
+}
+
+func newHHClient(cfg *config.Config) *hh.Client {
 
 	hhClient := hh.NewClient()
 	hhClient.SetRateLimit(cfg.HhMaxRequestsPerSecond)
+	hhClient.SetUserAgent(cfg.HhUserAgent)
+	if cfg.HhClientID != "" {
+		hhClient.SetClientCredentials(cfg.HhClientID, cfg.HhClientSecret)
+	} else if cfg.HhAppToken != "" {
+		hhClient.SetAppToken(cfg.HhAppToken)
+	}
+	return hhClient
+}
+
+func newHHDictionaries(ctx context.Context, cfg *config.Config, hhClient *hh.Client,
+	repo *repositories.Dictionaries) *services.HHDictionaries {
+
+	dictionaries := services.NewHHDictionaries(hhClient, repo)
+	if err := dictionaries.Load(ctx); err != nil {
+		log.Errorf("can't load hh dictionaries, defaults are used: %v", err)
+	}
+	if err := dictionaries.RunSync(cfg.HhDictionariesInterval); err != nil {
+		log.Fatalf("can't run hh dictionaries sync: %v", err)
+	}
+	return dictionaries
+}
+
+func newHHRetriever(cfg *config.Config, hhClient *hh.Client, employers *repositories.Employers,
+	dictionaries *services.HHDictionaries) *services.HHVacanciesRetriever {
 
-	aiService := services.NewAIService(aiClient)
 	retriever := services.NewHHVacanciesRetriever(hhClient)
+	retriever.WithEmployerCache(employers, cfg.HhEmployerCacheTTL)
+	retriever.WithDictionaries(dictionaries)
+	retriever.WithDetailsConcurrency(cfg.HhDetailsConcurrency)
+	if cfg.HhVacancyCacheTTL > 0 {
+		retriever.WithDetailsCache(cfg.HhVacancyCacheTTL)
+	}
+	return retriever
+}
+
+func runAnalyzer(cfg *config.Config, aiClient ai.Client, aiService *services.AIService,
+	retriever *services.HHVacanciesRetriever, vacancies *repositories.Vacancies, searches *repositories.Searches,
+	verdicts *repositories.Verdicts, usage *repositories.Usage, embeddings *repositories.Embeddings,
+	employerLists *repositories.EmployerLists, bus EventBus.Bus) {
 
 	analyzer, err := services.NewVacanciesAnalyzer(bus, aiService, retriever, searches, vacancies, cfg.AnalysisInterval)
 	if err != nil {
 		log.Fatalf("can't create analyzer: %v", err)
 	}
+	analyzer.WithBatching(cfg.AiBatchSize, cfg.AiBatchMaxWait)
+	analyzer.WithVerdictCache(verdicts, cfg.AiVerdictCacheTTL)
+	analyzer.WithUserDailyTokenLimit(usage, cfg.AiUserDailyTokenLimit)
+	analyzer.WithEmployerLists(employerLists)
+
+	if cfg.AiEmbeddingModel != "" {
+		embedder, ok := aiClient.(ai.Embedder)
+		if !ok {
+			log.Fatalf("AI provider %s doesn't support embeddings", cfg.AiProvider)
+		}
+		analyzer.WithSimilarityFilter(services.NewSimilarityFilter(embedder, embeddings, cfg.AiSimilarityThreshold))
+	}
 	go analyzer.Run()
 }
 
@@ -70,7 +178,8 @@ func main() {
 	}
 	defer dbContext.Close()
 
-	err = dbContext.Migrate()
+	hhClient := newHHClient(cfg)
+	err = dbContext.Migrate(hhClient)
 	if err != nil {
 		log.Fatalf("can't migrate db context: %v", err)
 	}
@@ -79,30 +188,58 @@ func main() {
 	regions := repositories.NewCachedRegions(repositories.NewRegionsRepository(dbContext.DB))
 	vacancies := repositories.NewVacanciesRepository(dbContext.DB)
 	data := repositories.NewDataRepository(dbContext.DB)
+	verdicts := repositories.NewVerdictsRepository(dbContext.DB)
+	feedback := repositories.NewFeedbackRepository(dbContext.DB)
+	usage := repositories.NewUsageRepository(dbContext.DB)
+	quota := repositories.NewAIQuotaRepository(dbContext.DB)
+	embeddings := repositories.NewEmbeddingsRepository(dbContext.DB)
+	employers := repositories.NewEmployersRepository(dbContext.DB)
+	employerLists := repositories.NewEmployerListsRepository(dbContext.DB)
+	dictionaries := newHHDictionaries(ctx, cfg, hhClient, repositories.NewDictionariesRepository(dbContext.DB))
+	retriever := newHHRetriever(cfg, hhClient, employers, dictionaries)
+	aiClient := newAIClient(ctx, cfg, quota)
+	aiService := newAIService(cfg, aiClient, feedback, usage)
+
 	//ToDo: separate func to run bot
 	bus := EventBus.New()
 
 	tgbot, err := bot.NewBot(cfg.TgToken, bus, bot.Repositories{
-		Search: searches,
-		Region: regions,
-		Data:   data,
+		Search:        searches,
+		Region:        regions,
+		Data:          data,
+		Feedback:      feedback,
+		Usage:         usage,
+		EmployerLists: employerLists,
 	})
 	if err != nil {
 		log.Fatalf("can't create bot: %v", err)
 	}
+	tgbot.WithAdmins(cfg.AdminIDs)
+	tgbot.WithEmployers(retriever)
+	tgbot.WithDictionaries(dictionaries)
+	if cfg.AiEmbeddingModel != "" {
+		tgbot.WithSimilarityFilter()
+	}
+	if cfg.AiWishClarification {
+		tgbot.WithWishClarifier(aiService)
+	}
 	go tgbot.Run()
 
-	runAnalyzer(ctx, cfg, vacancies, searches, bus)
+	runAnalyzer(cfg, aiClient, aiService, retriever, vacancies, searches, verdicts, usage, embeddings, employerLists, bus)
 
 	cleaner, err := services.NewVacanciesCleaner(vacancies, cfg.VacancyExpirationInDays)
 	if err != nil {
 		log.Fatalf("can't create vacancies cleaner: %v", err)
 	}
+	cleaner.WithVerdictsCleanup(verdicts)
+	cleaner.WithEmbeddingsCleanup(embeddings)
+	cleaner.WithEmployersCleanup(employers)
 
 	<-ctx.Done()
 
 	log.Info("Shutting down services...")
 	tgbot.Stop()
 	cleaner.Stop()
+	dictionaries.Stop()
 	log.Info("Services stopped.")
 }
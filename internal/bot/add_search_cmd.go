@@ -23,14 +23,23 @@ type addSearchCommand struct {
 	experience           models.Experience
 	regionID             string
 	schedules            []models.Schedule
+	workFormats          []string
+	salary               int
+	currency             string
+	onlyWithSalary       bool
 	wish                 string
+	similarityThreshold  *float64
+	strictness           models.Strictness
+	rules                models.SearchRules
+	filters              models.SearchFilters
 	initialSearchPeriod  int
 	finishCallback       func()
 	finalMessageKeyboard *botApi.ReplyKeyboardMarkup
 }
 
 func newAddSearchCommand(api apiInterface, chatID int64, userRepo searchRepository,
-	regionRepo regionRepository) *addSearchCommand {
+	regionRepo regionRepository, clarifier wishClarifier, dictionaries models.Dictionaries,
+	similarityFilter bool) *addSearchCommand {
 
 	cmd := &addSearchCommand{api: api, chatID: chatID, searches: userRepo, regions: regionRepo}
 
@@ -39,7 +48,7 @@ func newAddSearchCommand(api apiInterface, chatID int64, userRepo searchReposito
 		cmd.curHandlerIndex++
 	})
 
-	experience := newExperienceInput(chatID, func(experience models.Experience) {
+	experience := newExperienceInput(chatID, dictionaries, func(experience models.Experience) {
 		cmd.experience = experience
 		cmd.curHandlerIndex++
 	})
@@ -49,18 +58,46 @@ func newAddSearchCommand(api apiInterface, chatID int64, userRepo searchReposito
 		cmd.curHandlerIndex++
 	})
 
-	schedule := newScheduleInput(chatID, func(schedules []models.Schedule) {
+	schedule := newScheduleInput(chatID, dictionaries, func(schedules []models.Schedule, workFormats []string) {
 		cmd.schedules = schedules
+		cmd.workFormats = workFormats
 		cmd.curHandlerIndex++
 	})
 
-	wish := newWishInput(chatID, func(wish string) { cmd.wish = wish; cmd.curHandlerIndex++ })
+	salary := newSalaryInput(chatID, func(input string) {
+		cmd.salary, cmd.currency, _ = parseSalary(input)
+		cmd.curHandlerIndex++
+	})
+
+	onlyWithSalary := newOnlyWithSalaryInput(chatID, func(onlyWithSalary bool) {
+		cmd.onlyWithSalary = onlyWithSalary
+		cmd.curHandlerIndex++
+	})
+
+	wish := newWishInput(api, chatID, clarifier, func(wish string) { cmd.wish = wish; cmd.curHandlerIndex++ })
+	similarityThreshold := newSimilarityThresholdInput(chatID, func(input string) {
+		cmd.similarityThreshold, _ = parseSimilarityThreshold(input)
+		cmd.curHandlerIndex++
+	})
+	strictness := newStrictnessInput(chatID, func(strictness models.Strictness) {
+		cmd.strictness = strictness
+		cmd.curHandlerIndex++
+	})
+	rules := newRulesInput(chatID, func(rules models.SearchRules) { cmd.rules = rules; cmd.curHandlerIndex++ })
+	filters := newFiltersInput(chatID, regionRepo, dictionaries, func(filters models.SearchFilters) {
+		cmd.filters = filters
+		cmd.curHandlerIndex++
+	})
 	initialSearchPeriod := newInitialSearchPeriodInput(chatID, func(input string) {
 		cmd.initialSearchPeriod, _ = strconv.Atoi(input)
 		cmd.curHandlerIndex++
 	})
 
-	cmd.inputHandlers = []inputHandler{keywords, experience, region, schedule, wish, initialSearchPeriod}
+	cmd.inputHandlers = []inputHandler{keywords, experience, region, schedule, salary, onlyWithSalary, wish}
+	if similarityFilter {
+		cmd.inputHandlers = append(cmd.inputHandlers, similarityThreshold)
+	}
+	cmd.inputHandlers = append(cmd.inputHandlers, strictness, rules, filters, initialSearchPeriod)
 	return cmd
 }
 
@@ -81,7 +118,15 @@ func (c *addSearchCommand) SaveState() ([]byte, error) {
 		Experience          models.Experience
 		RegionID            string
 		Schedules           []models.Schedule
+		WorkFormats         []string
+		Salary              int
+		Currency            string
+		OnlyWithSalary      bool
 		Wish                string
+		SimilarityThreshold *float64
+		Strictness          models.Strictness
+		Rules               models.SearchRules
+		Filters             models.SearchFilters
 		InitialSearchPeriod int
 		*Alias
 	}{
@@ -90,7 +135,15 @@ func (c *addSearchCommand) SaveState() ([]byte, error) {
 		Experience:          c.experience,
 		RegionID:            c.regionID,
 		Schedules:           c.schedules,
+		WorkFormats:         c.workFormats,
+		Salary:              c.salary,
+		Currency:            c.currency,
+		OnlyWithSalary:      c.onlyWithSalary,
 		Wish:                c.wish,
+		SimilarityThreshold: c.similarityThreshold,
+		Strictness:          c.strictness,
+		Rules:               c.rules,
+		Filters:             c.filters,
 		InitialSearchPeriod: c.initialSearchPeriod,
 		Alias:               (*Alias)(c),
 	})
@@ -105,7 +158,15 @@ func (c *addSearchCommand) LoadState(data []byte) error {
 		Experience          models.Experience
 		RegionID            string
 		Schedules           []models.Schedule
+		WorkFormats         []string
+		Salary              int
+		Currency            string
+		OnlyWithSalary      bool
 		Wish                string
+		SimilarityThreshold *float64
+		Strictness          models.Strictness
+		Rules               models.SearchRules
+		Filters             models.SearchFilters
 		InitialSearchPeriod int
 		*Alias
 	}{
@@ -121,7 +182,15 @@ func (c *addSearchCommand) LoadState(data []byte) error {
 	c.experience = aux.Experience
 	c.regionID = aux.RegionID
 	c.schedules = aux.Schedules
+	c.workFormats = aux.WorkFormats
+	c.salary = aux.Salary
+	c.currency = aux.Currency
+	c.onlyWithSalary = aux.OnlyWithSalary
 	c.wish = aux.Wish
+	c.similarityThreshold = aux.SimilarityThreshold
+	c.strictness = aux.Strictness
+	c.rules = aux.Rules
+	c.filters = aux.Filters
 	c.initialSearchPeriod = aux.InitialSearchPeriod
 	return nil
 }
@@ -157,6 +226,14 @@ func (c *addSearchCommand) OnUserInput(input string) {
 func (c *addSearchCommand) addSearch() {
 
 	search := models.NewJobSearch(c.chatID, c.searchText, c.regionID, c.experience, c.schedules, c.wish, c.initialSearchPeriod)
+	search.WorkFormats = c.workFormats
+	search.Salary = c.salary
+	search.Currency = c.currency
+	search.OnlyWithSalary = c.onlyWithSalary
+	search.Rules = c.rules
+	search.Filters = c.filters
+	search.Strictness = c.strictness
+	search.SimilarityThreshold = c.similarityThreshold
 	msg := botApi.NewMessage(c.chatID, "")
 	if c.finalMessageKeyboard != nil {
 		msg.ReplyMarkup = c.finalMessageKeyboard
@@ -178,11 +255,6 @@ func newKeywordsInput(chatID int64, onFinish func(input string)) *textInput {
 		"\"Go OR Golang\".", onFinish)
 }
 
-func newWishInput(chatID int64, onFinish func(input string)) *textInput {
-	return newTextInput(chatID, "Укажите пожелания к вакансии в свободной форме.\n"+
-		"Например, \"чтобы соответствовала C# backend разработчику\" или \"хочу вкусняшки в офисе\"", onFinish)
-}
-
 func newInitialSearchPeriodInput(chatID int64, onFinish func(input string)) *textInput {
 	input := newTextInput(chatID, "Укажите, вакансии за сколько последних дней включить в поиск (от 0 до 5)",
 		onFinish)
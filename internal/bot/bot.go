@@ -7,17 +7,49 @@ import (
 	"fmt"
 	"github.com/asaskevich/EventBus"
 	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
 	"github.com/maxaizer/hh-parser/internal/domain/events"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/maxaizer/hh-parser/internal/logger"
+	"github.com/maxaizer/hh-parser/internal/metrics"
 	log "github.com/sirupsen/logrus"
 	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Repositories struct {
-	Search searchRepository
-	Region regionRepository
-	Data   dataRepository
+	Search        searchRepository
+	Region        regionRepository
+	Data          dataRepository
+	Feedback      feedbackRepository
+	Usage         usageReportRepository
+	EmployerLists employerListRepository
+}
+
+type employerListRepository interface {
+	Save(ctx context.Context, entry models.EmployerListEntry) error
+	Remove(ctx context.Context, userID int64, employerID string) (bool, error)
+	GetByUser(ctx context.Context, userID int64) (models.EmployerLists, error)
+}
+
+type employerProvider interface {
+	GetEmployer(ctx context.Context, ID string) (*models.Employer, error)
+}
+
+type dictionaryProvider interface {
+	Get() models.Dictionaries
+}
+
+type usageReportRepository interface {
+	GetReport(ctx context.Context, fromDay string) ([]models.AIUsage, error)
+}
+
+type feedbackRepository interface {
+	Save(ctx context.Context, userID int64, searchID int, vacancyID string, positive bool) error
+	GetByUser(ctx context.Context, userID int64) ([]models.VacancyFeedback, error)
+	RemoveByUser(ctx context.Context, userID int64) (int64, error)
 }
 
 type dataRepository interface {
@@ -35,18 +67,26 @@ type searchRepository interface {
 
 type regionRepository interface {
 	GetIdByName(ctx context.Context, name string) (string, error)
+	GetByID(ctx context.Context, id string) (*models.Region, error)
+	Search(ctx context.Context, name string, limit int) ([]models.Region, error)
 }
 
 type Bot struct {
-	api          *botApi.BotAPI
-	userContexts map[int64]*userContext
-	bus          EventBus.Bus
-	repositories Repositories
+	api              *botApi.BotAPI
+	userContexts     map[int64]*userContext
+	bus              EventBus.Bus
+	repositories     Repositories
+	adminIDs         []int64
+	clarifier        wishClarifier
+	employers        employerProvider
+	dictionaries     dictionaryProvider
+	similarityFilter bool
 }
 
 const backToMenuCommandName = "В главное меню"
 
-var globalCommands = []string{addSearchCommandName, removeSearchCommandName, backToMenuCommandName, editSearchCommandName}
+var globalCommands = []string{addSearchCommandName, removeSearchCommandName, backToMenuCommandName, editSearchCommandName,
+	listFeedbackCommandName, clearFeedbackCommandName, listEmployersCommandName}
 
 func NewBot(token string, bus EventBus.Bus, repositories Repositories) (*Bot, error) {
 
@@ -77,6 +117,18 @@ func NewBot(token string, bus EventBus.Bus, repositories Repositories) (*Bot, er
 		return nil, errors.New("data repository is nil")
 	}
 
+	if repositories.Feedback == nil {
+		return nil, errors.New("feedback repository is nil")
+	}
+
+	if repositories.Usage == nil {
+		return nil, errors.New("usage repository is nil")
+	}
+
+	if repositories.EmployerLists == nil {
+		return nil, errors.New("employer lists repository is nil")
+	}
+
 	createdBot := &Bot{api: api, userContexts: make(map[int64]*userContext), bus: bus, repositories: repositories}
 
 	err = bus.Subscribe(events.VacancyFoundTopic, createdBot.onVacancyFound)
@@ -86,6 +138,29 @@ func NewBot(token string, bus EventBus.Bus, repositories Repositories) (*Bot, er
 	return createdBot, nil
 }
 
+func (b *Bot) WithAdmins(userIDs []int64) {
+	b.adminIDs = userIDs
+}
+
+func (b *Bot) WithWishClarifier(clarifier wishClarifier) {
+	b.clarifier = clarifier
+}
+
+// WithEmployers lets bot show employer names in lists and check ids entered by user
+func (b *Bot) WithEmployers(employers employerProvider) {
+	b.employers = employers
+}
+
+// WithDictionaries makes bot build experience and schedule options from hh.ru dictionaries instead of defaults
+func (b *Bot) WithDictionaries(dictionaries dictionaryProvider) {
+	b.dictionaries = dictionaries
+}
+
+// WithSimilarityFilter lets users set similarity threshold of their searches, it's useless without embeddings
+func (b *Bot) WithSimilarityFilter() {
+	b.similarityFilter = true
+}
+
 func (b *Bot) Run() {
 
 	err := b.loadUserContexts()
@@ -100,6 +175,11 @@ func (b *Bot) Run() {
 
 	for update := range updates {
 
+		if update.CallbackQuery != nil {
+			go b.handleCallback(update.CallbackQuery)
+			continue
+		}
+
 		if update.Message == nil {
 			continue
 		}
@@ -160,6 +240,24 @@ func (b *Bot) handleCommand(user *botApi.User, chat *botApi.Chat, command string
 		} else {
 			ctx.RunCommand(cmd, command)
 		}
+	case usageReportCommandName:
+		if slices.Contains(b.adminIDs, user.ID) {
+			response, err = b.usageReport(chat.ID, args)
+		} else {
+			response = botApi.NewMessage(chat.ID, "Неизвестная команда!")
+		}
+	case listFeedbackCommandName:
+		response, err = b.listFeedback(user.ID, chat.ID)
+	case clearFeedbackCommandName:
+		response, err = b.clearFeedback(user.ID, chat.ID)
+	case listEmployersCommandName:
+		response, err = b.listEmployers(user.ID, chat.ID)
+	case blockEmployerCommandName:
+		response, err = b.addEmployerToList(user.ID, chat.ID, args, models.EmployerBlocked)
+	case allowEmployerCommandName:
+		response, err = b.addEmployerToList(user.ID, chat.ID, args, models.EmployerAllowed)
+	case forgetEmployerCommandName:
+		response, err = b.forgetEmployer(user.ID, chat.ID, args)
 	case backToMenuCommandName:
 		messageResponse := botApi.NewMessage(chat.ID, "Вы были успешно перенесены в главное меню")
 		messageResponse.ReplyMarkup = defaultReplyKeyboard()
@@ -187,13 +285,20 @@ func (b *Bot) handleCommand(user *botApi.User, chat *botApi.Chat, command string
 
 func (b *Bot) createCommand(name string, chatID int64) (command, error) {
 
+	dictionaries := models.DefaultDictionaries
+	if b.dictionaries != nil {
+		dictionaries = b.dictionaries.Get()
+	}
+
 	switch name {
 	case addSearchCommandName:
-		return newAddSearchCommand(b.api, chatID, b.repositories.Search, b.repositories.Region), nil
+		return newAddSearchCommand(b.api, chatID, b.repositories.Search, b.repositories.Region, b.clarifier,
+			dictionaries, b.similarityFilter), nil
 	case removeSearchCommandName:
-		return newRemoveSearchCommand(b.api, chatID, b.bus, b.repositories.Search)
+		return newRemoveSearchCommand(b.api, chatID, b.bus, b.repositories.Search, dictionaries)
 	case editSearchCommandName:
-		return newEditSearchCommand(b.api, chatID, b.bus, b.repositories.Search)
+		return newEditSearchCommand(b.api, chatID, b.bus, b.repositories.Search, b.repositories.Region,
+			b.clarifier, dictionaries, b.similarityFilter)
 	default:
 		return nil, fmt.Errorf("unknown command: %v", name)
 	}
@@ -225,13 +330,200 @@ func (b *Bot) handleInput(user *botApi.User, chat *botApi.Chat, input string) {
 }
 
 func (b *Bot) onVacancyFound(event events.VacancyFound) {
-	msg := botApi.NewMessage(event.Search.UserID,
-		fmt.Sprintf("Найдена подходящая вакансия по поиску \"%v\":\n%v", event.Search.SearchText, event.Url))
+	msg := botApi.NewMessage(event.Search.UserID, vacancyFoundText(event))
+	msg.ReplyMarkup = vacancyKeyboard(event.Search.ID, event.Vacancy)
 	if _, err := b.api.Send(msg); err != nil {
 		log.WithField(logger.ErrorTypeField, logger.ErrorTypeTgApi).Errorf("error occured while sending message: %v", err)
 	}
 }
 
+func (b *Bot) handleCallback(query *botApi.CallbackQuery) {
+
+	var answer string
+	if strings.HasPrefix(query.Data, hideEmployerCallbackPrefix+":") {
+		answer = b.hideEmployer(query)
+	} else {
+		answer = b.saveFeedback(query)
+	}
+
+	_, _ = requestWithLogError(b.api, botApi.NewCallback(query.ID, answer))
+}
+
+func (b *Bot) saveFeedback(query *botApi.CallbackQuery) string {
+
+	answer := "Спасибо за оценку!"
+
+	feedback, err := parseFeedbackCallback(query.Data)
+	if err != nil {
+		log.Warn(err)
+		answer = "Неизвестное действие"
+	} else {
+		err = b.repositories.Feedback.Save(context.Background(), query.From.ID, feedback.SearchID, feedback.VacancyID,
+			feedback.Positive)
+		switch {
+		case errors.Is(err, errs.NotifiedVacancyNotFound):
+			answer = "Вакансия устарела, оценить её уже нельзя"
+		case err != nil:
+			log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to save feedback: %v", err)
+			answer = "Внутренняя ошибка!"
+		default:
+			metrics.FeedbackCounter.WithLabelValues(strconv.FormatBool(feedback.Positive)).Inc()
+			if query.Message != nil {
+				feedbackRow := feedbackKeyboard(feedback.SearchID, feedback.VacancyID, &feedback.Positive).InlineKeyboard[0]
+				markup := replaceKeyboardRow(query.Message.ReplyMarkup, 0, feedbackRow)
+				_, _ = requestWithLogError(b.api,
+					botApi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, markup))
+			}
+		}
+	}
+	return answer
+}
+
+func (b *Bot) hideEmployer(query *botApi.CallbackQuery) string {
+
+	employerID, err := parseHideEmployerCallback(query.Data)
+	if err != nil {
+		log.Warn(err)
+		return "Неизвестное действие"
+	}
+
+	entry := models.EmployerListEntry{UserID: query.From.ID, EmployerID: employerID,
+		EmployerName: b.employerName(employerID), Kind: models.EmployerBlocked}
+
+	if err = b.repositories.EmployerLists.Save(context.Background(), entry); err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to hide employer: %v", err)
+		return "Внутренняя ошибка!"
+	}
+
+	if query.Message != nil && query.Message.ReplyMarkup != nil {
+		if index := keyboardRowIndex(*query.Message.ReplyMarkup, query.Data); index != -1 {
+			hiddenRow := botApi.NewInlineKeyboardRow(
+				botApi.NewInlineKeyboardButtonData(employerHiddenButtonText, query.Data))
+			markup := replaceKeyboardRow(query.Message.ReplyMarkup, index, hiddenRow)
+			_, _ = requestWithLogError(b.api,
+				botApi.NewEditMessageReplyMarkup(query.Message.Chat.ID, query.Message.MessageID, markup))
+		}
+	}
+	return "Вакансии этого работодателя больше не будут приходить"
+}
+
+func (b *Bot) employerName(employerID string) string {
+
+	if b.employers == nil {
+		return ""
+	}
+
+	employer, err := b.employers.GetEmployer(context.Background(), employerID)
+	if err != nil {
+		log.Warnf("failed to get employer %v: %v", employerID, err)
+		return ""
+	}
+	return employer.Name
+}
+
+func (b *Bot) listEmployers(userID int64, chatID int64) (botApi.Chattable, error) {
+
+	lists, err := b.repositories.EmployerLists.GetByUser(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return botApi.NewMessage(chatID, employerListsText(lists)), nil
+}
+
+func (b *Bot) addEmployerToList(userID int64, chatID int64, args string, kind models.EmployerListKind) (botApi.Chattable, error) {
+
+	employerID := strings.TrimSpace(args)
+	if validateEmployerID(employerID) != nil {
+		command := blockEmployerCommandName
+		if kind == models.EmployerAllowed {
+			command = allowEmployerCommandName
+		}
+		return botApi.NewMessage(chatID, "Укажите id работодателя, например: /"+command+" 1740"), nil
+	}
+
+	entry := models.EmployerListEntry{UserID: userID, EmployerID: employerID, Kind: kind}
+	if b.employers != nil {
+		employer, err := b.employers.GetEmployer(context.Background(), employerID)
+		switch {
+		case errors.Is(err, errs.EmployerNotFound):
+			return botApi.NewMessage(chatID, "Работодатель с таким id не найден на hh.ru"), nil
+		case err != nil:
+			log.Warnf("failed to get employer %v: %v", employerID, err)
+		default:
+			entry.EmployerName = employer.Name
+		}
+	}
+
+	if err := b.repositories.EmployerLists.Save(context.Background(), entry); err != nil {
+		return nil, err
+	}
+
+	text := "Работодатель " + employerEntryToText(entry) + " скрыт"
+	if kind == models.EmployerAllowed {
+		text = "Работодатель " + employerEntryToText(entry) + " добавлен в разрешённые. " +
+			"Теперь приходят вакансии только от разрешённых работодателей"
+	}
+	return botApi.NewMessage(chatID, text), nil
+}
+
+func (b *Bot) forgetEmployer(userID int64, chatID int64, args string) (botApi.Chattable, error) {
+
+	employerID := strings.TrimSpace(args)
+	if validateEmployerID(employerID) != nil {
+		return botApi.NewMessage(chatID, "Укажите id работодателя, например: /"+forgetEmployerCommandName+" 1740"), nil
+	}
+
+	removed, err := b.repositories.EmployerLists.Remove(context.Background(), userID, employerID)
+	if err != nil {
+		return nil, err
+	}
+	if !removed {
+		return botApi.NewMessage(chatID, "Этого работодателя нет в ваших списках"), nil
+	}
+	return botApi.NewMessage(chatID, "Работодатель убран из списков"), nil
+}
+
+func (b *Bot) listFeedback(userID int64, chatID int64) (botApi.Chattable, error) {
+
+	feedback, err := b.repositories.Feedback.GetByUser(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	searches, err := b.repositories.Search.GetByUser(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return botApi.NewMessage(chatID, feedbackListText(feedback, searches)), nil
+}
+
+func (b *Bot) usageReport(chatID int64, args string) (botApi.Chattable, error) {
+
+	fromDay, err := usageReportPeriod(args, time.Now())
+	if err != nil {
+		return botApi.NewMessage(chatID, "Укажите количество дней от 1 до 90, например: /usage 7"), nil
+	}
+
+	usage, err := b.repositories.Usage.GetReport(context.Background(), fromDay)
+	if err != nil {
+		return nil, err
+	}
+
+	return botApi.NewMessage(chatID, usageReportText(fromDay, usage)), nil
+}
+
+func (b *Bot) clearFeedback(userID int64, chatID int64) (botApi.Chattable, error) {
+
+	removed, err := b.repositories.Feedback.RemoveByUser(context.Background(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return botApi.NewMessage(chatID, fmt.Sprintf("Удалено оценок: %d", removed)), nil
+}
+
 func (b *Bot) saveUserContexts() error {
 	data, err := json.Marshal(b.userContexts)
 	if err != nil {
@@ -297,6 +589,11 @@ func defaultReplyKeyboard() botApi.ReplyKeyboardMarkup {
 			botApi.NewKeyboardButton(editSearchCommandName),
 			botApi.NewKeyboardButton(removeSearchCommandName),
 		),
+		botApi.NewKeyboardButtonRow(
+			botApi.NewKeyboardButton(listFeedbackCommandName),
+			botApi.NewKeyboardButton(clearFeedbackCommandName),
+			botApi.NewKeyboardButton(listEmployersCommandName),
+		),
 	)
 }
 
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"github.com/asaskevich/EventBus"
 	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
 	"github.com/maxaizer/hh-parser/internal/clients/hh"
 	events2 "github.com/maxaizer/hh-parser/internal/domain/events"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/stretchr/testify/assert"
 	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 type mockSearchRepo struct {
@@ -91,6 +94,25 @@ func (m *mockRegionRepo) GetIdByName(_ context.Context, name string) (string, er
 	return "", errors.New("not found")
 }
 
+func (m *mockRegionRepo) GetByID(_ context.Context, id string) (*models.Region, error) {
+	for _, region := range m.Regions {
+		if region.ID == id {
+			return &region, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockRegionRepo) Search(_ context.Context, name string, limit int) ([]models.Region, error) {
+	var regions []models.Region
+	for _, region := range m.Regions {
+		if strings.HasPrefix(region.NormalizedName, models.NormalizeRegionName(name)) {
+			regions = append(regions, region)
+		}
+	}
+	return regions[:min(limit, len(regions))], nil
+}
+
 func simulateUserInput(cmd command, inputs []string) {
 	for _, input := range inputs {
 		cmd.OnUserInput(input)
@@ -107,16 +129,18 @@ func Test_AddSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 	finished := false
 
 	keywords := "C#"
-	experience := string(noExperience)
+	experience := "Нет опыта"
 	schedule := "0"
 	wish := "Хочу пельмени"
+	rules := "-название: 1С, битрикс\nзарплата от: 100 000"
 	initialSearchPeriod := 1
 
-	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions)
+	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions, nil, models.DefaultDictionaries, true)
 	cmd.WithFinishCallback(func() { finished = true })
 
 	cmd.Run()
-	simulateUserInput(cmd, []string{keywords, experience, region.Name, schedule, wish, strconv.Itoa(initialSearchPeriod)})
+	simulateUserInput(cmd, []string{keywords, experience, region.Name, schedule, "250 000", string(onlyWithSalary),
+		wish, "0,4", string(strictLevel), rules, "0", strconv.Itoa(initialSearchPeriod)})
 
 	assert.True(finished)
 	assert.True(len(mockSearches.Searches) == 1)
@@ -124,6 +148,15 @@ func Test_AddSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 	assert.Equal(region.ID, mockSearches.Searches[0].RegionID)
 	assert.Equal(models.NoExperience, mockSearches.Searches[0].Experience)
 	assert.Equal(wish, mockSearches.Searches[0].UserWish)
+	assert.Equal(250000, mockSearches.Searches[0].Salary)
+	assert.Empty(mockSearches.Searches[0].Currency)
+	assert.True(mockSearches.Searches[0].OnlyWithSalary)
+	if assert.NotNil(mockSearches.Searches[0].SimilarityThreshold) {
+		assert.Equal(0.4, *mockSearches.Searches[0].SimilarityThreshold)
+	}
+	assert.Equal(models.StrictnessStrict, mockSearches.Searches[0].Strictness)
+	assert.Equal(models.SearchRules{NameExclude: []string{"1С", "битрикс"}, MinSalary: 100000},
+		mockSearches.Searches[0].Rules)
 	assert.Equal(initialSearchPeriod, mockSearches.Searches[0].InitialSearchPeriod)
 }
 
@@ -137,12 +170,12 @@ func Test_AddSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 	finished := false
 
 	keywords := "C#"
-	experience := string(noExperience)
+	experience := "Нет опыта"
 	schedule := "0"
 	wish := "Хочу пельмени"
 	initialSearchPeriod := 1
 
-	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions)
+	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions, nil, models.DefaultDictionaries, true)
 	cmd.WithFinishCallback(func() { finished = true })
 
 	cmd.Run()
@@ -150,7 +183,13 @@ func Test_AddSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 	simulateUserInput(cmd, []string{"justRandomExperience", experience})
 	simulateUserInput(cmd, []string{"justRandomRegion", region.Name})
 	simulateUserInput(cmd, []string{"-1", schedule})
+	simulateUserInput(cmd, []string{"много", "0", "3000 долларов", "3000 $"})
+	simulateUserInput(cmd, []string{"да", string(anySalary)})
 	cmd.OnUserInput(wish)
+	simulateUserInput(cmd, []string{"2", defaultSimilarityThresholdInput})
+	simulateUserInput(cmd, []string{"строго", string(lenientLevel)})
+	simulateUserInput(cmd, []string{"зарплата: 100", "зарплата от: много", "+название:", "0"})
+	simulateUserInput(cmd, []string{"работодатели: Яндекс", "работодатели: 1740"})
 	simulateUserInput(cmd, []string{strconv.Itoa(-1), strconv.Itoa(6), strconv.Itoa(initialSearchPeriod)})
 
 	assert.True(finished)
@@ -159,9 +198,130 @@ func Test_AddSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 	assert.Equal(region.ID, mockSearches.Searches[0].RegionID)
 	assert.Equal(models.NoExperience, mockSearches.Searches[0].Experience)
 	assert.Equal(wish, mockSearches.Searches[0].UserWish)
+	assert.Equal(3000, mockSearches.Searches[0].Salary)
+	assert.Equal("USD", mockSearches.Searches[0].Currency)
+	assert.False(mockSearches.Searches[0].OnlyWithSalary)
+	assert.Nil(mockSearches.Searches[0].SimilarityThreshold)
+	assert.Equal(models.StrictnessLenient, mockSearches.Searches[0].Strictness)
+	assert.Equal(models.SearchFilters{EmployerIDs: []string{"1740"}}, mockSearches.Searches[0].Filters)
 	assert.Equal(initialSearchPeriod, mockSearches.Searches[0].InitialSearchPeriod)
 }
 
+func Test_AddSearchCmd_WithHHDictionaries_ShouldOfferTheirValues(t *testing.T) {
+
+	assert := assert.New(t)
+
+	region := models.NewRegion("0", "Москва")
+	mockSearches := &mockSearchRepo{}
+	mockRegions := &mockRegionRepo{Regions: []models.Region{region}}
+	dictionaries := models.DefaultDictionaries.Merge(models.Dictionaries{
+		models.ExperienceDictionary: {{ID: "between1And3", Name: "От 1 года до 3 лет"}},
+		models.WorkFormatDictionary: {{ID: "ON_SITE", Name: "На месте работодателя"}, {ID: "REMOTE", Name: "Удалённо"}},
+	})
+
+	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions, nil, dictionaries, true)
+	cmd.Run()
+	simulateUserInput(cmd, []string{"Go", "Нет опыта", "От 1 года до 3 лет", region.Name, "3", "2",
+		"250 000", string(anySalary), "Хочу пельмени", defaultSimilarityThresholdInput, string(strictLevel), "0", "0", "1"})
+
+	assert.Len(mockSearches.Searches, 1)
+	assert.Equal(models.Experience("between1And3"), mockSearches.Searches[0].Experience)
+	assert.Empty(mockSearches.Searches[0].Schedules)
+	assert.Equal([]string{"REMOTE"}, mockSearches.Searches[0].WorkFormats)
+}
+
+func Test_AddSearchCmd_WithoutSimilarityFilter_ShouldNotAskForThreshold(t *testing.T) {
+
+	assert := assert.New(t)
+
+	region := models.NewRegion("0", "Москва")
+	mockSearches := &mockSearchRepo{}
+	mockRegions := &mockRegionRepo{Regions: []models.Region{region}}
+
+	cmd := newAddSearchCommand(&mockApi{}, 0, mockSearches, mockRegions, nil, models.DefaultDictionaries, false)
+	cmd.Run()
+	simulateUserInput(cmd, []string{"Go", "Нет опыта", region.Name, "0", "250 000", string(anySalary),
+		"Хочу пельмени", string(strictLevel), "0", "0", "1"})
+
+	assert.Len(mockSearches.Searches, 1)
+	assert.Nil(mockSearches.Searches[0].SimilarityThreshold)
+	assert.Equal(models.StrictnessStrict, mockSearches.Searches[0].Strictness)
+}
+
+func Test_EditSearchCmd_WithoutSimilarityFilter_ShouldNotOfferThreshold(t *testing.T) {
+
+	search := models.JobSearch{ID: 0, UserID: 0}
+	mockSearches := &mockSearchRepo{Searches: []models.JobSearch{search}}
+
+	cmd, err := newEditSearchCommand(&mockApi{}, search.UserID, EventBus.New(), mockSearches, &mockRegionRepo{}, nil,
+		models.DefaultDictionaries, false)
+	assert.NoError(t, err)
+
+	cmd.Run()
+	cmd.OnUserInput("1") //select search num
+	cmd.OnUserInput("3") //select changing of strictness, which follows rules without threshold
+	cmd.OnUserInput(string(strictLevel))
+
+	assert.Equal(t, models.StrictnessStrict, mockSearches.Searches[0].Strictness)
+	assert.Nil(t, mockSearches.Searches[0].SimilarityThreshold)
+}
+
+func Test_ParseSearchFilters_ShouldTakeValuesFromHHDictionaries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dictionaries := models.DefaultDictionaries.Merge(models.Dictionaries{
+		models.EmploymentDictionary: {{ID: "full", Name: "Полная занятость"}, {ID: "fly_in_fly_out", Name: "Вахта"}},
+	})
+
+	filters, err := parseSearchFilters(context.Background(), "занятость: вахта\nметки: Мало откликов",
+		&mockRegionRepo{}, dictionaries)
+	assert.NoError(err)
+	assert.Equal([]models.Employment{"fly_in_fly_out"}, filters.Employments)
+	assert.Equal([]models.VacancyLabel{models.LabelLowPerformance}, filters.Labels)
+	assert.Equal("занятость: вахта; метки: мало откликов", filtersToText(filters, dictionaries))
+
+	_, err = parseSearchFilters(context.Background(), "занятость: проектная работа", &mockRegionRepo{}, dictionaries)
+	assert.ErrorContains(err, "допустимые: полная занятость, вахта")
+}
+
+func Test_RegionInput_WhenNameIsAmbiguous_ShouldSuggestRegions(t *testing.T) {
+
+	assert := assert.New(t)
+
+	kirovskLen := models.NewRegion("1", "Кировск")
+	kirovskLen.ParentName = "Ленинградская область"
+	kirovskMur := models.NewRegion("2", "Кировск")
+	kirovskMur.ParentName = "Мурманская область"
+	kirovskWithComma := models.NewRegion("4", "Кировск, Ленинградская область")
+	kirovskWithComma.ParentName = "Россия"
+	regions := &mockRegionRepo{Regions: []models.Region{kirovskLen, kirovskMur, models.NewRegion("3", "Киров"),
+		kirovskWithComma}}
+
+	var regionID string
+	input := newRegionInput(0, regions, func(id string) { regionID = id })
+
+	msg, ok := input.HandleInput("Кировс").(botApi.MessageConfig)
+	assert.True(ok)
+	keyboard := msg.ReplyMarkup.(botApi.ReplyKeyboardMarkup)
+	assert.Equal("Кировск, Ленинградская область #1", keyboard.Keyboard[0][0].Text)
+	assert.Equal("Кировск, Мурманская область #2", keyboard.Keyboard[0][1].Text)
+	assert.Empty(regionID)
+
+	assert.Nil(input.HandleInput(keyboard.Keyboard[0][1].Text))
+	assert.Equal("2", regionID)
+
+	//suggestion is recognized by its id, so names containing separator aren't confused
+	assert.Equal("Кировск, Ленинградская область, Россия #4", keyboard.Keyboard[1][0].Text)
+	assert.Nil(input.HandleInput(keyboard.Keyboard[1][0].Text))
+	assert.Equal("4", regionID)
+
+	//button text is recognized without the input state, so suggestions survive restart
+	input = newRegionInput(0, regions, func(id string) { regionID = id })
+	assert.Nil(input.HandleInput(keyboard.Keyboard[0][0].Text))
+	assert.Equal("1", regionID)
+}
+
 func Test_RemoveSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 
 	assert := assert.New(t)
@@ -173,7 +333,7 @@ func Test_RemoveSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 	_ = mockBus.Subscribe(events2.SearchDeletedTopic, func(event events2.SearchDeleted) { eventPublished = true })
 	finished := false
 
-	cmd, err := newRemoveSearchCommand(&mockApi{}, search.UserID, mockBus, mockSearches)
+	cmd, err := newRemoveSearchCommand(&mockApi{}, search.UserID, mockBus, mockSearches, models.DefaultDictionaries)
 	assert.NoError(err)
 	cmd.WithFinishCallback(func() { finished = true })
 
@@ -193,7 +353,8 @@ func Test_RemoveSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 	mockSearches := &mockSearchRepo{Searches: []models.JobSearch{search}}
 	finished := false
 
-	cmd, err := newRemoveSearchCommand(&mockApi{}, search.UserID, EventBus.New(), mockSearches)
+	cmd, err := newRemoveSearchCommand(&mockApi{}, search.UserID, EventBus.New(), mockSearches,
+		models.DefaultDictionaries)
 	assert.NoError(err)
 	cmd.WithFinishCallback(func() { finished = true })
 
@@ -216,7 +377,9 @@ func Test_EditSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 	_ = mockBus.Subscribe(events2.SearchEditedTopic, func(event events2.SearchEdited) { eventPublished = true })
 	finished := false
 
-	cmd, err := newEditSearchCommand(&mockApi{}, search.UserID, mockBus, mockSearches)
+	mockRegions := &mockRegionRepo{Regions: []models.Region{models.NewRegion("2", "Санкт-Петербург")}}
+	cmd, err := newEditSearchCommand(&mockApi{}, search.UserID, mockBus, mockSearches, mockRegions, nil,
+		models.DefaultDictionaries, true)
 	assert.NoError(err)
 	cmd.WithFinishCallback(func() { finished = true })
 
@@ -237,6 +400,58 @@ func Test_EditSearchCmd_WhenValidData_ShouldBeSuccessful(t *testing.T) {
 
 	assert.False(finished)
 	assert.Equal(newWish, mockSearches.Searches[0].UserWish)
+
+	cmd.OnUserInput("2") //select changing of rules
+	cmd.OnUserInput("+навыки: Go\n-работодатель: 12345")
+
+	assert.False(finished)
+	assert.Equal(models.SearchRules{SkillsInclude: []string{"Go"}, ExcludedEmployers: []string{"12345"}},
+		mockSearches.Searches[0].Rules)
+
+	cmd.OnUserInput("3") //select changing of similarity threshold
+	simulateUserInput(cmd, []string{"1.5", "0,4"})
+
+	assert.False(finished)
+	if assert.NotNil(mockSearches.Searches[0].SimilarityThreshold) {
+		assert.Equal(0.4, *mockSearches.Searches[0].SimilarityThreshold)
+	}
+
+	cmd.OnUserInput("3")
+	cmd.OnUserInput(defaultSimilarityThresholdInput)
+	assert.Nil(mockSearches.Searches[0].SimilarityThreshold)
+
+	cmd.OnUserInput("4") //select changing of strictness
+	simulateUserInput(cmd, []string{"очень строго", string(strictLevel)})
+
+	assert.False(finished)
+	assert.Equal(models.StrictnessStrict, mockSearches.Searches[0].Strictness)
+
+	cmd.OnUserInput("5") //select changing of salary
+	simulateUserInput(cmd, []string{"200 000 руб", string(onlyWithSalary)})
+
+	assert.False(finished)
+	assert.Equal(200000, mockSearches.Searches[0].Salary)
+	assert.Equal(models.CurrencyRUR, mockSearches.Searches[0].Currency)
+	assert.True(mockSearches.Searches[0].OnlyWithSalary)
+
+	cmd.OnUserInput("5")
+	simulateUserInput(cmd, []string{noSalaryInput, string(anySalary)})
+	assert.Zero(mockSearches.Searches[0].Salary)
+	assert.False(mockSearches.Searches[0].OnlyWithSalary)
+
+	cmd.OnUserInput("6") //select changing of advanced filters
+	simulateUserInput(cmd, []string{"регионы: Атлантида", "занятость: удалённая", "роли: программист",
+		"регионы: санкт-петербург, 3\nзанятость: Полная занятость\nискать в: в названии вакансии, company_name\n" +
+			"исключить слова: 1С, битрикс\nметки: без вакансий от кадровых агентств"})
+
+	assert.False(finished)
+	assert.Equal(models.SearchFilters{
+		AreaIDs:      []string{"2", "3"},
+		Employments:  []models.Employment{models.FullEmployment},
+		SearchFields: []models.SearchField{models.SearchFieldName, models.SearchFieldCompanyName},
+		ExcludedText: "1С, битрикс",
+		Labels:       []models.VacancyLabel{models.LabelNotFromAgency},
+	}, mockSearches.Searches[0].Filters)
 }
 
 func Test_EditSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
@@ -247,7 +462,8 @@ func Test_EditSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 	mockSearches := &mockSearchRepo{Searches: []models.JobSearch{search}}
 	finished := false
 
-	cmd, err := newEditSearchCommand(&mockApi{}, search.UserID, EventBus.New(), mockSearches)
+	cmd, err := newEditSearchCommand(&mockApi{}, search.UserID, EventBus.New(), mockSearches, &mockRegionRepo{}, nil,
+		models.DefaultDictionaries, true)
 	assert.NoError(err)
 	cmd.WithFinishCallback(func() { finished = true })
 
@@ -255,9 +471,253 @@ func Test_EditSearchCmd_WhenInvalidInput_ShouldWaitForValid(t *testing.T) {
 
 	cmd.Run()
 	simulateUserInput(cmd, []string{"-1", "2", "1"}) //select search num
-	simulateUserInput(cmd, []string{"-1", "3", "0"}) //select changing of keywords
+	simulateUserInput(cmd, []string{"-1", "7", "0"}) //select changing of keywords
 	cmd.OnUserInput(newKeywords)
 
 	assert.False(finished)
 	assert.Equal(newKeywords, mockSearches.Searches[0].SearchText)
 }
+
+func Test_FeedbackCallback_ShouldRoundTrip(t *testing.T) {
+
+	assert := assert.New(t)
+
+	data := feedbackCallbackData(12, "98765", true)
+	assert.LessOrEqual(len(data), 64) // telegram limit for callback data
+
+	feedback, err := parseFeedbackCallback(data)
+	assert.NoError(err)
+	assert.Equal(feedbackCallback{SearchID: 12, VacancyID: "98765", Positive: true}, feedback)
+
+	feedback, err = parseFeedbackCallback(feedbackCallbackData(12, "98765", false))
+	assert.NoError(err)
+	assert.False(feedback.Positive)
+
+	for _, invalid := range []string{"", "fb:+:12", "fb:?:12:1", "fb:+:abc:1", "xx:+:12:1", "fb:+:12:"} {
+		_, err = parseFeedbackCallback(invalid)
+		assert.Error(err, invalid)
+	}
+}
+
+func Test_VacancyKeyboard_ShouldKeepHideButtonOnFeedback(t *testing.T) {
+
+	assert := assert.New(t)
+
+	keyboard := vacancyKeyboard(12, models.Vacancy{ID: "98765", Employer: models.Employer{ID: "1740"}})
+	assert.Len(keyboard.InlineKeyboard, 2)
+	assert.Equal(0, keyboardRowIndex(keyboard, feedbackCallbackData(12, "98765", true)))
+	assert.Equal(1, keyboardRowIndex(keyboard, hideEmployerCallbackData("1740")))
+	assert.LessOrEqual(len(hideEmployerCallbackData("1740")), 64) // telegram limit for callback data
+
+	selected := true
+	feedbackRow := feedbackKeyboard(12, "98765", &selected).InlineKeyboard[0]
+	updated := replaceKeyboardRow(&keyboard, 0, feedbackRow)
+	assert.Equal(feedbackRow, updated.InlineKeyboard[0])
+	assert.Equal(keyboard.InlineKeyboard[1], updated.InlineKeyboard[1])
+
+	assert.Len(vacancyKeyboard(12, models.Vacancy{ID: "98765"}).InlineKeyboard, 1, "anonymous employer can't be hidden")
+	assert.Len(replaceKeyboardRow(nil, 0, feedbackRow).InlineKeyboard, 1)
+}
+
+func Test_HideEmployerCallback_ShouldParseEmployerID(t *testing.T) {
+
+	employerID, err := parseHideEmployerCallback(hideEmployerCallbackData("1740"))
+	assert.NoError(t, err)
+	assert.Equal(t, "1740", employerID)
+
+	for _, invalid := range []string{"", "hide:", "hide:abc", "fb:+:1:1", "1740"} {
+		_, err = parseHideEmployerCallback(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func Test_EmployerListsText_ShouldShowBothLists(t *testing.T) {
+
+	lists := models.NewEmployerLists([]models.EmployerListEntry{
+		{EmployerID: "1", EmployerName: "Рога и копыта", Kind: models.EmployerBlocked},
+		{EmployerID: "2", Kind: models.EmployerBlocked},
+		{EmployerID: "1740", EmployerName: "Яндекс", Kind: models.EmployerAllowed},
+	})
+
+	expected := "Списки работодателей действуют для всех ваших автопоисков.\n\n" +
+		"Скрытые работодатели:\nРога и копыта (1)\n2\n\n" +
+		"Разрешённые работодатели (вакансии других не присылаются):\nЯндекс (1740)\n\n" + employerCommandsDescription
+	assert.Equal(t, expected, employerListsText(lists))
+
+	assert.Equal(t, "Списки работодателей действуют для всех ваших автопоисков.\n\nСписки пусты.\n\n"+
+		employerCommandsDescription, employerListsText(models.EmployerLists{}))
+}
+
+func Test_FeedbackListText_ShouldGroupBySearch(t *testing.T) {
+
+	searches := []models.JobSearch{{ID: 1, SearchText: "golang"}, {ID: 2, SearchText: "php"}}
+	feedback := []models.VacancyFeedback{
+		{SearchID: 1, VacancyName: "Go разработчик", Positive: true,
+			Verdict: models.MatchVerdict{Summary: models.VacancySummary{Salary: "от 300 000 ₽"}}},
+		{SearchID: 1, VacancyName: "Go тимлид", Positive: false},
+		{SearchID: 2, VacancyName: "PHP разработчик", Positive: false},
+	}
+
+	expected := "Ваши оценки вакансий:\n\n" +
+		"Поиск \"golang\":\n👍 Go разработчик (💰 от 300 000 ₽)\n👎 Go тимлид\n\n" +
+		"Поиск \"php\":\n👎 PHP разработчик"
+	assert.Equal(t, expected, feedbackListText(feedback, searches))
+	assert.Equal(t, "Вы ещё не оценили ни одной вакансии", feedbackListText(nil, searches))
+}
+
+func Test_UsageReport_ShouldSumByUser(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	fromDay, err := usageReportPeriod("7", now)
+	assert.NoError(err)
+	assert.Equal("2025-03-04", fromDay)
+
+	fromDay, err = usageReportPeriod("", now)
+	assert.NoError(err)
+	assert.Equal("2025-03-10", fromDay)
+
+	_, err = usageReportPeriod("0", now)
+	assert.Error(err)
+
+	text := usageReportText(fromDay, []models.AIUsage{
+		{UserID: 1, SearchID: 1, Requests: 2, PromptTokens: 100, CandidateTokens: 10},
+		{UserID: 1, SearchID: 2, Requests: 1, PromptTokens: 50, CandidateTokens: 5},
+		{UserID: 2, SearchID: 3, Requests: 1, PromptTokens: 10, CandidateTokens: 1},
+	})
+	expected := "Расход ИИ с 2025-03-10: 176 токенов (160 на запрос, 16 на ответ), запросов: 4\n\n" +
+		"Пользователь 1: 165 токенов (150 на запрос, 15 на ответ), запросов: 3\n" +
+		"  поиск 1: 110 токенов (100 на запрос, 10 на ответ), запросов: 2\n" +
+		"  поиск 2: 55 токенов (50 на запрос, 5 на ответ), запросов: 1\n\n" +
+		"Пользователь 2: 11 токенов (10 на запрос, 1 на ответ), запросов: 1\n" +
+		"  поиск 3: 11 токенов (10 на запрос, 1 на ответ), запросов: 1"
+	assert.Equal(expected, text)
+}
+
+func Test_VacancyFoundText_ShouldRenderSummaryUnderLink(t *testing.T) {
+
+	event := events2.VacancyFound{
+		Search:  models.JobSearch{SearchText: "golang"},
+		Vacancy: models.Vacancy{Url: "hh.ru/vacancy/1"},
+		Verdict: models.MatchVerdict{
+			Score:      80,
+			Confidence: models.ConfidenceHigh,
+			Summary: models.VacancySummary{
+				Salary:      "от 300 000 ₽",
+				Stack:       []string{"Go", "PostgreSQL"},
+				WorkFormat:  models.WorkFormatRemote,
+				CompanySize: "стартап",
+				RedFlags:    []string{"переработки"},
+			},
+		},
+	}
+
+	expected := "Найдена подходящая вакансия по поиску \"golang\":\nhh.ru/vacancy/1\n\n" +
+		"💰 от 300 000 ₽\n🛠 Go, PostgreSQL\n🏢 удалённо, стартап\n🚩 переработки\n\n" +
+		"Соответствие: 80/100, уверенность высокая"
+	assert.Equal(t, expected, vacancyFoundText(event))
+
+	event.Verdict.Summary = models.VacancySummary{WorkFormat: models.WorkFormatUnknown}
+	assert.Equal(t, "Найдена подходящая вакансия по поиску \"golang\":\nhh.ru/vacancy/1\n\n"+
+		"Соответствие: 80/100, уверенность высокая", vacancyFoundText(event))
+}
+
+func Test_VacancyFoundText_ShouldPreferSalaryFromHH(t *testing.T) {
+
+	event := events2.VacancyFound{
+		Search: models.JobSearch{SearchText: "golang"},
+		Vacancy: models.Vacancy{
+			Url:      "hh.ru/vacancy/1",
+			Employer: models.Employer{Name: "Роболайн"},
+			Area:     models.DictionaryItem{ID: "1", Name: "Москва"},
+			Address:  &models.Address{MetroStations: []string{"Лианозово", "Физтех"}},
+			Salary:   &models.Salary{From: 80000, To: 1200000, Currency: models.CurrencyRUR, Gross: true},
+		},
+		Verdict: models.MatchVerdict{
+			Score:   80,
+			Summary: models.VacancySummary{Salary: "от 80 тысяч", Stack: []string{"Go"}},
+		},
+	}
+
+	expected := "Найдена подходящая вакансия по поиску \"golang\":\nhh.ru/vacancy/1\n\n" +
+		"💼 Роболайн, Москва, м. Лианозово, м. Физтех\n💰 от 80 000 до 1 200 000 ₽ до вычета налогов\n🛠 Go\n\n" +
+		"Соответствие: 80/100"
+	assert.Equal(t, expected, vacancyFoundText(event))
+}
+
+type mockWishClarifier struct {
+	clarification models.WishClarification
+	err           error
+	requests      []string
+}
+
+func (m *mockWishClarifier) ClarifyWish(_ context.Context, _ int64, wish string) (models.WishClarification, error) {
+	m.requests = append(m.requests, wish)
+	return m.clarification, m.err
+}
+
+func Test_WishInput_WhenWishIsVague_ShouldOfferImprovedWish(t *testing.T) {
+
+	assert := assert.New(t)
+
+	clarifier := &mockWishClarifier{clarification: models.WishClarification{
+		ImprovedWish: "удалённая работа Go разработчиком с зарплатой от 200 000 ₽",
+		Questions:    []string{"Какой стек?"},
+	}}
+
+	var result []string
+	input := newWishInput(&mockApi{}, 0, clarifier, func(wish string) { result = append(result, wish) })
+	input.InitMessage()
+
+	assert.NotNil(input.HandleInput("норм работа"))
+	assert.Empty(result)
+
+	assert.Nil(input.HandleInput(useImprovedWishButton))
+	assert.Equal([]string{clarifier.clarification.ImprovedWish}, result)
+
+	assert.NotNil(input.HandleInput("норм работа"))
+	assert.Nil(input.HandleInput(keepOriginalWishButton))
+	assert.Equal("норм работа", result[1])
+}
+
+func Test_WishInput_WhenUserSendsNewWish_ShouldCheckItAgain(t *testing.T) {
+
+	assert := assert.New(t)
+
+	clarifier := &mockWishClarifier{clarification: models.WishClarification{Questions: []string{"Какой стек?"}}}
+
+	var result string
+	input := newWishInput(&mockApi{}, 0, clarifier, func(wish string) { result = wish })
+	input.InitMessage()
+
+	assert.NotNil(input.HandleInput("норм работа"))
+	assert.NotNil(input.HandleInput(useImprovedWishButton), "there is no improved wish to choose")
+
+	clarifier.clarification = models.WishClarification{IsClear: true}
+	assert.Nil(input.HandleInput("бэкенд на Go"))
+	assert.Equal("бэкенд на Go", result)
+	assert.Equal([]string{"норм работа", "бэкенд на Go"}, clarifier.requests)
+}
+
+func Test_WishInput_WhenClarificationFails_ShouldKeepOriginalWish(t *testing.T) {
+
+	clarifier := &mockWishClarifier{err: ai.ErrQuotaExhausted}
+
+	var result string
+	input := newWishInput(&mockApi{}, 0, clarifier, func(wish string) { result = wish })
+
+	assert.Nil(t, input.HandleInput("норм работа"))
+	assert.Equal(t, "норм работа", result)
+}
+
+func Test_WishInput_WhenButtonPressedWithoutClarification_ShouldAskForWish(t *testing.T) {
+
+	clarifier := &mockWishClarifier{}
+	finished := false
+	input := newWishInput(&mockApi{}, 0, clarifier, func(string) { finished = true })
+
+	assert.NotNil(t, input.HandleInput(keepOriginalWishButton))
+	assert.False(t, finished)
+	assert.Empty(t, clarifier.requests)
+}
@@ -22,6 +22,15 @@ type saveable interface {
 	LoadState(data []byte) error
 }
 
+func requestWithLogError(api *tgbotapi.BotAPI, chattable tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	resp, err := api.Request(chattable)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeTgApi).
+			Errorf("error occured while sending request: %v", err)
+	}
+	return resp, err
+}
+
 func sendWithLogError(api apiInterface, chattable tgbotapi.Chattable) (tgbotapi.Message, error) {
 	msg, err := api.Send(chattable)
 	if err != nil {
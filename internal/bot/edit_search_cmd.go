@@ -11,6 +11,7 @@ import (
 	"github.com/maxaizer/hh-parser/internal/logger"
 	log "github.com/sirupsen/logrus"
 	"strconv"
+	"strings"
 )
 
 const editSearchCommandName = "Изменить автопоиск"
@@ -20,52 +21,110 @@ const (
 	inputFieldToEditStep
 	inputKeywordsStep
 	inputWishStep
+	inputRulesStep
+	inputSimilarityThresholdStep
+	inputStrictnessStep
+	inputSalaryStep
+	inputOnlyWithSalaryStep
+	inputFiltersStep
 )
 
+const defaultSimilarityThresholdInput = "-"
+
 type editSearchCommand struct {
 	api                  apiInterface
 	chatID               int64
 	bus                  EventBus.Bus
 	searches             searchRepository
-	inputHandlers        [4]inputHandler
+	inputHandlers        [10]inputHandler
 	curInputIdx          int
 	search               *models.JobSearch
 	finishCallback       func()
 	finalMessageKeyboard *botApi.ReplyKeyboardMarkup
 }
 
-func newEditSearchCommand(api apiInterface, chatID int64, bus EventBus.Bus, searchRepo searchRepository) (*editSearchCommand, error) {
+type editOption struct {
+	title string
+	step  int
+}
+
+func newEditOptions(similarityFilter bool) []editOption {
+	options := []editOption{
+		{"изменить ключевые слова", inputKeywordsStep},
+		{"изменить пожелание к вакансии", inputWishStep},
+		{"изменить правила фильтрации", inputRulesStep},
+	}
+	if similarityFilter {
+		options = append(options, editOption{"изменить порог схожести", inputSimilarityThresholdStep})
+	}
+	return append(options,
+		editOption{"изменить строгость отбора", inputStrictnessStep},
+		editOption{"изменить зарплату", inputSalaryStep},
+		editOption{"изменить расширенные фильтры", inputFiltersStep},
+	)
+}
+
+func newEditSearchCommand(api apiInterface, chatID int64, bus EventBus.Bus, searchRepo searchRepository,
+	regionRepo regionRepository, clarifier wishClarifier, dictionaries models.Dictionaries,
+	similarityFilter bool) (*editSearchCommand, error) {
 
 	cmd := editSearchCommand{api: api, chatID: chatID, bus: bus, searches: searchRepo, curInputIdx: inputSearchStep}
 
 	var err error
-	cmd.inputHandlers[inputSearchStep], err = newSearchInput(chatID, searchRepo, func(s *models.JobSearch) {
+	cmd.inputHandlers[inputSearchStep], err = newSearchInput(chatID, searchRepo, dictionaries, func(s *models.JobSearch) {
 		cmd.search = s
 		cmd.curInputIdx = inputFieldToEditStep
 	})
-	cmd.inputHandlers[inputFieldToEditStep] = newInputHandlerChoose(cmd.chatID, func(input string) {
+	options := newEditOptions(similarityFilter)
+	cmd.inputHandlers[inputFieldToEditStep] = newInputHandlerChoose(cmd.chatID, options, func(input string) {
 		num, _ := strconv.Atoi(input)
-		switch num {
-		case 0:
-			cmd.curInputIdx = inputKeywordsStep
-		case 1:
-			cmd.curInputIdx = inputWishStep
-		default:
+		if num < 0 || num >= len(options) {
 			log.Errorf("editSearchCommand: wrong handler number: %d", num)
 			_, _ = sendWithLogError(cmd.api, botApi.NewMessage(cmd.chatID, "Внутренняя ошибка"))
 			cmd.finishCallback()
+			return
 		}
+		cmd.curInputIdx = options[num].step
 	})
 	cmd.inputHandlers[inputKeywordsStep] = newKeywordsInput(cmd.chatID, func(input string) {
 		cmd.search.SearchText = input
 		cmd.editSearch()
 		cmd.curInputIdx = inputFieldToEditStep
 	})
-	cmd.inputHandlers[inputWishStep] = newWishInput(cmd.chatID, func(input string) {
+	cmd.inputHandlers[inputWishStep] = newWishInput(api, cmd.chatID, clarifier, func(input string) {
 		cmd.search.UserWish = input
 		cmd.editSearch()
 		cmd.curInputIdx = inputFieldToEditStep
 	})
+	cmd.inputHandlers[inputRulesStep] = newRulesInput(cmd.chatID, func(rules models.SearchRules) {
+		cmd.search.Rules = rules
+		cmd.editSearch()
+		cmd.curInputIdx = inputFieldToEditStep
+	})
+	cmd.inputHandlers[inputSimilarityThresholdStep] = newSimilarityThresholdInput(cmd.chatID, func(input string) {
+		cmd.search.SimilarityThreshold, _ = parseSimilarityThreshold(input)
+		cmd.editSearch()
+		cmd.curInputIdx = inputFieldToEditStep
+	})
+	cmd.inputHandlers[inputStrictnessStep] = newStrictnessInput(cmd.chatID, func(strictness models.Strictness) {
+		cmd.search.Strictness = strictness
+		cmd.editSearch()
+		cmd.curInputIdx = inputFieldToEditStep
+	})
+	cmd.inputHandlers[inputSalaryStep] = newSalaryInput(cmd.chatID, func(input string) {
+		cmd.search.Salary, cmd.search.Currency, _ = parseSalary(input)
+		cmd.curInputIdx = inputOnlyWithSalaryStep
+	})
+	cmd.inputHandlers[inputOnlyWithSalaryStep] = newOnlyWithSalaryInput(cmd.chatID, func(onlyWithSalary bool) {
+		cmd.search.OnlyWithSalary = onlyWithSalary
+		cmd.editSearch()
+		cmd.curInputIdx = inputFieldToEditStep
+	})
+	cmd.inputHandlers[inputFiltersStep] = newFiltersInput(cmd.chatID, regionRepo, dictionaries, func(filters models.SearchFilters) {
+		cmd.search.Filters = filters
+		cmd.editSearch()
+		cmd.curInputIdx = inputFieldToEditStep
+	})
 
 	return &cmd, err
 }
@@ -156,15 +215,51 @@ func (c *editSearchCommand) editSearch() {
 	_, _ = sendWithLogError(c.api, botApi.NewMessage(c.chatID, "Поиск успешно обновлён!"))
 }
 
-func newInputHandlerChoose(chatID int64, onFinish func(input string)) *textInput {
-	input := newTextInput(chatID, "0 - изменить ключевые слова\n1 - изменить пожелание к вакансии.",
-		onFinish)
+func newInputHandlerChoose(chatID int64, options []editOption, onFinish func(input string)) *textInput {
+
+	lines := make([]string, 0, len(options))
+	for i, option := range options {
+		lines = append(lines, fmt.Sprintf("%d - %s", i, option.title))
+	}
+
+	input := newTextInput(chatID, strings.Join(lines, "\n")+".", onFinish)
 	input.AddValidation(validation{
 		function: func(input string) bool {
 			digit, err := strconv.Atoi(input)
-			return err == nil && digit >= 0 && digit <= 1
+			return err == nil && digit >= 0 && digit < len(options)
+		},
+		errorMessage: fmt.Sprintf("Введите число от 0 до %d", len(options)-1),
+	})
+	return input
+}
+
+func newSimilarityThresholdInput(chatID int64, onFinish func(input string)) *textInput {
+	input := newTextInput(chatID, "Укажите порог схожести вакансии с пожеланием от 0 до 1. Вакансии с меньшей "+
+		"схожестью будут отброшены без проверки ИИ. Чем выше порог, тем больше вакансий отсеивается.\n"+
+		"0 - не проверять схожесть, "+defaultSimilarityThresholdInput+" - использовать порог по умолчанию.", onFinish)
+	input.AddValidation(validation{
+		function: func(input string) bool {
+			_, err := parseSimilarityThreshold(input)
+			return err == nil
 		},
-		errorMessage: "Введите число от 0 до 1",
+		errorMessage: "Введите число от 0 до 1, например 0.4, или \"" + defaultSimilarityThresholdInput + "\"",
 	})
 	return input
 }
+
+func parseSimilarityThreshold(input string) (*float64, error) {
+
+	input = strings.TrimSpace(input)
+	if input == defaultSimilarityThresholdInput {
+		return nil, nil
+	}
+
+	threshold, err := strconv.ParseFloat(strings.ReplaceAll(input, ",", "."), 64)
+	if err != nil {
+		return nil, err
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("threshold %v is out of range", threshold)
+	}
+	return &threshold, nil
+}
@@ -0,0 +1,122 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"strings"
+	"unicode"
+)
+
+const (
+	listEmployersCommandName    = "Работодатели"
+	blockEmployerCommandName    = "block"
+	allowEmployerCommandName    = "allow"
+	forgetEmployerCommandName   = "forget"
+	hideEmployerCallbackPrefix  = "hide"
+	hideEmployerButtonText      = "🚫 Скрыть работодателя"
+	employerHiddenButtonText    = "🚫 Работодатель скрыт"
+	employerCommandsDescription = "/" + blockEmployerCommandName + " id — скрыть вакансии работодателя\n" +
+		"/" + allowEmployerCommandName + " id — присылать вакансии только от разрешённых работодателей\n" +
+		"/" + forgetEmployerCommandName + " id — убрать работодателя из списков\n" +
+		"id работодателя есть в его ссылке: hh.ru/employer/id"
+)
+
+func hideEmployerCallbackData(employerID string) string {
+	return hideEmployerCallbackPrefix + ":" + employerID
+}
+
+func parseHideEmployerCallback(data string) (string, error) {
+
+	employerID, found := strings.CutPrefix(data, hideEmployerCallbackPrefix+":")
+	if !found {
+		return "", fmt.Errorf("unknown callback data: %q", data)
+	}
+	if err := validateEmployerID(employerID); err != nil {
+		return "", err
+	}
+	return employerID, nil
+}
+
+func validateEmployerID(employerID string) error {
+	if employerID == "" {
+		return errors.New("employer id is empty")
+	}
+	if strings.IndexFunc(employerID, func(r rune) bool { return !unicode.IsDigit(r) }) != -1 {
+		return fmt.Errorf("invalid employer id: %q", employerID)
+	}
+	return nil
+}
+
+// vacancyKeyboard is feedback buttons, followed by the button hiding vacancies of the employer
+func vacancyKeyboard(searchID int, vacancy models.Vacancy) botApi.InlineKeyboardMarkup {
+
+	keyboard := feedbackKeyboard(searchID, vacancy.ID, nil)
+	if vacancy.Employer.ID != "" {
+		keyboard.InlineKeyboard = append(keyboard.InlineKeyboard, botApi.NewInlineKeyboardRow(
+			botApi.NewInlineKeyboardButtonData(hideEmployerButtonText, hideEmployerCallbackData(vacancy.Employer.ID)),
+		))
+	}
+	return keyboard
+}
+
+// replaceKeyboardRow keeps other rows of the sent keyboard, so feedback and hiding don't reset each other
+func replaceKeyboardRow(keyboard *botApi.InlineKeyboardMarkup, index int,
+	row []botApi.InlineKeyboardButton) botApi.InlineKeyboardMarkup {
+
+	var rows [][]botApi.InlineKeyboardButton
+	if keyboard != nil {
+		rows = append(rows, keyboard.InlineKeyboard...)
+	}
+	for len(rows) <= index {
+		rows = append(rows, nil)
+	}
+	rows[index] = row
+	return botApi.NewInlineKeyboardMarkup(rows...)
+}
+
+func keyboardRowIndex(keyboard botApi.InlineKeyboardMarkup, callbackData string) int {
+	for i, row := range keyboard.InlineKeyboard {
+		for _, button := range row {
+			if button.CallbackData != nil && *button.CallbackData == callbackData {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func employerListsText(lists models.EmployerLists) string {
+
+	text := "Списки работодателей действуют для всех ваших автопоисков."
+	if lists.IsEmpty() {
+		text += "\n\nСписки пусты."
+	}
+
+	sections := []struct {
+		title   string
+		entries []models.EmployerListEntry
+	}{
+		{"Скрытые работодатели", lists.Blocked},
+		{"Разрешённые работодатели (вакансии других не присылаются)", lists.Allowed},
+	}
+	for _, section := range sections {
+		if len(section.entries) == 0 {
+			continue
+		}
+		text += "\n\n" + section.title + ":"
+		for _, entry := range section.entries {
+			text += "\n" + employerEntryToText(entry)
+		}
+	}
+
+	return text + "\n\n" + employerCommandsDescription
+}
+
+func employerEntryToText(entry models.EmployerListEntry) string {
+	if entry.EmployerName == "" {
+		return entry.EmployerID
+	}
+	return fmt.Sprintf("%s (%s)", entry.EmployerName, entry.EmployerID)
+}
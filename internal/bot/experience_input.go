@@ -3,61 +3,49 @@ package bot
 import (
 	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"slices"
 )
 
-type experienceLevel string
-
-const (
-	noExperience experienceLevel = "Нет опыта 😡"
-	between1and3 experienceLevel = "Между 1 и 3"
-	between3and6 experienceLevel = "Между 3 и 6"
-	moreThan6    experienceLevel = "Больше 6 лет"
-)
+const experienceButtonsPerRow = 2
 
 type experienceInput struct {
 	chatID   int64
+	levels   []models.DictionaryItem
 	onFinish func(experience models.Experience)
 }
 
-func newExperienceInput(chatID int64, onFinish func(experience models.Experience)) *experienceInput {
-	return &experienceInput{chatID: chatID, onFinish: onFinish}
+func newExperienceInput(chatID int64, dictionaries models.Dictionaries,
+	onFinish func(experience models.Experience)) *experienceInput {
+	return &experienceInput{chatID: chatID, levels: dictionaries[models.ExperienceDictionary], onFinish: onFinish}
 }
 
 func (a *experienceInput) InitMessage() botApi.Chattable {
 	msg := botApi.NewMessage(a.chatID, "Введите опыт работы.")
-	msg.ReplyMarkup = experienceKeyboard()
+	msg.ReplyMarkup = a.keyboard()
 	return msg
 }
 
 func (a *experienceInput) HandleInput(input string) botApi.Chattable {
 
-	var experience models.Experience
-
-	switch experienceLevel(input) {
-	case noExperience:
-		experience = models.NoExperience
-	case between1and3:
-		experience = models.Between1and3
-	case between3and6:
-		experience = models.Between3and6
-	case moreThan6:
-		experience = models.MoreThan6
-	default:
+	idx := slices.IndexFunc(a.levels, func(level models.DictionaryItem) bool {
+		return level.Name == input
+	})
+	if idx == -1 {
 		return botApi.NewMessage(a.chatID, "Неправильный ввод 😔.")
 	}
 
-	a.onFinish(experience)
+	a.onFinish(models.Experience(a.levels[idx].ID))
 	return nil
 }
 
-func experienceKeyboard() botApi.ReplyKeyboardMarkup {
-	return botApi.NewReplyKeyboard(
-		botApi.NewKeyboardButtonRow(
-			botApi.NewKeyboardButton(string(noExperience)),
-			botApi.NewKeyboardButton(string(between1and3)),
-		),
-		botApi.NewKeyboardButtonRow(
-			botApi.NewKeyboardButton(string(between3and6)),
-			botApi.NewKeyboardButton(string(moreThan6)),
-		))
+func (a *experienceInput) keyboard() botApi.ReplyKeyboardMarkup {
+	var rows [][]botApi.KeyboardButton
+	for levels := range slices.Chunk(a.levels, experienceButtonsPerRow) {
+		var row []botApi.KeyboardButton
+		for _, level := range levels {
+			row = append(row, botApi.NewKeyboardButton(level.Name))
+		}
+		rows = append(rows, row)
+	}
+	return botApi.NewReplyKeyboard(rows...)
 }
@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	listFeedbackCommandName  = "Мои оценки"
+	clearFeedbackCommandName = "Сбросить оценки"
+	feedbackCallbackPrefix   = "fb"
+	positiveFeedbackMark     = "👍"
+	negativeFeedbackMark     = "👎"
+)
+
+type feedbackCallback struct {
+	SearchID  int
+	VacancyID string
+	Positive  bool
+}
+
+func feedbackCallbackData(searchID int, vacancyID string, positive bool) string {
+	mark := "-"
+	if positive {
+		mark = "+"
+	}
+	return fmt.Sprintf("%s:%s:%d:%s", feedbackCallbackPrefix, mark, searchID, vacancyID)
+}
+
+func parseFeedbackCallback(data string) (feedbackCallback, error) {
+
+	parts := strings.Split(data, ":")
+	if len(parts) != 4 || parts[0] != feedbackCallbackPrefix {
+		return feedbackCallback{}, fmt.Errorf("unknown callback data: %q", data)
+	}
+
+	if parts[1] != "+" && parts[1] != "-" {
+		return feedbackCallback{}, fmt.Errorf("invalid feedback mark: %q", parts[1])
+	}
+
+	searchID, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return feedbackCallback{}, fmt.Errorf("invalid search id: %w", err)
+	}
+
+	if parts[3] == "" {
+		return feedbackCallback{}, errors.New("vacancy id is empty")
+	}
+
+	return feedbackCallback{SearchID: searchID, VacancyID: parts[3], Positive: parts[1] == "+"}, nil
+}
+
+func feedbackKeyboard(searchID int, vacancyID string, selected *bool) botApi.InlineKeyboardMarkup {
+
+	positiveText, negativeText := positiveFeedbackMark, negativeFeedbackMark
+	if selected != nil && *selected {
+		positiveText += " ✓"
+	}
+	if selected != nil && !*selected {
+		negativeText += " ✓"
+	}
+
+	return botApi.NewInlineKeyboardMarkup(botApi.NewInlineKeyboardRow(
+		botApi.NewInlineKeyboardButtonData(positiveText, feedbackCallbackData(searchID, vacancyID, true)),
+		botApi.NewInlineKeyboardButtonData(negativeText, feedbackCallbackData(searchID, vacancyID, false)),
+	))
+}
+
+func feedbackListText(feedback []models.VacancyFeedback, searches []models.JobSearch) string {
+
+	if len(feedback) == 0 {
+		return "Вы ещё не оценили ни одной вакансии"
+	}
+
+	searchNames := make(map[int]string, len(searches))
+	for _, search := range searches {
+		searchNames[search.ID] = search.SearchText
+	}
+
+	text := "Ваши оценки вакансий:"
+	lastSearchID := -1
+	for _, item := range feedback {
+		if item.SearchID != lastSearchID {
+			text += fmt.Sprintf("\n\nПоиск \"%s\":", searchNames[item.SearchID])
+			lastSearchID = item.SearchID
+		}
+
+		mark := negativeFeedbackMark
+		if item.Positive {
+			mark = positiveFeedbackMark
+		}
+		text += fmt.Sprintf("\n%s %s", mark, item.VacancyName)
+		if salary := item.Verdict.Summary.Salary; salary != "" {
+			text += fmt.Sprintf(" (💰 %s)", salary)
+		}
+	}
+	return text
+}
@@ -0,0 +1,214 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/samber/lo"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	areasFilterKey        = "регионы"
+	rolesFilterKey        = "роли"
+	industriesFilterKey   = "отрасли"
+	employmentFilterKey   = "занятость"
+	searchFieldsFilterKey = "искать в"
+	excludedTextFilterKey = "исключить слова"
+	labelsFilterKey       = "метки"
+	employersFilterKey    = "работодатели"
+)
+
+type filtersInput struct {
+	chatID       int64
+	regions      regionRepository
+	dictionaries models.Dictionaries
+	onFinish     func(filters models.SearchFilters)
+}
+
+func newFiltersInput(chatID int64, regions regionRepository, dictionaries models.Dictionaries,
+	onFinish func(filters models.SearchFilters)) *filtersInput {
+	return &filtersInput{chatID: chatID, regions: regions, dictionaries: dictionaries, onFinish: onFinish}
+}
+
+func (f *filtersInput) InitMessage() botApi.Chattable {
+	msg := botApi.NewMessage(f.chatID, "Укажите расширенные фильтры поиска hh.ru, по одному на строке.\n"+
+		areasFilterKey+": дополнительные регионы\n"+
+		rolesFilterKey+": id профессиональных ролей\n"+
+		industriesFilterKey+": id отраслей компаний\n"+
+		employmentFilterKey+": "+dictionaryNames(f.dictionaries[models.EmploymentDictionary])+"\n"+
+		searchFieldsFilterKey+": "+dictionaryNames(f.dictionaries[models.SearchFieldDictionary])+"\n"+
+		excludedTextFilterKey+": слова, которых не должно быть в вакансии\n"+
+		labelsFilterKey+": "+dictionaryNames(f.dictionaries[models.LabelDictionary])+"\n"+
+		employersFilterKey+": id работодателей\n\n"+
+		"Например:\n"+areasFilterKey+": Москва, 2\n"+excludedTextFilterKey+": 1С, битрикс\n\n"+
+		"Введите 0, чтобы не задавать фильтры.")
+	msg.ReplyMarkup = keyboardWithExit()
+	return msg
+}
+
+func (f *filtersInput) HandleInput(input string) botApi.Chattable {
+
+	if strings.TrimSpace(input) == "0" {
+		f.onFinish(models.SearchFilters{})
+		return nil
+	}
+
+	filters, err := parseSearchFilters(context.Background(), input, f.regions, f.dictionaries)
+	if err != nil {
+		return botApi.NewMessage(f.chatID, err.Error())
+	}
+
+	f.onFinish(filters)
+	return nil
+}
+
+func parseSearchFilters(ctx context.Context, input string, regions regionRepository,
+	dictionaries models.Dictionaries) (models.SearchFilters, error) {
+
+	var filters models.SearchFilters
+
+	for _, line := range strings.Split(input, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return models.SearchFilters{}, fmt.Errorf("Неверная строка \"%s\": ожидается \"фильтр: значения\".", line)
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		terms := splitTerms(value)
+		if len(terms) == 0 {
+			return models.SearchFilters{}, fmt.Errorf("Не указаны значения для фильтра \"%s\".", key)
+		}
+
+		var err error
+		switch key {
+		case areasFilterKey:
+			for _, term := range terms {
+				area, _err := regionIDFromTerm(ctx, term, regions)
+				if _err != nil {
+					return models.SearchFilters{}, _err
+				}
+				filters.AreaIDs = append(filters.AreaIDs, area)
+			}
+		case rolesFilterKey:
+			filters.ProfessionalRoles = append(filters.ProfessionalRoles, terms...)
+		case industriesFilterKey:
+			filters.Industries = append(filters.Industries, terms...)
+		case employmentFilterKey:
+			filters.Employments, err = appendByNames(filters.Employments, terms,
+				dictionaries[models.EmploymentDictionary])
+		case searchFieldsFilterKey:
+			filters.SearchFields, err = appendByNames(filters.SearchFields, terms,
+				dictionaries[models.SearchFieldDictionary])
+		case excludedTextFilterKey:
+			if filters.ExcludedText != "" {
+				terms = append([]string{filters.ExcludedText}, terms...)
+			}
+			filters.ExcludedText = strings.Join(terms, ", ")
+		case labelsFilterKey:
+			filters.Labels, err = appendByNames(filters.Labels, terms, dictionaries[models.LabelDictionary])
+		case employersFilterKey:
+			filters.EmployerIDs = append(filters.EmployerIDs, terms...)
+		default:
+			return models.SearchFilters{}, fmt.Errorf("Неизвестный фильтр \"%s\".", key)
+		}
+
+		if err != nil {
+			return models.SearchFilters{}, err
+		}
+	}
+
+	if filters.IsEmpty() {
+		return models.SearchFilters{}, errors.New("Не указано ни одного фильтра.")
+	}
+	if err := filters.Validate(); err != nil {
+		return models.SearchFilters{}, errors.New("Id ролей, отраслей и работодателей должны быть числами.")
+	}
+	return filters, nil
+}
+
+func regionIDFromTerm(ctx context.Context, term string, regions regionRepository) (string, error) {
+
+	if strings.IndexFunc(term, func(r rune) bool { return !unicode.IsDigit(r) }) == -1 {
+		return term, nil
+	}
+
+	id, err := regions.GetIdByName(ctx, term)
+	if err != nil {
+		return "", fmt.Errorf("Регион \"%s\" не найден.", term)
+	}
+	return id, nil
+}
+
+// appendByNames looks terms up in hh.ru dictionary by name or id
+func appendByNames[T ~string](values []T, terms []string, items []models.DictionaryItem) ([]T, error) {
+	for _, term := range terms {
+		idx := slices.IndexFunc(items, func(item models.DictionaryItem) bool {
+			return normalizeName(item.Name) == normalizeName(term) || item.ID == term
+		})
+		if idx == -1 {
+			return nil, fmt.Errorf("Неизвестное значение \"%s\", допустимые: %s.", term, dictionaryNames(items))
+		}
+		values = append(values, T(items[idx].ID))
+	}
+	return values, nil
+}
+
+func normalizeName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "ё", "е")
+}
+
+func dictionaryNames(items []models.DictionaryItem) string {
+	return strings.Join(lo.Map(items, func(item models.DictionaryItem, _ int) string {
+		return strings.ToLower(item.Name)
+	}), ", ")
+}
+
+func filtersToText(filters models.SearchFilters, dictionaries models.Dictionaries) string {
+
+	if filters.IsEmpty() {
+		return "не заданы"
+	}
+
+	var parts []string
+	lists := []struct {
+		key    string
+		values []string
+	}{
+		{areasFilterKey, filters.AreaIDs},
+		{rolesFilterKey, filters.ProfessionalRoles},
+		{industriesFilterKey, filters.Industries},
+		{employmentFilterKey, namesOf(filters.Employments, dictionaries, models.EmploymentDictionary)},
+		{searchFieldsFilterKey, namesOf(filters.SearchFields, dictionaries, models.SearchFieldDictionary)},
+		{labelsFilterKey, namesOf(filters.Labels, dictionaries, models.LabelDictionary)},
+		{employersFilterKey, filters.EmployerIDs},
+	}
+
+	for _, list := range lists {
+		if len(list.values) > 0 {
+			parts = append(parts, list.key+": "+strings.Join(list.values, ", "))
+		}
+	}
+	if filters.ExcludedText != "" {
+		parts = append(parts, excludedTextFilterKey+": "+filters.ExcludedText)
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+func namesOf[T ~string](values []T, dictionaries models.Dictionaries, dictionary string) []string {
+	return lo.Map(values, func(value T, _ int) string {
+		if name, ok := dictionaries.NameOf(dictionary, string(value)); ok {
+			return strings.ToLower(name)
+		}
+		return string(value)
+	})
+}
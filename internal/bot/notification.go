@@ -0,0 +1,177 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/domain/events"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"strconv"
+	"strings"
+)
+
+func vacancyFoundText(event events.VacancyFound) string {
+
+	text := fmt.Sprintf("Найдена подходящая вакансия по поиску \"%v\":\n%v", event.Search.SearchText, event.Vacancy.Url)
+
+	summary := event.Verdict.Summary
+	if event.Vacancy.Salary != nil {
+		//salary from hh.ru is more accurate than the one retold by AI
+		summary.Salary = ""
+	}
+
+	var blocks []string
+	if details := vacancyDetailsToText(event.Vacancy); details != "" {
+		blocks = append(blocks, details)
+	}
+	if !summary.IsEmpty() {
+		blocks = append(blocks, summaryToText(summary))
+	}
+	if len(blocks) > 0 {
+		text += "\n\n" + strings.Join(blocks, "\n")
+	}
+	text += "\n\n" + verdictToText(event.Verdict)
+	return text
+}
+
+func vacancyDetailsToText(vacancy models.Vacancy) string {
+
+	var lines []string
+
+	var place []string
+	if vacancy.Employer.Name != "" {
+		place = append(place, vacancy.Employer.Name)
+	}
+	if vacancy.Area.Name != "" {
+		place = append(place, vacancy.Area.Name)
+	}
+	if vacancy.Address != nil && len(vacancy.Address.MetroStations) > 0 {
+		place = append(place, "м. "+strings.Join(vacancy.Address.MetroStations, ", м. "))
+	}
+	if len(place) > 0 {
+		lines = append(lines, "💼 "+strings.Join(place, ", "))
+	}
+
+	if vacancy.Salary != nil {
+		if salary := salaryToText(*vacancy.Salary); salary != "" {
+			lines = append(lines, "💰 "+salary)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func salaryToText(salary models.Salary) string {
+
+	var parts []string
+	if salary.From > 0 {
+		parts = append(parts, "от "+formatAmount(salary.From))
+	}
+	if salary.To > 0 {
+		parts = append(parts, "до "+formatAmount(salary.To))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+
+	parts = append(parts, currencyToText(salary.Currency))
+	if salary.Gross {
+		parts = append(parts, "до вычета налогов")
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatAmount(amount int) string {
+
+	digits := strconv.Itoa(amount)
+	var groups []string
+	for len(digits) > 3 {
+		groups = append([]string{digits[len(digits)-3:]}, groups...)
+		digits = digits[:len(digits)-3]
+	}
+	return strings.Join(append([]string{digits}, groups...), " ")
+}
+
+func currencyToText(currency string) string {
+	switch currency {
+	case models.CurrencyRUR:
+		return "₽"
+	case "USD":
+		return "$"
+	case "EUR":
+		return "€"
+	case "KZT":
+		return "₸"
+	default:
+		return currency
+	}
+}
+
+func verdictToText(verdict models.MatchVerdict) string {
+
+	text := fmt.Sprintf("Соответствие: %d/100", verdict.Score)
+	if confidence, err := confidenceToText(verdict.Confidence); err == nil {
+		text += ", уверенность " + confidence
+	}
+
+	if len(verdict.MatchedCriteria) > 0 {
+		text += "\n✅ " + strings.Join(verdict.MatchedCriteria, "; ")
+	}
+	if len(verdict.MissedCriteria) > 0 {
+		text += "\n❌ " + strings.Join(verdict.MissedCriteria, "; ")
+	}
+	if verdict.Rationale != "" {
+		text += "\n💬 " + verdict.Rationale
+	}
+	return text
+}
+
+func summaryToText(summary models.VacancySummary) string {
+
+	var lines []string
+	if summary.Salary != "" {
+		lines = append(lines, "💰 "+summary.Salary)
+	}
+	if len(summary.Stack) > 0 {
+		lines = append(lines, "🛠 "+strings.Join(summary.Stack, ", "))
+	}
+
+	var company []string
+	if format, err := workFormatToText(summary.WorkFormat); err == nil {
+		company = append(company, format)
+	}
+	if summary.CompanySize != "" {
+		company = append(company, summary.CompanySize)
+	}
+	if len(company) > 0 {
+		lines = append(lines, "🏢 "+strings.Join(company, ", "))
+	}
+
+	if len(summary.RedFlags) > 0 {
+		lines = append(lines, "🚩 "+strings.Join(summary.RedFlags, "; "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func workFormatToText(format models.WorkFormat) (string, error) {
+	switch format {
+	case models.WorkFormatRemote:
+		return "удалённо", nil
+	case models.WorkFormatOffice:
+		return "офис", nil
+	case models.WorkFormatHybrid:
+		return "гибрид", nil
+	default:
+		return "", fmt.Errorf("unknown work format: %s", format)
+	}
+}
+
+func confidenceToText(confidence models.Confidence) (string, error) {
+	switch confidence {
+	case models.ConfidenceLow:
+		return "низкая", nil
+	case models.ConfidenceMedium:
+		return "средняя", nil
+	case models.ConfidenceHigh:
+		return "высокая", nil
+	default:
+		return "", fmt.Errorf("invalid confidence: %s", confidence)
+	}
+}
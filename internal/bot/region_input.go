@@ -3,11 +3,22 @@ package bot
 import (
 	"context"
 	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/maxaizer/hh-parser/internal/logger"
 	log "github.com/sirupsen/logrus"
-	"strings"
+	"regexp"
+	"slices"
 )
 
+const (
+	anyRegionInput       = "Не указывать"
+	maxRegionSuggestions = 6
+	regionButtonsPerRow  = 2
+)
+
+// suggested regions end with their id, so the choice doesn't depend on names which may contain anything
+var regionSuggestionRegexp = regexp.MustCompile(`^.+ #(\d+)$`)
+
 type regionInput struct {
 	chatID   int64
 	onFinish func(regionID string)
@@ -19,29 +30,81 @@ func newRegionInput(chatID int64, regionRepo regionRepository, onFinish func(reg
 }
 
 func (a *regionInput) InitMessage() botApi.Chattable {
-	msg := botApi.NewMessage(a.chatID, "Введите регион поиска.")
+	msg := botApi.NewMessage(a.chatID, "Введите регион поиска: страну, область или город. "+
+		"Поиск по региону включает все населённые пункты в нём.")
 	msg.ReplyMarkup = regionKeyboard()
 	return msg
 }
 
 func (a *regionInput) HandleInput(input string) botApi.Chattable {
 
-	if input == "Не указывать" {
+	if input == anyRegionInput {
 		a.onFinish("")
 		return nil
 	}
 
-	regionID, err := a.regions.GetIdByName(context.Background(), strings.ToLower(input))
+	if match := regionSuggestionRegexp.FindStringSubmatch(input); match != nil {
+		region, err := a.regions.GetByID(context.Background(), match[1])
+		if err != nil {
+			log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Error(err)
+			return botApi.NewMessage(a.chatID, "Внутренняя ошибка.")
+		}
+		if region != nil {
+			a.onFinish(region.ID)
+			return nil
+		}
+	}
+
+	regions, err := a.regions.Search(context.Background(), input, maxRegionSuggestions)
 	if err != nil {
 		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Error(err)
 		return botApi.NewMessage(a.chatID, "Внутренняя ошибка.")
 	}
-	if regionID == "" {
+	if len(regions) == 0 {
 		return botApi.NewMessage(a.chatID, "Регион не найден.")
 	}
 
-	a.onFinish(regionID)
-	return nil
+	if region, ok := singleExactMatch(regions, input); ok {
+		a.onFinish(region.ID)
+		return nil
+	}
+
+	var buttons []botApi.KeyboardButton
+	for _, region := range regions {
+		buttons = append(buttons, botApi.NewKeyboardButton(regionSuggestion(region)))
+	}
+
+	msg := botApi.NewMessage(a.chatID, "Уточните регион, выберите один из вариантов или введите другой.")
+	msg.ReplyMarkup = suggestionsKeyboard(buttons)
+	return msg
+}
+
+func regionSuggestion(region models.Region) string {
+	return region.FullName() + " #" + region.ID
+}
+
+func singleExactMatch(regions []models.Region, name string) (models.Region, bool) {
+
+	var exact []models.Region
+	for _, region := range regions {
+		if region.NormalizedName == models.NormalizeRegionName(name) {
+			exact = append(exact, region)
+		}
+	}
+
+	if len(exact) != 1 {
+		return models.Region{}, false
+	}
+	return exact[0], true
+}
+
+func suggestionsKeyboard(buttons []botApi.KeyboardButton) botApi.ReplyKeyboardMarkup {
+	var rows [][]botApi.KeyboardButton
+	for row := range slices.Chunk(buttons, regionButtonsPerRow) {
+		rows = append(rows, row)
+	}
+	rows = append(rows, botApi.NewKeyboardButtonRow(botApi.NewKeyboardButton(anyRegionInput)))
+	return botApi.NewReplyKeyboard(rows...)
 }
 
 func regionKeyboard() botApi.ReplyKeyboardMarkup {
@@ -55,7 +118,7 @@ func regionKeyboard() botApi.ReplyKeyboardMarkup {
 			botApi.NewKeyboardButton("Екатеринбург"),
 		),
 		botApi.NewKeyboardButtonRow(
-			botApi.NewKeyboardButton("Не указывать"),
+			botApi.NewKeyboardButton(anyRegionInput),
 		),
 	)
 }
@@ -24,10 +24,11 @@ type removeSearchCommand struct {
 	finalMessageKeyboard *botApi.ReplyKeyboardMarkup
 }
 
-func newRemoveSearchCommand(api apiInterface, chatID int64, bus EventBus.Bus, searchRepo searchRepository) (*removeSearchCommand, error) {
+func newRemoveSearchCommand(api apiInterface, chatID int64, bus EventBus.Bus, searchRepo searchRepository,
+	dictionaries models.Dictionaries) (*removeSearchCommand, error) {
 
 	cmd := removeSearchCommand{api: api, chatID: chatID, bus: bus, searches: searchRepo}
-	input, err := newSearchInput(chatID, searchRepo, func(s *models.JobSearch) {
+	input, err := newSearchInput(chatID, searchRepo, dictionaries, func(s *models.JobSearch) {
 		cmd.searchID = s.ID
 		cmd.searchInputFinished = true
 	})
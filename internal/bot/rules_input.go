@@ -0,0 +1,156 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"strconv"
+	"strings"
+)
+
+const (
+	nameIncludeKey        = "+название"
+	nameExcludeKey        = "-название"
+	descriptionIncludeKey = "+описание"
+	descriptionExcludeKey = "-описание"
+	skillsIncludeKey      = "+навыки"
+	skillsExcludeKey      = "-навыки"
+	minSalaryKey          = "зарплата от"
+	excludedEmployersKey  = "-работодатель"
+)
+
+type rulesInput struct {
+	chatID   int64
+	onFinish func(rules models.SearchRules)
+}
+
+func newRulesInput(chatID int64, onFinish func(rules models.SearchRules)) *rulesInput {
+	return &rulesInput{chatID: chatID, onFinish: onFinish}
+}
+
+func (r *rulesInput) InitMessage() botApi.Chattable {
+	msg := botApi.NewMessage(r.chatID, "Укажите правила предварительной фильтрации, по одному на строке. "+
+		"Вакансии, которые им не соответствуют, будут отброшены без проверки ИИ.\n"+
+		nameIncludeKey+": слова, одно из которых должно быть в названии\n"+
+		nameExcludeKey+": слова, которых не должно быть в названии\n"+
+		descriptionIncludeKey+" и "+descriptionExcludeKey+": то же для описания\n"+
+		skillsIncludeKey+" и "+skillsExcludeKey+": то же для ключевых навыков\n"+
+		minSalaryKey+": минимальная зарплата в рублях\n"+
+		excludedEmployersKey+": названия или id работодателей\n\n"+
+		"Например:\n"+nameExcludeKey+": 1С, битрикс\n"+minSalaryKey+": 150000\n\n"+
+		"Введите 0, чтобы не задавать правила.")
+	msg.ReplyMarkup = keyboardWithExit()
+	return msg
+}
+
+func (r *rulesInput) HandleInput(input string) botApi.Chattable {
+
+	if strings.TrimSpace(input) == "0" {
+		r.onFinish(models.SearchRules{})
+		return nil
+	}
+
+	rules, err := parseSearchRules(input)
+	if err != nil {
+		return botApi.NewMessage(r.chatID, err.Error())
+	}
+
+	r.onFinish(rules)
+	return nil
+}
+
+func parseSearchRules(input string) (models.SearchRules, error) {
+
+	var rules models.SearchRules
+
+	for _, line := range strings.Split(input, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			return models.SearchRules{}, fmt.Errorf("Неверная строка \"%s\": ожидается \"правило: значения\".", line)
+		}
+
+		key = strings.ToLower(strings.TrimSpace(key))
+		terms := splitTerms(value)
+
+		if key != minSalaryKey && len(terms) == 0 {
+			return models.SearchRules{}, fmt.Errorf("Не указаны значения для правила \"%s\".", key)
+		}
+
+		switch key {
+		case nameIncludeKey:
+			rules.NameInclude = append(rules.NameInclude, terms...)
+		case nameExcludeKey:
+			rules.NameExclude = append(rules.NameExclude, terms...)
+		case descriptionIncludeKey:
+			rules.DescriptionInclude = append(rules.DescriptionInclude, terms...)
+		case descriptionExcludeKey:
+			rules.DescriptionExclude = append(rules.DescriptionExclude, terms...)
+		case skillsIncludeKey:
+			rules.SkillsInclude = append(rules.SkillsInclude, terms...)
+		case skillsExcludeKey:
+			rules.SkillsExclude = append(rules.SkillsExclude, terms...)
+		case excludedEmployersKey:
+			rules.ExcludedEmployers = append(rules.ExcludedEmployers, terms...)
+		case minSalaryKey:
+			salary, err := strconv.Atoi(strings.ReplaceAll(strings.TrimSpace(value), " ", ""))
+			if err != nil || salary <= 0 {
+				return models.SearchRules{}, errors.New("Зарплата должна быть положительным числом.")
+			}
+			rules.MinSalary = salary
+		default:
+			return models.SearchRules{}, fmt.Errorf("Неизвестное правило \"%s\".", key)
+		}
+	}
+
+	if rules.IsEmpty() {
+		return models.SearchRules{}, errors.New("Не указано ни одного правила.")
+	}
+	return rules, nil
+}
+
+func splitTerms(value string) []string {
+	var terms []string
+	for _, term := range strings.Split(value, ",") {
+		if term = strings.TrimSpace(term); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func rulesToText(rules models.SearchRules) string {
+
+	if rules.IsEmpty() {
+		return "не заданы"
+	}
+
+	var parts []string
+	lists := []struct {
+		key   string
+		terms []string
+	}{
+		{nameIncludeKey, rules.NameInclude},
+		{nameExcludeKey, rules.NameExclude},
+		{descriptionIncludeKey, rules.DescriptionInclude},
+		{descriptionExcludeKey, rules.DescriptionExclude},
+		{skillsIncludeKey, rules.SkillsInclude},
+		{skillsExcludeKey, rules.SkillsExclude},
+		{excludedEmployersKey, rules.ExcludedEmployers},
+	}
+
+	for _, list := range lists {
+		if len(list.terms) > 0 {
+			parts = append(parts, list.key+": "+strings.Join(list.terms, ", "))
+		}
+	}
+	if rules.MinSalary > 0 {
+		parts = append(parts, minSalaryKey+": "+strconv.Itoa(rules.MinSalary))
+	}
+
+	return strings.Join(parts, "; ")
+}
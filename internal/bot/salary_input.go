@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"errors"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const noSalaryInput = "-"
+
+type salaryFilterOption string
+
+const (
+	onlyWithSalary salaryFilterOption = "Только с зарплатой"
+	anySalary      salaryFilterOption = "Все вакансии"
+)
+
+var currencyCodeRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+
+func newSalaryInput(chatID int64, onFinish func(input string)) *textInput {
+	input := newTextInput(chatID, "Укажите желаемую зарплату, например \"250 000\" или \"3000 USD\". Вакансии, "+
+		"где зарплата указана и меньше желаемой, не попадут в поиск.\n"+noSalaryInput+" - зарплата не важна.", onFinish)
+	input.AddValidation(validation{
+		function: func(input string) bool {
+			_, _, err := parseSalary(input)
+			return err == nil
+		},
+		errorMessage: "Введите положительное число и, при необходимости, валюту, например \"3000 USD\", " +
+			"или \"" + noSalaryInput + "\"",
+	})
+	return input
+}
+
+func parseSalary(input string) (int, string, error) {
+
+	input = strings.TrimSpace(input)
+	if input == noSalaryInput {
+		return 0, "", nil
+	}
+
+	amountEnd := strings.LastIndexAny(input, "0123456789") + 1
+	amount, err := strconv.Atoi(strings.ReplaceAll(input[:amountEnd], " ", ""))
+	if err != nil {
+		return 0, "", err
+	}
+	if amount <= 0 {
+		return 0, "", errors.New("salary must be positive")
+	}
+
+	currency, err := parseCurrency(input[amountEnd:])
+	if err != nil {
+		return 0, "", err
+	}
+	return amount, currency, nil
+}
+
+func parseCurrency(input string) (string, error) {
+
+	input = strings.ToUpper(strings.TrimSpace(input))
+	switch input {
+	case "":
+		return "", nil
+	case "₽", "Р", "РУБ", "RUB", models.CurrencyRUR:
+		return models.CurrencyRUR, nil
+	case "$":
+		return "USD", nil
+	case "€":
+		return "EUR", nil
+	}
+
+	if !currencyCodeRegexp.MatchString(input) {
+		return "", errors.New("invalid currency")
+	}
+	return input, nil
+}
+
+type onlyWithSalaryInput struct {
+	chatID   int64
+	onFinish func(onlyWithSalary bool)
+}
+
+func newOnlyWithSalaryInput(chatID int64, onFinish func(onlyWithSalary bool)) *onlyWithSalaryInput {
+	return &onlyWithSalaryInput{chatID: chatID, onFinish: onFinish}
+}
+
+func (o *onlyWithSalaryInput) InitMessage() botApi.Chattable {
+	msg := botApi.NewMessage(o.chatID, "Показывать вакансии, в которых не указана зарплата?")
+	msg.ReplyMarkup = botApi.NewReplyKeyboard(
+		botApi.NewKeyboardButtonRow(
+			botApi.NewKeyboardButton(string(onlyWithSalary)),
+			botApi.NewKeyboardButton(string(anySalary)),
+		),
+		botApi.NewKeyboardButtonRow(
+			botApi.NewKeyboardButton(backToMenuCommandName),
+		))
+	return msg
+}
+
+func (o *onlyWithSalaryInput) HandleInput(input string) botApi.Chattable {
+
+	switch salaryFilterOption(input) {
+	case onlyWithSalary:
+		o.onFinish(true)
+	case anySalary:
+		o.onFinish(false)
+	default:
+		return botApi.NewMessage(o.chatID, "Неправильный ввод 😔.")
+	}
+	return nil
+}
+
+func salaryFilterToText(search models.JobSearch) string {
+
+	text := "зарплата не важна"
+	if search.Salary > 0 {
+		currency := search.Currency
+		if currency == "" {
+			currency = models.CurrencyRUR
+		}
+		text = "зарплата от " + formatAmount(search.Salary) + " " + currencyToText(currency)
+	}
+
+	if search.OnlyWithSalary {
+		text += ", только с указанной зарплатой"
+	}
+	return text
+}
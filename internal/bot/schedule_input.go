@@ -7,55 +7,68 @@ import (
 	"strings"
 )
 
+// scheduleInput asks for work formats when hh.ru provides them and falls back to deprecated schedules otherwise
 type scheduleInput struct {
-	chatID   int64
-	onFinish func(schedules []models.Schedule)
+	chatID      int64
+	workFormats bool
+	options     []models.DictionaryItem
+	onFinish    func(schedules []models.Schedule, workFormats []string)
 }
 
-func newScheduleInput(chatID int64, onFinish func(schedules []models.Schedule)) *scheduleInput {
-	return &scheduleInput{chatID: chatID, onFinish: onFinish}
+func newScheduleInput(chatID int64, dictionaries models.Dictionaries,
+	onFinish func(schedules []models.Schedule, workFormats []string)) *scheduleInput {
+
+	input := &scheduleInput{chatID: chatID, onFinish: onFinish}
+	if formats := dictionaries[models.WorkFormatDictionary]; len(formats) > 0 {
+		input.workFormats = true
+		input.options = formats
+	} else {
+		input.options = dictionaries[models.ScheduleDictionary]
+	}
+	return input
 }
 
 func (a *scheduleInput) InitMessage() botApi.Chattable {
-	msg := botApi.NewMessage(a.chatID, "Введите желаемый график работы.\n"+
-		"0 - без разницы, 1 - полный день, 2 - гибкий график, 3 - удалённая работа\n"+
-		"также можно комбинировать: \"2, 3\"")
+
+	text := "Введите желаемый график работы.\n0 - без разницы"
+	if a.workFormats {
+		text = "Введите желаемый формат работы.\n0 - без разницы"
+	}
+	for i, option := range a.options {
+		text += ", " + strconv.Itoa(i+1) + " - " + strings.ToLower(option.Name)
+	}
+	text += "\nтакже можно комбинировать: \"1, 2\""
+
+	msg := botApi.NewMessage(a.chatID, text)
 	msg.ReplyMarkup = keyboardWithExit()
 	return msg
 }
 
 func (a *scheduleInput) HandleInput(input string) botApi.Chattable {
 
-	schedules := strings.Split(input, ",")
-
 	if input == "0" {
-		a.onFinish(nil)
+		a.onFinish(nil, nil)
 		return nil
 	}
 
-	for i := 0; i < len(schedules); i++ {
-		schedules[i] = strings.TrimSpace(schedules[i])
-		_, err := strconv.Atoi(schedules[i])
-		if schedules[i] == "" || err != nil {
+	var ids []string
+	for _, number := range strings.Split(input, ",") {
+		idx, err := strconv.Atoi(strings.TrimSpace(number))
+		if err != nil || idx < 1 || idx > len(a.options) {
 			return botApi.NewMessage(a.chatID, "Неверный ввод.")
 		}
+		ids = append(ids, a.options[idx-1].ID)
 	}
 
-	var res []models.Schedule
-
-	for i := 0; i < len(schedules); i++ {
-		switch schedules[i] {
-		case "1":
-			res = append(res, models.FullDay)
-		case "2":
-			res = append(res, models.Flexible)
-		case "3":
-			res = append(res, models.Remote)
-		default:
-			return botApi.NewMessage(a.chatID, "Неверный ввод.")
-		}
+	if a.workFormats {
+		a.onFinish(nil, ids)
+		return nil
 	}
 
-	a.onFinish(res)
+	schedules := make([]models.Schedule, 0, len(ids))
+	for _, id := range ids {
+		schedules = append(schedules, models.Schedule(id))
+	}
+	a.onFinish(schedules, nil)
 	return nil
 }
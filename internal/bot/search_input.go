@@ -2,13 +2,13 @@ package bot
 
 import (
 	"context"
-	"fmt"
 	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/maxaizer/hh-parser/internal/logger"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"strconv"
+	"strings"
 )
 
 var errorNoUserSearches = errors.New("user has no searches")
@@ -17,10 +17,12 @@ type searchInput struct {
 	chatID       int64
 	searches     searchRepository
 	userSearches []models.JobSearch
+	dictionaries models.Dictionaries
 	onFinish     func(search *models.JobSearch)
 }
 
-func newSearchInput(chatID int64, searchRepo searchRepository, onFinish func(search *models.JobSearch)) (*searchInput, error) {
+func newSearchInput(chatID int64, searchRepo searchRepository, dictionaries models.Dictionaries,
+	onFinish func(search *models.JobSearch)) (*searchInput, error) {
 	userSearches, err := searchRepo.GetByUser(context.Background(), chatID)
 	if err != nil {
 		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Error(err)
@@ -29,7 +31,8 @@ func newSearchInput(chatID int64, searchRepo searchRepository, onFinish func(sea
 	if len(userSearches) == 0 {
 		return nil, errorNoUserSearches
 	}
-	return &searchInput{chatID: chatID, searches: searchRepo, userSearches: userSearches, onFinish: onFinish}, nil
+	return &searchInput{chatID: chatID, searches: searchRepo, userSearches: userSearches,
+		dictionaries: dictionaries, onFinish: onFinish}, nil
 }
 
 func (s *searchInput) InitMessage() botApi.Chattable {
@@ -66,27 +69,30 @@ func (s *searchInput) searchesToText(searches []models.JobSearch) (text string)
 			text += ", регион не важен"
 		}
 
-		experience, err := experienceToText(searches[i].Experience)
-		if err != nil {
-			log.Errorf(err.Error())
-		} else {
-			text += ", " + experience
-		}
+		text += ", опыт: " + s.dictionaryName(models.ExperienceDictionary, string(searches[i].Experience))
 
-		if searches[i].Schedules == "" {
+		if searches[i].Schedules == "" && len(searches[i].WorkFormats) == 0 {
 			text += ", график работы не важен"
 		}
 
 		for _, schedule := range searches[i].SchedulesAsArray() {
-			schedule, err := scheduleToText(schedule)
-			if err != nil {
-				log.Errorf(err.Error())
-			} else {
-				text += ", " + schedule
-			}
+			text += ", " + s.dictionaryName(models.ScheduleDictionary, string(schedule))
+		}
+		for _, workFormat := range searches[i].WorkFormats {
+			text += ", " + s.dictionaryName(models.WorkFormatDictionary, workFormat)
 		}
 
+		text += ", " + salaryFilterToText(searches[i])
 		text += ", пожелание: \"" + searches[i].UserWish + "\""
+		text += ", правила: " + rulesToText(searches[i].Rules)
+		text += ", расширенные фильтры: " + filtersToText(searches[i].Filters, s.dictionaries)
+
+		strictness, err := strictnessToText(searches[i].Strictness)
+		if err != nil {
+			log.Errorf(err.Error())
+		} else {
+			text += ", " + strictness
+		}
 
 		createdAt := searches[i].CreatedAt.Format("2006-01-02 15:04:05")
 		text += ", создан " + createdAt + "\n"
@@ -94,30 +100,10 @@ func (s *searchInput) searchesToText(searches []models.JobSearch) (text string)
 	return text
 }
 
-func scheduleToText(schedule models.Schedule) (string, error) {
-	switch schedule {
-	case models.FullDay:
-		return "полный день", nil
-	case models.Flexible:
-		return "гибкий график", nil
-	case models.Remote:
-		return "удалённая работа", nil
-	default:
-		return "", fmt.Errorf("invalid schedule: %s", schedule)
-	}
-}
-
-func experienceToText(experience models.Experience) (string, error) {
-	switch experience {
-	case models.NoExperience:
-		return "без опыта", nil
-	case models.Between1and3:
-		return "опыт от 1 до 3 лет", nil
-	case models.Between3and6:
-		return "опыт от 3 до 6 лет", nil
-	case models.MoreThan6:
-		return "опыт от 6 лет", nil
-	default:
-		return "", fmt.Errorf("invalid experience: %s", experience)
+// dictionaryName falls back to the raw id, so values removed from hh.ru are still shown
+func (s *searchInput) dictionaryName(dictionary string, id string) string {
+	if name, ok := s.dictionaries.NameOf(dictionary, id); ok {
+		return strings.ToLower(name)
 	}
+	return id
 }
@@ -0,0 +1,77 @@
+package bot
+
+import (
+	"fmt"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+)
+
+type strictnessLevel string
+
+const (
+	strictLevel  strictnessLevel = "Строго"
+	normalLevel  strictnessLevel = "Обычно"
+	lenientLevel strictnessLevel = "Мягко"
+)
+
+type strictnessInput struct {
+	chatID   int64
+	onFinish func(strictness models.Strictness)
+}
+
+func newStrictnessInput(chatID int64, onFinish func(strictness models.Strictness)) *strictnessInput {
+	return &strictnessInput{chatID: chatID, onFinish: onFinish}
+}
+
+func (s *strictnessInput) InitMessage() botApi.Chattable {
+	msg := botApi.NewMessage(s.chatID, "Выберите строгость отбора вакансий.\n"+
+		string(strictLevel)+" - только вакансии, в соответствии которых ИИ уверен\n"+
+		string(normalLevel)+" - вакансии, которые скорее подходят\n"+
+		string(lenientLevel)+" - всё, что может подойти, даже с сомнениями")
+	msg.ReplyMarkup = strictnessKeyboard()
+	return msg
+}
+
+func (s *strictnessInput) HandleInput(input string) botApi.Chattable {
+
+	var strictness models.Strictness
+
+	switch strictnessLevel(input) {
+	case strictLevel:
+		strictness = models.StrictnessStrict
+	case normalLevel:
+		strictness = models.StrictnessNormal
+	case lenientLevel:
+		strictness = models.StrictnessLenient
+	default:
+		return botApi.NewMessage(s.chatID, "Неправильный ввод 😔.")
+	}
+
+	s.onFinish(strictness)
+	return nil
+}
+
+func strictnessToText(strictness models.Strictness) (string, error) {
+	switch strictness {
+	case models.StrictnessStrict:
+		return "строгий отбор", nil
+	case models.StrictnessNormal, "":
+		return "обычный отбор", nil
+	case models.StrictnessLenient:
+		return "мягкий отбор", nil
+	default:
+		return "", fmt.Errorf("invalid strictness: %s", strictness)
+	}
+}
+
+func strictnessKeyboard() botApi.ReplyKeyboardMarkup {
+	return botApi.NewReplyKeyboard(
+		botApi.NewKeyboardButtonRow(
+			botApi.NewKeyboardButton(string(strictLevel)),
+			botApi.NewKeyboardButton(string(normalLevel)),
+			botApi.NewKeyboardButton(string(lenientLevel)),
+		),
+		botApi.NewKeyboardButtonRow(
+			botApi.NewKeyboardButton(backToMenuCommandName),
+		))
+}
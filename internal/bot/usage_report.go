@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	usageReportCommandName = "usage"
+	defaultUsageReportDays = 1
+	maxUsageReportDays     = 90
+)
+
+func usageReportPeriod(args string, now time.Time) (string, error) {
+
+	days := defaultUsageReportDays
+	if args = strings.TrimSpace(args); args != "" {
+		var err error
+		days, err = strconv.Atoi(args)
+		if err != nil || days < 1 || days > maxUsageReportDays {
+			return "", fmt.Errorf("invalid number of days: %q", args)
+		}
+	}
+	return models.UsageDay(now.AddDate(0, 0, -(days - 1))), nil
+}
+
+func usageReportText(fromDay string, usage []models.AIUsage) string {
+
+	if len(usage) == 0 {
+		return fmt.Sprintf("С %s ИИ не использовался", fromDay)
+	}
+
+	var total models.AIUsage
+	users := make(map[int64]*models.AIUsage)
+	var userIDs []int64
+	for _, item := range usage {
+		total.Requests += item.Requests
+		total.PromptTokens += item.PromptTokens
+		total.CandidateTokens += item.CandidateTokens
+
+		user, ok := users[item.UserID]
+		if !ok {
+			user = &models.AIUsage{UserID: item.UserID}
+			users[item.UserID] = user
+			userIDs = append(userIDs, item.UserID)
+		}
+		user.Requests += item.Requests
+		user.PromptTokens += item.PromptTokens
+		user.CandidateTokens += item.CandidateTokens
+	}
+
+	text := fmt.Sprintf("Расход ИИ с %s: %s", fromDay, usageToText(total))
+	for _, userID := range userIDs {
+		text += fmt.Sprintf("\n\nПользователь %d: %s", userID, usageToText(*users[userID]))
+		for _, item := range usage {
+			if item.UserID == userID {
+				text += fmt.Sprintf("\n  поиск %d: %s", item.SearchID, usageToText(item))
+			}
+		}
+	}
+	return text
+}
+
+func usageToText(usage models.AIUsage) string {
+	return fmt.Sprintf("%d токенов (%d на запрос, %d на ответ), запросов: %d",
+		usage.TotalTokens(), usage.PromptTokens, usage.CandidateTokens, usage.Requests)
+}
@@ -0,0 +1,136 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	botApi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/metrics"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const (
+	useImprovedWishButton  = "Взять предложенное"
+	keepOriginalWishButton = "Оставить как есть"
+	wishClarifyTimeout     = 30 * time.Second
+)
+
+type wishClarifier interface {
+	ClarifyWish(ctx context.Context, userID int64, wish string) (models.WishClarification, error)
+}
+
+type wishInput struct {
+	api           apiInterface
+	chatID        int64
+	clarifier     wishClarifier
+	onFinish      func(wish string)
+	original      string
+	clarification *models.WishClarification
+}
+
+func newWishInput(api apiInterface, chatID int64, clarifier wishClarifier, onFinish func(wish string)) *wishInput {
+	return &wishInput{api: api, chatID: chatID, clarifier: clarifier, onFinish: onFinish}
+}
+
+func (w *wishInput) InitMessage() botApi.Chattable {
+	w.clarification = nil
+	msg := botApi.NewMessage(w.chatID, "Укажите пожелания к вакансии в свободной форме.\n"+
+		"Например, \"чтобы соответствовала C# backend разработчику\" или \"хочу вкусняшки в офисе\"")
+	msg.ReplyMarkup = keyboardWithExit()
+	return msg
+}
+
+func (w *wishInput) HandleInput(input string) botApi.Chattable {
+
+	if w.clarification != nil {
+		switch {
+		case input == keepOriginalWishButton:
+			metrics.WishClarificationsCounter.WithLabelValues("original_kept").Inc()
+			w.finish(w.original)
+			return nil
+		case input == useImprovedWishButton && w.clarification.ImprovedWish != "":
+			metrics.WishClarificationsCounter.WithLabelValues("improved_accepted").Inc()
+			w.finish(w.clarification.ImprovedWish)
+			return nil
+		}
+	}
+
+	// the choice state isn't persisted, so after a restart a button press can arrive without a pending clarification
+	if input == keepOriginalWishButton || input == useImprovedWishButton || strings.TrimSpace(input) == "" {
+		return botApi.NewMessage(w.chatID, "Введите пожелание к вакансии.")
+	}
+
+	return w.clarify(input)
+}
+
+func (w *wishInput) clarify(wish string) botApi.Chattable {
+
+	if w.clarifier == nil {
+		w.finish(wish)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), wishClarifyTimeout)
+	defer cancel()
+
+	clarification, err := w.clarifier.ClarifyWish(ctx, w.chatID, wish)
+	if err != nil {
+		if errors.Is(err, ai.ErrQuotaExhausted) {
+			log.Warnf("wish clarification is unavailable: %v", err)
+		} else {
+			log.Errorf("failed to clarify wish: %v", err)
+		}
+		metrics.WishClarificationsCounter.WithLabelValues("unavailable").Inc()
+		_, _ = sendWithLogError(w.api, botApi.NewMessage(w.chatID,
+			"Сейчас не получилось проверить пожелание, сохраняем его как есть."))
+		w.finish(wish)
+		return nil
+	}
+
+	if clarification.IsClear {
+		metrics.WishClarificationsCounter.WithLabelValues("clear").Inc()
+		w.finish(wish)
+		return nil
+	}
+
+	metrics.WishClarificationsCounter.WithLabelValues("suggested").Inc()
+	w.original = wish
+	w.clarification = &clarification
+	return w.clarificationMessage()
+}
+
+func (w *wishInput) clarificationMessage() botApi.Chattable {
+
+	text := "Пожелание выглядит расплывчатым, из-за этого вакансии могут отбираться неточно."
+	if len(w.clarification.Questions) > 0 {
+		text += "\n\nЧто стоит уточнить:"
+		for _, question := range w.clarification.Questions {
+			text += "\n- " + question
+		}
+	}
+
+	var rows [][]botApi.KeyboardButton
+	if w.clarification.ImprovedWish != "" {
+		text += "\n\nПредлагаемая формулировка:\n" + w.clarification.ImprovedWish
+		rows = append(rows, botApi.NewKeyboardButtonRow(botApi.NewKeyboardButton(useImprovedWishButton)))
+	}
+	text += "\n\nВыберите вариант кнопкой или отправьте уточнённое пожелание."
+
+	rows = append(rows,
+		botApi.NewKeyboardButtonRow(botApi.NewKeyboardButton(keepOriginalWishButton)),
+		botApi.NewKeyboardButtonRow(botApi.NewKeyboardButton(backToMenuCommandName)),
+	)
+
+	msg := botApi.NewMessage(w.chatID, text)
+	msg.ReplyMarkup = botApi.NewReplyKeyboard(rows...)
+	return msg
+}
+
+func (w *wishInput) finish(wish string) {
+	w.original = ""
+	w.clarification = nil
+	w.onFinish(wish)
+}
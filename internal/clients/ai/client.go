@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Client interface {
+	GenerateResponse(ctx context.Context, text string) (Response, error)
+	GenerateJSONResponse(ctx context.Context, text string, schema *Schema) (Response, error)
+	Model() string
+	SetMinuteRateLimit(maxRequestsPerMinute float32)
+	SetDayQuota(quota *DayQuota)
+}
+
+type ProviderConfig struct {
+	Provider             string
+	BaseURL              string
+	APIKey               string
+	Model                string
+	EmbeddingModel       string
+	MaxRequestsPerMinute float32
+	MaxRequestsPerDay    float32
+	QuotaStore           QuotaStore
+	QuotaLocation        *time.Location
+}
+
+type Factory func(ctx context.Context, cfg ProviderConfig) (Client, error)
+
+type Registry struct {
+	factories map[string]Factory
+}
+
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+func (r *Registry) Register(provider string, factory Factory) {
+	r.factories[provider] = factory
+}
+
+func (r *Registry) NewClient(ctx context.Context, cfg ProviderConfig) (Client, error) {
+
+	factory, ok := r.factories[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown AI provider: %q", cfg.Provider)
+	}
+
+	client, err := factory(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("can't create %s client: %w", cfg.Provider, err)
+	}
+
+	if cfg.MaxRequestsPerMinute > 0 {
+		client.SetMinuteRateLimit(cfg.MaxRequestsPerMinute)
+	}
+	if cfg.MaxRequestsPerDay > 0 {
+		key := cfg.Provider + "/" + cfg.Model
+		client.SetDayQuota(NewDayQuota(cfg.Provider, key, int(cfg.MaxRequestsPerDay), cfg.QuotaStore, cfg.QuotaLocation))
+	}
+	return client, nil
+}
+
+func (r *Registry) NewFallbackClient(ctx context.Context, configs []ProviderConfig) (Client, error) {
+
+	if len(configs) == 1 {
+		return r.NewClient(ctx, configs[0])
+	}
+
+	clients := make([]Client, 0, len(configs))
+	for _, cfg := range configs {
+		client, err := r.NewClient(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return NewFallbackClient(clients...)
+}
@@ -0,0 +1,17 @@
+package ai
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_CosineSimilarity(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.InDelta(1, CosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(0, CosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.InDelta(-1, CosineSimilarity([]float32{1, 0}, []float32{-1, 0}), 1e-9)
+	assert.Equal(0.0, CosineSimilarity([]float32{1}, []float32{1, 0}))
+	assert.Equal(0.0, CosineSimilarity([]float32{0, 0}, []float32{1, 0}))
+}
@@ -0,0 +1,195 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/metrics"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+const (
+	failuresBeforeFallback = 3
+	failedClientCooldown   = 10 * time.Minute
+)
+
+type fallbackEntry struct {
+	client           Client
+	failures         int
+	unavailableUntil time.Time
+}
+
+type FallbackClient struct {
+	mu      sync.Mutex
+	entries []*fallbackEntry
+	active  int
+	now     func() time.Time
+}
+
+func NewFallbackClient(clients ...Client) (*FallbackClient, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("no AI clients for fallback chain")
+	}
+
+	entries := make([]*fallbackEntry, 0, len(clients))
+	for _, client := range clients {
+		entries = append(entries, &fallbackEntry{client: client})
+	}
+	return &FallbackClient{entries: entries, now: time.Now}, nil
+}
+
+func (f *FallbackClient) Model() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.entries[f.firstAvailable()].client.Model()
+}
+
+// Embed always uses the primary client, so that all stored embeddings share the same vector space
+func (f *FallbackClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embedder, ok := f.entries[0].client.(Embedder)
+	if !ok {
+		return nil, ErrEmbeddingsNotConfigured
+	}
+	return embedder.Embed(ctx, texts)
+}
+
+func (f *FallbackClient) EmbeddingModel() string {
+	if embedder, ok := f.entries[0].client.(Embedder); ok {
+		return embedder.EmbeddingModel()
+	}
+	return ""
+}
+
+func (f *FallbackClient) SetMinuteRateLimit(maxRequestsPerMinute float32) {
+	f.entries[0].client.SetMinuteRateLimit(maxRequestsPerMinute)
+}
+
+func (f *FallbackClient) SetDayQuota(quota *DayQuota) {
+	f.entries[0].client.SetDayQuota(quota)
+}
+
+func (f *FallbackClient) GenerateResponse(ctx context.Context, text string) (Response, error) {
+	return f.generate(ctx, func(client Client) (Response, error) {
+		return client.GenerateResponse(ctx, text)
+	})
+}
+
+func (f *FallbackClient) GenerateJSONResponse(ctx context.Context, text string, schema *Schema) (Response, error) {
+	return f.generate(ctx, func(client Client) (Response, error) {
+		return client.GenerateJSONResponse(ctx, text, schema)
+	})
+}
+
+func (f *FallbackClient) generate(ctx context.Context, request func(client Client) (Response, error)) (Response, error) {
+
+	var lastErr error
+	for i := 0; i < len(f.entries); i++ {
+
+		f.mu.Lock()
+		index, ok := f.nextAvailable(i)
+		f.mu.Unlock()
+		if !ok {
+			break
+		}
+		i = index
+
+		entry := f.entries[index]
+		resp, err := request(entry.client)
+		if err == nil {
+			f.onSuccess(index)
+			if resp.Model == "" {
+				resp.Model = entry.client.Model()
+			}
+			return resp, nil
+		}
+
+		if ctx.Err() != nil {
+			return resp, err
+		}
+
+		lastErr = err
+		if !f.onFailure(index, err) {
+			return resp, err
+		}
+	}
+
+	if lastErr == nil || errors.Is(lastErr, ErrQuotaExhausted) {
+		return Response{}, f.exhaustedError()
+	}
+	return Response{}, lastErr
+}
+
+func (f *FallbackClient) onSuccess(index int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.entries[index].failures = 0
+	f.switchTo(index)
+}
+
+// onFailure returns true if the request should be retried on the next client of the chain
+func (f *FallbackClient) onFailure(index int, err error) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry := f.entries[index]
+
+	var quotaErr *QuotaExhaustedError
+	if errors.As(err, &quotaErr) {
+		entry.unavailableUntil = quotaErr.ResetAt
+	} else {
+		entry.failures++
+		if entry.failures < failuresBeforeFallback {
+			return false
+		}
+		entry.unavailableUntil = f.now().Add(failedClientCooldown)
+	}
+
+	entry.failures = 0
+	log.Warnf("AI model %s is unavailable until %v: %v", entry.client.Model(), entry.unavailableUntil, err)
+	return index < len(f.entries)-1
+}
+
+func (f *FallbackClient) switchTo(index int) {
+	if f.active == index {
+		return
+	}
+
+	from, to := f.entries[f.active].client.Model(), f.entries[index].client.Model()
+	log.Infof("switching AI model from %s to %s", from, to)
+	metrics.AiModelSwitchesCounter.WithLabelValues(from, to).Inc()
+	f.active = index
+}
+
+func (f *FallbackClient) nextAvailable(from int) (int, bool) {
+	now := f.now()
+	for i := from; i < len(f.entries); i++ {
+		if !now.Before(f.entries[i].unavailableUntil) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func (f *FallbackClient) firstAvailable() int {
+	if index, ok := f.nextAvailable(0); ok {
+		return index
+	}
+	return f.active
+}
+
+func (f *FallbackClient) exhaustedError() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	resetAt := f.entries[0].unavailableUntil
+	for _, entry := range f.entries[1:] {
+		if entry.unavailableUntil.Before(resetAt) {
+			resetAt = entry.unavailableUntil
+		}
+	}
+	return &QuotaExhaustedError{Provider: "fallback chain", ResetAt: resetAt,
+		Err: fmt.Errorf("all %d AI models are unavailable", len(f.entries))}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type stubClient struct {
+	model    string
+	errs     []error
+	requests int
+}
+
+func (s *stubClient) GenerateResponse(ctx context.Context, text string) (Response, error) {
+	return s.GenerateJSONResponse(ctx, text, nil)
+}
+
+func (s *stubClient) GenerateJSONResponse(ctx context.Context, text string, schema *Schema) (Response, error) {
+	s.requests++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return Response{}, err
+		}
+	}
+	return Response{Text: "ok"}, nil
+}
+
+func (s *stubClient) Model() string {
+	return s.model
+}
+
+func (s *stubClient) SetMinuteRateLimit(maxRequestsPerMinute float32) {}
+
+func (s *stubClient) SetDayQuota(quota *DayQuota) {}
+
+func Test_FallbackClient_WhenPrimaryQuotaExhausted_ShouldSwitchUntilReset(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	resetAt := now.Add(time.Hour)
+
+	primary := &stubClient{model: "primary",
+		errs: []error{&QuotaExhaustedError{Provider: "gemini", ResetAt: resetAt, Err: ErrQuotaExhausted}}}
+	secondary := &stubClient{model: "secondary"}
+
+	client, err := NewFallbackClient(primary, secondary)
+	assert.NoError(err)
+	client.now = func() time.Time { return now }
+
+	resp, err := client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.NoError(err)
+	assert.Equal("secondary", resp.Model)
+	assert.Equal("secondary", client.Model())
+
+	_, err = client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.NoError(err)
+	assert.Equal(1, primary.requests)
+	assert.Equal(2, secondary.requests)
+
+	now = resetAt
+	resp, err = client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.NoError(err)
+	assert.Equal("primary", resp.Model)
+	assert.Equal(2, primary.requests)
+}
+
+func Test_FallbackClient_WhenPrimaryFailsRepeatedly_ShouldSwitch(t *testing.T) {
+
+	assert := assert.New(t)
+
+	failure := errors.New("internal error")
+	primary := &stubClient{model: "primary", errs: []error{failure, failure, failure}}
+	secondary := &stubClient{model: "secondary"}
+
+	client, err := NewFallbackClient(primary, secondary)
+	assert.NoError(err)
+
+	for i := 0; i < failuresBeforeFallback-1; i++ {
+		_, err = client.GenerateJSONResponse(context.Background(), "request", nil)
+		assert.ErrorIs(err, failure)
+	}
+
+	resp, err := client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.NoError(err)
+	assert.Equal("secondary", resp.Model)
+	assert.Equal(failuresBeforeFallback, primary.requests)
+}
+
+func Test_FallbackClient_WhenAllExhausted_ShouldReturnEarliestReset(t *testing.T) {
+
+	assert := assert.New(t)
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	primary := &stubClient{model: "primary",
+		errs: []error{&QuotaExhaustedError{ResetAt: now.Add(2 * time.Hour), Err: ErrQuotaExhausted}}}
+	secondary := &stubClient{model: "secondary",
+		errs: []error{&QuotaExhaustedError{ResetAt: now.Add(time.Hour), Err: ErrQuotaExhausted}}}
+
+	client, err := NewFallbackClient(primary, secondary)
+	assert.NoError(err)
+	client.now = func() time.Time { return now }
+
+	_, err = client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.ErrorIs(err, ErrQuotaExhausted)
+
+	var quotaErr *QuotaExhaustedError
+	assert.True(errors.As(err, &quotaErr))
+	assert.Equal(now.Add(time.Hour), quotaErr.ResetAt)
+
+	_, err = client.GenerateJSONResponse(context.Background(), "request", nil)
+	assert.ErrorIs(err, ErrQuotaExhausted)
+	assert.Equal(1, primary.requests)
+	assert.Equal(1, secondary.requests)
+}
@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"context"
+	"golang.org/x/time/rate"
+)
+
+type RateLimiter struct {
+	minuteRateLimiter *rate.Limiter
+	dayQuota          *DayQuota
+}
+
+func (l *RateLimiter) SetMinuteRateLimit(maxRequestsPerMinute float32) {
+	l.minuteRateLimiter = rate.NewLimiter(rate.Limit(maxRequestsPerMinute/60), 1)
+}
+
+func (l *RateLimiter) SetDayQuota(quota *DayQuota) {
+	l.dayQuota = quota
+}
+
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l.minuteRateLimiter != nil {
+		if err := l.minuteRateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if l.dayQuota != nil {
+		return l.dayQuota.Acquire(ctx)
+	}
+	return nil
+}
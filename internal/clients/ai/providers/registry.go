@@ -0,0 +1,14 @@
+package providers
+
+import (
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/clients/gemini"
+	"github.com/maxaizer/hh-parser/internal/clients/openai"
+)
+
+func NewRegistry() *ai.Registry {
+	registry := ai.NewRegistry()
+	registry.Register(gemini.ProviderName, gemini.NewProvider)
+	registry.Register(openai.ProviderName, openai.NewProvider)
+	return registry
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var ErrQuotaExhausted = errors.New("AI quota exhausted")
+
+type QuotaExhaustedError struct {
+	Provider string
+	ResetAt  time.Time
+	Err      error
+}
+
+func (e *QuotaExhaustedError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s quota exhausted until %v: %v", e.Provider, e.ResetAt, e.Err)
+	}
+	return fmt.Sprintf("%s quota exhausted until %v", e.Provider, e.ResetAt)
+}
+
+func (e *QuotaExhaustedError) Is(target error) bool {
+	return target == ErrQuotaExhausted
+}
+
+func (e *QuotaExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+type QuotaStore interface {
+	TryAcquire(ctx context.Context, key string, window string, limit int) (bool, error)
+}
+
+type DayQuota struct {
+	provider string
+	key      string
+	limit    int
+	store    QuotaStore
+	location *time.Location
+	now      func() time.Time
+}
+
+func NewDayQuota(provider string, key string, limit int, store QuotaStore, location *time.Location) *DayQuota {
+	if store == nil {
+		store = NewMemoryQuotaStore()
+	}
+	if location == nil {
+		location = time.UTC
+	}
+	return &DayQuota{provider: provider, key: key, limit: limit, store: store, location: location, now: time.Now}
+}
+
+func (q *DayQuota) Acquire(ctx context.Context) error {
+
+	now := q.now().In(q.location)
+	acquired, err := q.store.TryAcquire(ctx, q.key, now.Format(time.DateOnly), q.limit)
+	if err != nil {
+		return fmt.Errorf("can't check %s day quota: %w", q.provider, err)
+	}
+	if !acquired {
+		return &QuotaExhaustedError{Provider: q.provider, ResetAt: q.windowEnd(now)}
+	}
+	return nil
+}
+
+func (q *DayQuota) windowEnd(now time.Time) time.Time {
+	year, month, day := now.Date()
+	return time.Date(year, month, day+1, 0, 0, 0, 0, q.location)
+}
+
+type MemoryQuotaStore struct {
+	mu   sync.Mutex
+	used map[string]int
+}
+
+func NewMemoryQuotaStore() *MemoryQuotaStore {
+	return &MemoryQuotaStore{used: make(map[string]int)}
+}
+
+func (s *MemoryQuotaStore) TryAcquire(_ context.Context, key string, window string, limit int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := key + "/" + window
+	if s.used[id] >= limit {
+		return false, nil
+	}
+	s.used[id]++
+	return true, nil
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_DayQuota_WhenExhausted_ShouldReturnResetTimeAndResumeInNextWindow(t *testing.T) {
+
+	assert := assert.New(t)
+
+	location, err := time.LoadLocation("America/Los_Angeles")
+	assert.NoError(err)
+
+	now := time.Date(2025, 3, 10, 23, 30, 0, 0, location)
+	quota := NewDayQuota("gemini", "gemini/flash", 2, nil, location)
+	quota.now = func() time.Time { return now }
+
+	assert.NoError(quota.Acquire(context.Background()))
+	assert.NoError(quota.Acquire(context.Background()))
+
+	err = quota.Acquire(context.Background())
+	assert.ErrorIs(err, ErrQuotaExhausted)
+
+	var quotaErr *QuotaExhaustedError
+	assert.True(errors.As(err, &quotaErr))
+	assert.True(time.Date(2025, 3, 11, 0, 0, 0, 0, location).Equal(quotaErr.ResetAt))
+
+	now = now.Add(time.Hour)
+	assert.NoError(quota.Acquire(context.Background()))
+}
+
+func Test_DayQuota_ShouldShareUsageThroughStore(t *testing.T) {
+
+	store := NewMemoryQuotaStore()
+
+	first := NewDayQuota("gemini", "gemini/flash", 1, store, time.UTC)
+	assert.NoError(t, first.Acquire(context.Background()))
+
+	restarted := NewDayQuota("gemini", "gemini/flash", 1, store, time.UTC)
+	assert.ErrorIs(t, restarted.Acquire(context.Background()), ErrQuotaExhausted)
+
+	otherModel := NewDayQuota("gemini", "gemini/pro", 1, store, time.UTC)
+	assert.NoError(t, otherModel.Acquire(context.Background()))
+}
+
+type hintedError struct {
+	delay time.Duration
+}
+
+func (e hintedError) Error() string {
+	return "too many requests"
+}
+
+func (e hintedError) RetryAfter() (time.Duration, bool) {
+	return e.delay, true
+}
+
+func Test_WithRetry_ShouldHonorRetryHints(t *testing.T) {
+
+	assert := assert.New(t)
+	neverRetry := func(error) bool { return false }
+
+	calls := 0
+	resp, err := WithRetry(context.Background(), "test", func() (Response, error) {
+		calls++
+		if calls == 1 {
+			return Response{}, hintedError{delay: 10 * time.Millisecond}
+		}
+		return Response{Text: "ok"}, nil
+	}, neverRetry)
+	assert.NoError(err)
+	assert.Equal("ok", resp.Text)
+	assert.Equal(2, calls)
+
+	calls = 0
+	_, err = WithRetry(context.Background(), "test", func() (Response, error) {
+		calls++
+		return Response{}, hintedError{delay: time.Hour}
+	}, neverRetry)
+	assert.ErrorIs(err, ErrQuotaExhausted)
+	assert.Equal(1, calls)
+
+	calls = 0
+	_, err = WithRetry(context.Background(), "test", func() (Response, error) {
+		calls++
+		return Response{}, errors.New("bad request")
+	}, neverRetry)
+	assert.Error(err)
+	assert.Equal(1, calls)
+}
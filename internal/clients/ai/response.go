@@ -0,0 +1,16 @@
+package ai
+
+type Usage struct {
+	PromptTokens    int
+	CandidateTokens int
+}
+
+func (u Usage) Total() int {
+	return u.PromptTokens + u.CandidateTokens
+}
+
+type Response struct {
+	Text  string
+	Model string
+	Usage Usage
+}
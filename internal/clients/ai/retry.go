@@ -0,0 +1,67 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+const (
+	maxAttempts     = 3
+	delayBetweenTry = 2 * time.Second
+	maxRetryHint    = time.Minute
+)
+
+type RetryHintError interface {
+	error
+	RetryAfter() (time.Duration, bool)
+}
+
+func WithRetry[T any](ctx context.Context, provider string, attempt func() (T, error),
+	shouldRetry func(err error) bool) (T, error) {
+
+	var resp T
+	var err error
+
+	for i := 0; i < maxAttempts; i++ {
+		if i > 0 {
+			log.Warnf("%s api returned error, retrying: %v", provider, err)
+		}
+
+		resp, err = attempt()
+		if err == nil {
+			return resp, nil
+		}
+
+		delay := delayBetweenTry
+		if hint, ok := retryHint(err); ok {
+			if hint > maxRetryHint {
+				return resp, &QuotaExhaustedError{Provider: provider, ResetAt: time.Now().Add(hint), Err: err}
+			}
+			delay = hint
+		} else if !shouldRetry(err) {
+			return resp, err
+		}
+
+		if i == maxAttempts-1 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return resp, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return resp, err
+}
+
+func retryHint(err error) (time.Duration, bool) {
+	var hinted RetryHintError
+	if !errors.As(err, &hinted) {
+		return 0, false
+	}
+	return hinted.RetryAfter()
+}
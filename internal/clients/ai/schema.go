@@ -0,0 +1,21 @@
+package ai
+
+type Type string
+
+const (
+	TypeString  Type = "string"
+	TypeInteger Type = "integer"
+	TypeNumber  Type = "number"
+	TypeBoolean Type = "boolean"
+	TypeArray   Type = "array"
+	TypeObject  Type = "object"
+)
+
+type Schema struct {
+	Type        Type
+	Description string
+	Enum        []string
+	Items       *Schema
+	Properties  map[string]*Schema
+	Required    []string
+}
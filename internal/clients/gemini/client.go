@@ -2,22 +2,38 @@ package gemini
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/generative-ai-go/genai"
-	"github.com/samber/lo"
-	log "github.com/sirupsen/logrus"
-	"golang.org/x/time/rate"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"net/http"
 	"strings"
 	"time"
 )
 
+const ProviderName = "gemini"
+
 type Client struct {
-	client            *genai.Client
-	model             *genai.GenerativeModel
-	minuteRateLimiter *rate.Limiter
-	dayRateLimiter    *rate.Limiter
+	ai.RateLimiter
+	client         *genai.Client
+	modelName      string
+	model          *genai.GenerativeModel
+	embeddingModel string
+}
+
+const maxEmbeddingBatchSize = 100
+
+func NewProvider(ctx context.Context, cfg ai.ProviderConfig) (ai.Client, error) {
+	client, err := NewClient(ctx, cfg.APIKey, cfg.Model)
+	if err != nil {
+		return nil, err
+	}
+	client.SetEmbeddingModel(cfg.EmbeddingModel)
+	return client, nil
 }
 
 func NewClient(ctx context.Context, apiKey string, model string) (*Client, error) {
@@ -30,8 +46,9 @@ func NewClient(ctx context.Context, apiKey string, model string) (*Client, error
 	genModel := client.GenerativeModel(model)
 
 	wrapper := Client{
-		client: client,
-		model:  genModel,
+		client:    client,
+		modelName: model,
+		model:     genModel,
 	}
 
 	exists, err := wrapper.doesModelExist(ctx, model)
@@ -45,28 +62,72 @@ func NewClient(ctx context.Context, apiKey string, model string) (*Client, error
 	return &wrapper, nil
 }
 
-func (c *Client) SetMinuteRateLimit(maxRequestsPerMinute float32) {
-	c.minuteRateLimiter = rate.NewLimiter(rate.Limit(maxRequestsPerMinute/60), 1)
+func (c *Client) Model() string {
+	return c.modelName
+}
+
+func (c *Client) SetEmbeddingModel(model string) {
+	c.embeddingModel = model
 }
 
-func (c *Client) SetDayRateLimit(maxRequestsPerDay float32) {
-	c.dayRateLimiter = rate.NewLimiter(rate.Limit(maxRequestsPerDay/86400), int(maxRequestsPerDay))
+func (c *Client) EmbeddingModel() string {
+	return c.embeddingModel
 }
 
-func (c *Client) GenerateResponse(ctx context.Context, text string) (string, error) {
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+
+	if c.embeddingModel == "" {
+		return nil, ai.ErrEmbeddingsNotConfigured
+	}
+
+	model := c.client.EmbeddingModel(c.embeddingModel)
+	model.TaskType = genai.TaskTypeSemanticSimilarity
 
-	var resp string
-	var err error
+	result := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatchSize {
+		end := min(start+maxEmbeddingBatchSize, len(texts))
+
+		batch := model.NewBatch()
+		for _, text := range texts[start:end] {
+			batch.AddContent(genai.Text(text))
+		}
+
+		response, err := ai.WithRetry(ctx, ProviderName, func() (*genai.BatchEmbedContentsResponse, error) {
+			if err := c.Wait(ctx); err != nil {
+				return nil, err
+			}
+			response, err := model.BatchEmbedContents(ctx, batch)
+			return response, toRateLimitError(err)
+		}, isRetryable)
+		if err != nil {
+			return nil, err
+		}
 
-	_, _, _ = lo.AttemptWhileWithDelay(3, 2*time.Second, func(i int, _ time.Duration) (error, bool) {
-		if i > 0 {
-			log.Warn("gemini api returned 500 error, retrying...")
+		if len(response.Embeddings) != end-start {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(response.Embeddings), end-start)
 		}
-		resp, err = c.waitAndGenerateResponse(ctx, text)
-		return err, isInternalError(err)
-	})
+		for _, embedding := range response.Embeddings {
+			result = append(result, embedding.Values)
+		}
+	}
+	return result, nil
+}
+
+func (c *Client) GenerateResponse(ctx context.Context, text string) (ai.Response, error) {
+	return c.generateWithRetry(ctx, c.model, text)
+}
+
+func (c *Client) GenerateJSONResponse(ctx context.Context, text string, schema *ai.Schema) (ai.Response, error) {
+	model := c.client.GenerativeModel(c.modelName)
+	model.ResponseMIMEType = "application/json"
+	model.ResponseSchema = toGenaiSchema(schema)
+	return c.generateWithRetry(ctx, model, text)
+}
 
-	return resp, err
+func (c *Client) generateWithRetry(ctx context.Context, model *genai.GenerativeModel, text string) (ai.Response, error) {
+	return ai.WithRetry(ctx, ProviderName, func() (ai.Response, error) {
+		return c.waitAndGenerateResponse(ctx, model, text)
+	}, isRetryable)
 }
 
 func (c *Client) doesModelExist(ctx context.Context, name string) (bool, error) {
@@ -86,44 +147,128 @@ func (c *Client) doesModelExist(ctx context.Context, name string) (bool, error)
 	}
 }
 
-func (c *Client) waitAndGenerateResponse(ctx context.Context, text string) (string, error) {
+func (c *Client) waitAndGenerateResponse(ctx context.Context, model *genai.GenerativeModel, text string) (ai.Response, error) {
 
-	limiters := []*rate.Limiter{c.minuteRateLimiter, c.dayRateLimiter}
-	for _, limiter := range limiters {
-		if limiter != nil {
-			err := limiter.Wait(ctx)
-			if err != nil {
-				return "", err
-			}
-		}
+	if err := c.Wait(ctx); err != nil {
+		return ai.Response{}, err
 	}
 
-	resp, err := c.tryGenerateResponse(ctx, text)
-	if err != nil {
-		return "", err
-	}
-	return resp, nil
+	return c.tryGenerateResponse(ctx, model, text)
 }
 
-func (c *Client) tryGenerateResponse(ctx context.Context, text string) (string, error) {
+func (c *Client) tryGenerateResponse(ctx context.Context, model *genai.GenerativeModel, text string) (ai.Response, error) {
 
-	response, err := c.model.GenerateContent(ctx, genai.Text(text))
+	response, err := model.GenerateContent(ctx, genai.Text(text))
 	if err != nil {
-		return "", err
+		return ai.Response{}, toRateLimitError(err)
+	}
+
+	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil ||
+		len(response.Candidates[0].Content.Parts) == 0 {
+		return ai.Response{}, fmt.Errorf("response has no content")
 	}
 
 	part := response.Candidates[0].Content.Parts[0]
 
-	if textPart, ok := part.(genai.Text); ok {
-		return string(textPart), nil
+	textPart, ok := part.(genai.Text)
+	if !ok {
+		return ai.Response{}, fmt.Errorf("response part is not text")
+	}
+
+	result := ai.Response{Text: string(textPart), Model: c.modelName}
+	if response.UsageMetadata != nil {
+		result.Usage = ai.Usage{
+			PromptTokens:    int(response.UsageMetadata.PromptTokenCount),
+			CandidateTokens: int(response.UsageMetadata.CandidatesTokenCount),
+		}
+	}
+	return result, nil
+}
+
+type rateLimitError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *rateLimitError) Unwrap() error {
+	return e.err
+}
+
+func (e *rateLimitError) RetryAfter() (time.Duration, bool) {
+	return e.retryAfter, e.retryAfter > 0
+}
+
+func toRateLimitError(err error) error {
+
+	apiErr, ok := apierror.FromError(err)
+	if !ok {
+		return err
+	}
+
+	if apiErr.HTTPCode() != http.StatusTooManyRequests && apiErr.GRPCStatus().Code() != codes.ResourceExhausted {
+		return err
 	}
 
-	return "", fmt.Errorf("response part is not text")
+	result := &rateLimitError{err: err}
+	if retryInfo := apiErr.Details().RetryInfo; retryInfo != nil && retryInfo.GetRetryDelay() != nil {
+		result.retryAfter = retryInfo.GetRetryDelay().AsDuration()
+	}
+	return result
 }
 
-func isInternalError(err error) bool {
+func isRetryable(err error) bool {
 	if err == nil {
 		return false
 	}
+
+	var rateLimitErr *rateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return true
+	}
 	return strings.Contains(err.Error(), "Error 500")
 }
+
+func toGenaiSchema(schema *ai.Schema) *genai.Schema {
+	if schema == nil {
+		return nil
+	}
+
+	result := &genai.Schema{
+		Description: schema.Description,
+		Enum:        schema.Enum,
+		Items:       toGenaiSchema(schema.Items),
+		Required:    schema.Required,
+	}
+
+	switch schema.Type {
+	case ai.TypeString:
+		result.Type = genai.TypeString
+	case ai.TypeInteger:
+		result.Type = genai.TypeInteger
+	case ai.TypeNumber:
+		result.Type = genai.TypeNumber
+	case ai.TypeBoolean:
+		result.Type = genai.TypeBoolean
+	case ai.TypeArray:
+		result.Type = genai.TypeArray
+	case ai.TypeObject:
+		result.Type = genai.TypeObject
+	}
+
+	if len(schema.Enum) > 0 {
+		result.Format = "enum"
+	}
+
+	if len(schema.Properties) > 0 {
+		result.Properties = make(map[string]*genai.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			result.Properties[name] = toGenaiSchema(property)
+		}
+	}
+
+	return result
+}
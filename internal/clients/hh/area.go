@@ -1,8 +1,11 @@
 package hh
 
+// Area is a node of hh.ru areas tree, Level is its depth: countries have level 0, their regions 1 and so on
 type Area struct {
-	ID   string
-	Name string
+	ID       string
+	ParentID string
+	Name     string
+	Level    int
 }
 
 type area struct {
@@ -4,27 +4,66 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 	"io"
 	"net/http"
+	"strings"
 )
 
-type getVacanciesResponse struct {
+// VacanciesPage is a page of search results, Found is a total number of vacancies matching the search,
+// but only first MaxSearchResults of them can be paged through
+type VacanciesPage struct {
 	Vacancies []VacancyPreview `json:"items"`
+	Found     int
+	Pages     int
+	Page      int
+	PerPage   int `json:"per_page"`
 }
 
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+const defaultBaseURL = "https://api.hh.ru"
+
 type Client struct {
 	httpClient  HTTPClient
 	rateLimiter *rate.Limiter
+	retry       retryPolicy
+	baseURL     string
+	userAgent   string
+	tokens      tokenSource
 }
 
 func NewClient() *Client {
-	return &Client{httpClient: &http.Client{}}
+	return &Client{
+		httpClient: &http.Client{},
+		retry:      retryPolicy{maxAttempts: defaultMaxAttempts, baseDelay: defaultBaseDelay},
+		baseURL:    defaultBaseURL,
+	}
+}
+
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// SetUserAgent sets HH-User-Agent, hh.ru expects it in form "AppName/1.0 (contact@example.com)"
+func (c *Client) SetUserAgent(userAgent string) {
+	c.userAgent = userAgent
+}
+
+// SetAppToken sets an application token issued at dev.hh.ru, it doesn't expire and can't be refreshed
+func (c *Client) SetAppToken(token string) {
+	c.tokens = staticToken(token)
+}
+
+// SetClientCredentials makes client obtain an application token with client_credentials grant
+// and refresh it when it expires or gets rejected
+func (c *Client) SetClientCredentials(clientID, clientSecret string) {
+	c.tokens = &clientCredentials{client: c, clientID: clientID, clientSecret: clientSecret}
 }
 
 func (c *Client) SetHTTPClient(client HTTPClient) {
@@ -35,33 +74,41 @@ func (c *Client) SetRateLimit(maxRequestsPerSecond float32) {
 	c.rateLimiter = rate.NewLimiter(rate.Limit(maxRequestsPerSecond), 1)
 }
 
-func (c *Client) GetVacancies(parameters SearchParameters) ([]VacancyPreview, error) {
+func (c *Client) GetVacancies(ctx context.Context, parameters SearchParameters) ([]VacancyPreview, error) {
+	page, err := c.SearchVacancies(ctx, parameters)
+	if err != nil {
+		return nil, err
+	}
+	return page.Vacancies, nil
+}
+
+func (c *Client) SearchVacancies(ctx context.Context, parameters SearchParameters) (VacanciesPage, error) {
 
 	if err := parameters.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid parameters: %w", err)
+		return VacanciesPage{}, fmt.Errorf("invalid parameters: %w", err)
 	}
 
-	apiURL := "https://api.hh.ru/vacancies"
+	apiURL := c.baseURL + "/vacancies"
 	params := parameters.ToUrlParams()
 
-	body, err := c.sendRequest("GET", apiURL+"?"+params.Encode(), nil)
+	body, err := c.sendRequest(ctx, "GET", apiURL+"?"+params.Encode())
 	if err != nil {
-		return nil, err
+		return VacanciesPage{}, err
 	}
 
-	var vacanciesResponse getVacanciesResponse
-	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&vacanciesResponse); err != nil {
-		return nil, fmt.Errorf("error decoding JSON response:: %v", err)
+	var page VacanciesPage
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&page); err != nil {
+		return VacanciesPage{}, fmt.Errorf("error decoding JSON response:: %v", err)
 	}
 
-	return vacanciesResponse.Vacancies, nil
+	return page, nil
 }
 
-func (c *Client) GetVacancy(id string) (Vacancy, error) {
+func (c *Client) GetVacancy(ctx context.Context, id string) (Vacancy, error) {
 
-	apiURL := "https://api.hh.ru/vacancies/" + id
+	apiURL := c.baseURL + "/vacancies/" + id
 
-	body, err := c.sendRequest("GET", apiURL, nil)
+	body, err := c.sendRequest(ctx, "GET", apiURL)
 	if err != nil {
 		return Vacancy{}, err
 	}
@@ -74,11 +121,45 @@ func (c *Client) GetVacancy(id string) (Vacancy, error) {
 	return vacancyResponse, nil
 }
 
-func (c *Client) GetAreas() ([]Area, error) {
+func (c *Client) GetEmployer(ctx context.Context, id string) (EmployerDetails, error) {
+
+	apiURL := c.baseURL + "/employers/" + id
+
+	body, err := c.sendRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return EmployerDetails{}, err
+	}
+
+	var employer EmployerDetails
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&employer); err != nil {
+		return EmployerDetails{}, fmt.Errorf("error decoding JSON response: %v", err)
+	}
+
+	return employer, nil
+}
+
+func (c *Client) GetDictionaries(ctx context.Context) (Dictionaries, error) {
 
-	apiUrl := "https://api.hh.ru/areas"
+	apiURL := c.baseURL + "/dictionaries"
 
-	body, err := c.sendRequest("GET", apiUrl, nil)
+	body, err := c.sendRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+
+	var dictionaries Dictionaries
+	if err := json.NewDecoder(bytes.NewReader(body)).Decode(&dictionaries); err != nil {
+		return nil, fmt.Errorf("error decoding JSON response: %v", err)
+	}
+
+	return dictionaries, nil
+}
+
+func (c *Client) GetAreas(ctx context.Context) ([]Area, error) {
+
+	apiUrl := c.baseURL + "/areas"
+
+	body, err := c.sendRequest(ctx, "GET", apiUrl)
 	if err != nil {
 		return nil, err
 	}
@@ -90,40 +171,91 @@ func (c *Client) GetAreas() ([]Area, error) {
 
 	var allAreas []Area
 
-	var collectAreas func(areas []area)
-	collectAreas = func(areas []area) {
+	var collectAreas func(areas []area, level int)
+	collectAreas = func(areas []area, level int) {
 		for _, area := range areas {
-			allAreas = append(allAreas, Area{ID: area.ID, Name: area.Name})
-			collectAreas(area.Areas)
+			parentID := ""
+			if area.ParentID != nil {
+				parentID = *area.ParentID
+			}
+			allAreas = append(allAreas, Area{ID: area.ID, ParentID: parentID, Name: area.Name, Level: level})
+			collectAreas(area.Areas, level+1)
 		}
 	}
-	collectAreas(areas)
+	collectAreas(areas, 0)
 	return allAreas, nil
 }
 
-func (c *Client) sendRequest(method string, url string, body io.Reader) ([]byte, error) {
+func (c *Client) sendRequest(ctx context.Context, method string, url string) ([]byte, error) {
+
+	body, err := c.sendAuthorizedRequest(ctx, method, url)
+	if errors.Is(err, ErrUnauthorized) && c.tokens != nil && c.tokens.Invalidate() {
+		log.Warnf("hh app token was rejected, requesting a new one: %v", err)
+		body, err = c.sendAuthorizedRequest(ctx, method, url)
+	}
+	return body, err
+}
+
+// sendAuthorizedRequest gets the token before retries, so token request failures aren't retried twice
+func (c *Client) sendAuthorizedRequest(ctx context.Context, method string, url string) ([]byte, error) {
+
+	var token string
+	if c.tokens != nil {
+		var err error
+		if token, err = c.tokens.Token(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		return req, nil
+	})
+}
+
+// do sends requests made by newRequest through the rate limiter and retries them, a new request is made
+// for every attempt, so its body can be read again
+func (c *Client) do(ctx context.Context, newRequest func() (*http.Request, error)) ([]byte, error) {
+	return c.retry.do(ctx, func() ([]byte, error) {
+		return c.sendRequestOnce(ctx, newRequest)
+	})
+}
+
+func (c *Client) sendRequestOnce(ctx context.Context, newRequest func() (*http.Request, error)) ([]byte, error) {
 
 	if c.rateLimiter != nil {
-		err := c.rateLimiter.Wait(context.Background())
+		err := c.rateLimiter.Wait(ctx)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	req, err := http.NewRequest(method, url, body)
+	req, err := newRequest()
 	if err != nil {
-		return nil, fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
+	c.setUserAgent(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("error sending request: %v", err)
+		return nil, fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	return c.handleResponse(resp)
 }
 
+func (c *Client) setUserAgent(req *http.Request) {
+	req.Header.Set("HH-User-Agent", c.userAgent)
+	req.Header.Set("User-Agent", c.userAgent)
+}
+
 func (c *Client) handleResponse(resp *http.Response) ([]byte, error) {
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -131,7 +263,7 @@ func (c *Client) handleResponse(resp *http.Response) ([]byte, error) {
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("request failed with status %v, body: %v", resp.StatusCode, string(body))
+		return nil, newStatusError(resp, body)
 	}
 
 	return body, nil
@@ -2,12 +2,15 @@ package hh
 
 import (
 	"bytes"
+	"context"
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"io"
 	"net/http"
 	"os"
 	"testing"
+	"time"
 )
 
 type mockHTTPClient struct {
@@ -52,13 +55,13 @@ func Test_HHClient_GetVacancies_ShouldBeSuccessful(t *testing.T) {
 
 	params := SearchParameters{
 		Text:       "golang",
-		Experience: NoExperience,
-		Schedules:  []Schedule{FullDay},
+		Experience: "noExperience",
+		Schedules:  []Schedule{"fullDay"},
 		Page:       1,
 		PerPage:    10,
 		Period:     1,
 	}
-	vacancies, err := client.GetVacancies(params)
+	vacancies, err := client.GetVacancies(context.Background(), params)
 	assert.NoError(err)
 
 	assert.True(len(vacancies) == 2)
@@ -66,6 +69,44 @@ func Test_HHClient_GetVacancies_ShouldBeSuccessful(t *testing.T) {
 	assert.Equal(vacancies[0].Name, "Разработчик веб-приложений / фронтенд / верстальщик HTML (Junior)")
 	assert.Equal(vacancies[1].ID, "108122273")
 	assert.Equal(vacancies[1].Name, "Junior/Junior+ Golang developer")
+	assert.Equal("remote", vacancies[1].Schedule.ID)
+	assert.Equal("https://img.hhcdn.ru/employer-logo/4197921.png", vacancies[1].Employer.LogoUrls.Medium)
+	assert.Nil(vacancies[1].Salary)
+	assert.Nil(vacancies[1].Address)
+}
+
+func Test_HHClient_SearchVacancies_ShouldReturnTotals(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dateFrom := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	dateTo := time.Date(2024, 10, 2, 12, 0, 0, 0, time.UTC)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.Query().Get("date_from") == "2024-10-01T00:00:00+0000" &&
+			req.URL.Query().Get("date_to") == "2024-10-02T12:00:00+0000"
+	})).Return(getVacanciesMock())
+
+	client := NewClient()
+	client.SetHTTPClient(mockClient)
+
+	page, err := client.SearchVacancies(context.Background(), SearchParameters{Text: "golang", DateFrom: dateFrom,
+		DateTo: dateTo, PerPage: 2})
+	assert.NoError(err)
+	assert.Len(page.Vacancies, 2)
+	assert.Equal(6, page.Found)
+	assert.Equal(3, page.Pages)
+	assert.Equal(2, page.PerPage)
+
+	invalid := []SearchParameters{
+		{Text: "golang", DateFrom: dateTo, DateTo: dateFrom, PerPage: 2},
+		{Text: "golang", DateTo: dateTo, Period: 1, PerPage: 2},
+	}
+	for _, params := range invalid {
+		_, err = client.SearchVacancies(context.Background(), params)
+		assert.Error(err)
+	}
 }
 
 func Test_HHClient_GetVacancy_ShouldBeSuccessful(t *testing.T) {
@@ -81,8 +122,311 @@ func Test_HHClient_GetVacancy_ShouldBeSuccessful(t *testing.T) {
 	client := NewClient()
 	client.SetHTTPClient(mockClient)
 
-	vacancy, err := client.GetVacancy(vacancyID)
+	vacancy, err := client.GetVacancy(context.Background(), vacancyID)
 	assert.NoError(err)
 	assert.Equal(vacancy.ID, vacancyID)
 	assert.Equal(vacancy.Name, "Младший Back-end разработчик")
+
+	assert.Equal(&Salary{From: lo.ToPtr(80000), To: lo.ToPtr(100000), Currency: "RUR", Gross: lo.ToPtr(true)},
+		vacancy.Salary)
+	assert.Equal("370421", vacancy.Employer.ID)
+	assert.True(vacancy.Employer.Trusted)
+	assert.Equal("https://img.hhcdn.ru/employer-logo/5678901.png", vacancy.Employer.LogoUrls.Medium)
+	assert.Equal(&DictionaryItem{ID: "1", Name: "Москва"}, vacancy.Area)
+	assert.Equal("Москва, Долгопрудненское шоссе, 3", vacancy.Address.Raw)
+	assert.Len(vacancy.Address.MetroStations, 3)
+	assert.Equal("Лианозово", vacancy.Address.MetroStations[0].StationName)
+	assert.Equal("full", vacancy.Employment.ID)
+	assert.Equal("fullDay", vacancy.Schedule.ID)
+	assert.Equal("noExperience", vacancy.Experience.ID)
+	assert.Equal([]DictionaryItem{{ID: "96", Name: "Программист, разработчик"}}, vacancy.ProfessionalRoles)
+	assert.Equal([]Language{{ID: "eng", Name: "Английский", Level: DictionaryItem{ID: "b1", Name: "B1 — Средний"}}},
+		vacancy.Languages)
+	assert.False(vacancy.Archived)
+}
+
+func Test_HHClient_GetAreas_ShouldKeepHierarchy(t *testing.T) {
+
+	assert := assert.New(t)
+
+	file, err := os.ReadFile("testdata/get_areas.json")
+	assert.NoError(err)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "https://api.hh.ru/areas"
+	})).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewBuffer(file))}, nil)
+
+	client := NewClient()
+	client.SetHTTPClient(mockClient)
+
+	areas, err := client.GetAreas(context.Background())
+	assert.NoError(err)
+	assert.Equal([]Area{
+		{ID: "113", Name: "Россия"},
+		{ID: "1", ParentID: "113", Name: "Москва", Level: 1},
+		{ID: "1202", ParentID: "113", Name: "Новосибирская область", Level: 1},
+		{ID: "4", ParentID: "1202", Name: "Новосибирск", Level: 2},
+		{ID: "3218", ParentID: "1202", Name: "Бердск", Level: 2},
+		{ID: "16", Name: "Беларусь"},
+	}, areas)
+}
+
+func Test_HHClient_GetEmployer_ShouldBeSuccessful(t *testing.T) {
+
+	assert := assert.New(t)
+	employerID := "370421"
+
+	file, err := os.ReadFile("testdata/get_employer.json")
+	assert.NoError(err)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "https://api.hh.ru/employers/"+employerID
+	})).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewBuffer(file))}, nil)
+
+	client := NewClient()
+	client.SetHTTPClient(mockClient)
+
+	employer, err := client.GetEmployer(context.Background(), employerID)
+	assert.NoError(err)
+	assert.Equal(employerID, employer.ID)
+	assert.Equal("Роболайн", employer.Name)
+	assert.True(employer.Trusted)
+	assert.Equal("https://roboline.ru", employer.SiteUrl)
+	assert.Equal(12, employer.OpenVacancies)
+	assert.Equal(&DictionaryItem{ID: "1", Name: "Москва"}, employer.Area)
+	assert.Len(employer.Industries, 2)
+	assert.Equal(DictionaryItem{ID: "7.540", Name: "Разработка программного обеспечения"}, employer.Industries[0])
+}
+
+func Test_SearchParameters_ShouldAddSalary(t *testing.T) {
+
+	assert := assert.New(t)
+
+	params := SearchParameters{
+		Text:           "golang",
+		Experience:     "noExperience",
+		Salary:         250000,
+		Currency:       "RUR",
+		OnlyWithSalary: true,
+		PerPage:        10,
+	}
+	assert.NoError(params.Validate())
+	assert.Equal("currency=RUR&experience=noExperience&only_with_salary=true&page=0&perPage=10&salary=250000&"+
+		"text=golang", params.ToUrlParams().Encode())
+
+	params = SearchParameters{Text: "golang", Currency: "USD", PerPage: 10}
+	assert.Error(params.Validate())
+
+	params = SearchParameters{Text: "golang", Salary: -1, PerPage: 10}
+	assert.Error(params.Validate())
+}
+
+func Test_SearchParameters_ShouldAddFilters(t *testing.T) {
+
+	assert := assert.New(t)
+
+	params := SearchParameters{
+		Text:              "golang",
+		AreaIDs:           []string{"1", "2"},
+		Employments:       []Employment{"full", "project"},
+		ProfessionalRoles: []string{"96"},
+		Industries:        []string{"7.540"},
+		EmployerIDs:       []string{"1740"},
+		SearchFields:      []SearchField{"name"},
+		ExcludedText:      "1С битрикс",
+		Labels:            []Label{"not_from_agency"},
+		PerPage:           10,
+	}
+	assert.NoError(params.Validate())
+	assert.Equal("area=1&area=2&employer_id=1740&employment=full&employment=project&excluded_text=1%D0%A1+"+
+		"%D0%B1%D0%B8%D1%82%D1%80%D0%B8%D0%BA%D1%81&experience=&industry=7.540&label=not_from_agency&page=0&"+
+		"perPage=10&professional_role=96&search_field=name&text=golang", params.ToUrlParams().Encode())
+
+	invalid := []SearchParameters{
+		{AreaIDs: []string{"Москва"}, PerPage: 10},
+		{Industries: []string{"7."}, PerPage: 10},
+	}
+	for _, params := range invalid {
+		assert.Error(params.Validate(), params)
+	}
+}
+
+func Test_HHClient_GetDictionaries_ShouldBeSuccessful(t *testing.T) {
+
+	assert := assert.New(t)
+
+	file, err := os.ReadFile("testdata/get_dictionaries.json")
+	assert.NoError(err)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		return req.URL.String() == "https://api.hh.ru/dictionaries"
+	})).Return(&http.Response{StatusCode: 200, Body: io.NopCloser(bytes.NewBuffer(file))}, nil)
+
+	client := NewClient()
+	client.SetHTTPClient(mockClient)
+
+	dictionaries, err := client.GetDictionaries(context.Background())
+	assert.NoError(err)
+	assert.Len(dictionaries[ExperienceDictionary], 4)
+	assert.Equal(DictionaryItem{ID: "noExperience", Name: "Нет опыта"}, dictionaries[ExperienceDictionary][0])
+	assert.Len(dictionaries[ScheduleDictionary], 5)
+	assert.Equal(DictionaryItem{ID: "REMOTE", Name: "Удалённо"}, dictionaries[WorkFormatDictionary][1])
+	assert.Equal(DictionaryItem{ID: "USD", Name: "Доллары"}, dictionaries["currency"][1])
+}
+
+func Test_SearchParameters_ShouldDropValuesUnknownToDictionaries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dictionaries := Dictionaries{
+		ExperienceDictionary:  {{ID: "noExperience"}, {ID: "between1And3"}},
+		ScheduleDictionary:    {{ID: "fullDay"}, {ID: "remote"}},
+		WorkFormatDictionary:  {{ID: "REMOTE"}},
+		EmploymentDictionary:  {{ID: "full"}},
+		SearchFieldDictionary: {{ID: "name"}},
+		LabelDictionary:       {{ID: "not_from_agency"}},
+	}
+
+	valid := SearchParameters{
+		Experience:   "between1And3",
+		Schedules:    []Schedule{"remote"},
+		WorkFormats:  []WorkFormat{"REMOTE"},
+		Employments:  []Employment{"full"},
+		SearchFields: []SearchField{"name"},
+		Labels:       []Label{"not_from_agency"},
+	}
+	params := valid
+	assert.Empty(params.DropUnknownValues(dictionaries))
+	assert.Equal(valid, params)
+
+	params = SearchParameters{Experience: "moreThan10"}
+	assert.Empty(params.DropUnknownValues(Dictionaries{}), "values of not loaded dictionaries can't be checked")
+	assert.Equal(Experience("moreThan10"), params.Experience)
+
+	params = SearchParameters{
+		Experience:   "moreThan10",
+		Schedules:    []Schedule{"remote", "flyInFlyOut"},
+		WorkFormats:  []WorkFormat{"remote"},
+		Employments:  []Employment{"remote"},
+		SearchFields: []SearchField{"skills"},
+		Labels:       []Label{"cookies"},
+	}
+	assert.Len(params.DropUnknownValues(dictionaries), 6)
+	assert.Equal(SearchParameters{Schedules: []Schedule{"remote"}}, params)
+}
+
+func statusResponse(status int, body string, header http.Header) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(bytes.NewBufferString(body))}
+}
+
+func newTestClient(httpClient HTTPClient) *Client {
+	client := NewClient()
+	client.SetHTTPClient(httpClient)
+	client.retry.baseDelay = time.Millisecond
+	return client
+}
+
+func Test_HHClient_WhenTransientError_ShouldRetry(t *testing.T) {
+
+	assert := assert.New(t)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Return(statusResponse(http.StatusServiceUnavailable, "", nil), nil).Once()
+	mockClient.On("Do", mock.Anything).
+		Return(statusResponse(http.StatusTooManyRequests, "", http.Header{"Retry-After": []string{"0"}}), nil).Once()
+	mockClient.On("Do", mock.Anything).Return(getVacancyMock()).Once()
+
+	vacancy, err := newTestClient(mockClient).GetVacancy(context.Background(), "108444291")
+	assert.NoError(err)
+	assert.Equal("108444291", vacancy.ID)
+	mockClient.AssertExpectations(t)
+}
+
+func Test_HHClient_WhenRetriesExhausted_ShouldReturnTypedError(t *testing.T) {
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Return(statusResponse(http.StatusTooManyRequests, "", nil), nil)
+
+	_, err := newTestClient(mockClient).GetVacancy(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	mockClient.AssertNumberOfCalls(t, "Do", defaultMaxAttempts)
+}
+
+func Test_HHClient_WhenClientError_ShouldNotRetry(t *testing.T) {
+
+	assert := assert.New(t)
+
+	captchaBody := `{"errors": [{"type": "captcha_required", "value": "captcha_required", ` +
+		`"captcha_url": "https://hh.ru/account/captcha"}], "request_id": "1"}`
+	cases := []struct {
+		response *http.Response
+		expected error
+	}{
+		{statusResponse(http.StatusNotFound, `{"errors": [{"type": "not_found"}]}`, nil), ErrNotFound},
+		{statusResponse(http.StatusForbidden, `{"errors": [{"type": "forbidden"}]}`, nil), ErrForbidden},
+		{statusResponse(http.StatusForbidden, captchaBody, nil), ErrCaptchaRequired},
+		{statusResponse(http.StatusBadRequest, "", nil), ErrBadRequest},
+	}
+
+	for _, c := range cases {
+		mockClient := &mockHTTPClient{}
+		mockClient.On("Do", mock.Anything).Return(c.response, nil).Once()
+
+		_, err := newTestClient(mockClient).GetVacancy(context.Background(), "1")
+		assert.ErrorIs(err, c.expected)
+		mockClient.AssertExpectations(t)
+	}
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Return(statusResponse(http.StatusForbidden, captchaBody, nil), nil).Once()
+	_, err := newTestClient(mockClient).GetVacancy(context.Background(), "1")
+
+	var statusErr *StatusError
+	if assert.ErrorAs(err, &statusErr) {
+		assert.Equal("https://hh.ru/account/captcha", statusErr.CaptchaURL)
+	}
+}
+
+func Test_HHClient_WhenContextCanceled_ShouldStopRetrying(t *testing.T) {
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Run(func(mock.Arguments) { cancel() }).
+		Return(statusResponse(http.StatusServiceUnavailable, "", nil), nil)
+
+	client := newTestClient(mockClient)
+	client.retry.baseDelay = time.Hour
+
+	_, err := client.GetVacancy(ctx, "1")
+	assert.ErrorIs(t, err, context.Canceled)
+	mockClient.AssertNumberOfCalls(t, "Do", 1)
+}
+
+func Test_RetryPolicy_Backoff_ShouldGrowWithJitter(t *testing.T) {
+
+	policy := retryPolicy{maxAttempts: defaultMaxAttempts, baseDelay: time.Second}
+	for attempt := 0; attempt < 10; attempt++ {
+		expected := min(time.Second<<attempt, maxBackoffDelay)
+		delay := policy.backoff(attempt)
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.LessOrEqual(t, delay, expected)
+	}
+}
+
+func Test_HHClient_WhenRetryAfterIsTooLong_ShouldNotWait(t *testing.T) {
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).
+		Return(statusResponse(http.StatusTooManyRequests, "", http.Header{"Retry-After": []string{"3600"}}), nil)
+
+	_, err := newTestClient(mockClient).GetVacancy(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	mockClient.AssertNumberOfCalls(t, "Do", 1)
 }
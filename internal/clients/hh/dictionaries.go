@@ -0,0 +1,72 @@
+package hh
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+const (
+	ExperienceDictionary  = "experience"
+	ScheduleDictionary    = "schedule"
+	WorkFormatDictionary  = "work_format"
+	EmploymentDictionary  = "employment"
+	SearchFieldDictionary = "vacancy_search_fields"
+	LabelDictionary       = "vacancy_label"
+)
+
+// Dictionaries are values of hh.ru enums by dictionary name, as returned by /dictionaries
+type Dictionaries map[string][]DictionaryItem
+
+type dictionaryItem struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+	Name string `json:"name"`
+}
+
+func (d *Dictionaries) UnmarshalJSON(data []byte) error {
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*d = make(Dictionaries, len(raw))
+	for name, value := range raw {
+		var items []dictionaryItem
+		if err := json.Unmarshal(value, &items); err != nil {
+			continue //not a list of values, hh.ru may add such entries
+		}
+
+		for _, item := range items {
+			id := item.ID
+			if id == "" {
+				id = item.Code //currencies are identified by code
+			}
+			if id != "" {
+				(*d)[name] = append((*d)[name], DictionaryItem{ID: id, Name: item.Name})
+			}
+		}
+	}
+	return nil
+}
+
+// allows reports whether value is in the dictionary, values of unknown dictionaries are allowed
+func (d Dictionaries) allows(name string, id string) bool {
+	items := d[name]
+	return len(items) == 0 || slices.ContainsFunc(items, func(item DictionaryItem) bool {
+		return item.ID == id
+	})
+}
+
+func knownValues[T ~string](dictionaries Dictionaries, name string, values []T, dropped *[]string) []T {
+	var known []T
+	for _, value := range values {
+		if value != "" && !dictionaries.allows(name, string(value)) {
+			*dropped = append(*dropped, fmt.Sprintf("%s: %q", name, value))
+			continue
+		}
+		known = append(known, value)
+	}
+	return known
+}
@@ -0,0 +1,16 @@
+package hh
+
+type EmployerDetails struct {
+	ID            string           `json:"id"`
+	Name          string           `json:"name"`
+	Type          string           `json:"type"`
+	Trusted       bool             `json:"trusted"`
+	AccreditedIT  bool             `json:"accredited_it_employer"`
+	Description   string           `json:"description"`
+	SiteUrl       string           `json:"site_url"`
+	Url           string           `json:"alternate_url"`
+	Area          *DictionaryItem  `json:"area"`
+	Industries    []DictionaryItem `json:"industries"`
+	OpenVacancies int              `json:"open_vacancies"`
+	LogoUrls      *LogoUrls        `json:"logo_urls"`
+}
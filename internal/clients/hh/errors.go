@@ -0,0 +1,110 @@
+package hh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrNotFound        = errors.New("hh: not found")
+	ErrRateLimited     = errors.New("hh: rate limited")
+	ErrForbidden       = errors.New("hh: forbidden")
+	ErrUnauthorized    = errors.New("hh: unauthorized")
+	ErrCaptchaRequired = errors.New("hh: captcha required")
+	ErrBadRequest      = errors.New("hh: bad request")
+	ErrUnavailable     = errors.New("hh: service unavailable")
+)
+
+const (
+	captchaRequiredErrorType = "captcha_required"
+	oauthErrorType           = "oauth"
+)
+
+type StatusError struct {
+	StatusCode      int
+	Body            string
+	CaptchaURL      string
+	RetryAfterDelay time.Duration
+	kind            error
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %v, body: %v", e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.kind
+}
+
+func (e *StatusError) RetryAfter() (time.Duration, bool) {
+	return e.RetryAfterDelay, e.RetryAfterDelay > 0
+}
+
+func (e *StatusError) isRetryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+type errorResponse struct {
+	Errors []struct {
+		Type       string `json:"type"`
+		Value      string `json:"value"`
+		CaptchaURL string `json:"captcha_url"`
+	} `json:"errors"`
+}
+
+func newStatusError(resp *http.Response, body []byte) *StatusError {
+
+	statusErr := &StatusError{
+		StatusCode:      resp.StatusCode,
+		Body:            string(body),
+		RetryAfterDelay: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		statusErr.kind = ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized:
+		statusErr.kind = ErrUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests:
+		statusErr.kind = ErrRateLimited
+	case resp.StatusCode == http.StatusForbidden:
+		statusErr.kind = ErrForbidden
+		var parsed errorResponse
+		if err := json.Unmarshal(body, &parsed); err == nil {
+			for _, e := range parsed.Errors {
+				switch {
+				case e.Type == captchaRequiredErrorType || e.Value == captchaRequiredErrorType:
+					statusErr.kind = ErrCaptchaRequired
+					statusErr.CaptchaURL = e.CaptchaURL
+				case e.Type == oauthErrorType:
+					statusErr.kind = ErrUnauthorized
+				}
+			}
+		}
+	case resp.StatusCode >= http.StatusInternalServerError:
+		statusErr.kind = ErrUnavailable
+	case resp.StatusCode >= http.StatusBadRequest:
+		statusErr.kind = ErrBadRequest
+	}
+	return statusErr
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
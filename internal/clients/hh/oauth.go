@@ -0,0 +1,106 @@
+package hh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokens are refreshed a bit earlier than they expire to not send a request with a token expiring on the way
+const tokenExpiryMargin = time.Minute
+
+type tokenSource interface {
+	Token(ctx context.Context) (string, error)
+	// Invalidate drops the token rejected by hh.ru and reports whether a new one can be obtained
+	Invalidate() bool
+}
+
+type staticToken string
+
+func (t staticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+func (t staticToken) Invalidate() bool {
+	return false
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type clientCredentials struct {
+	client       *Client
+	clientID     string
+	clientSecret string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *clientCredentials) Token(ctx context.Context) (string, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.expiresAt.IsZero() || time.Now().Add(tokenExpiryMargin).Before(c.expiresAt)) {
+		return c.token, nil
+	}
+
+	token, err := c.requestToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("can't get hh app token: %w", err)
+	}
+
+	c.token = token.AccessToken
+	c.expiresAt = time.Time{}
+	if token.ExpiresIn > 0 {
+		c.expiresAt = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return c.token, nil
+}
+
+func (c *clientCredentials) Invalidate() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+	return true
+}
+
+func (c *clientCredentials) requestToken(ctx context.Context) (tokenResponse, error) {
+
+	form := url.Values{}
+	form.Add("grant_type", "client_credentials")
+	form.Add("client_id", c.clientID)
+	form.Add("client_secret", c.clientSecret)
+
+	//token is requested through the same limiter and retries as other requests to not exceed hh.ru limits
+	body, err := c.client.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", c.client.baseURL+"/token", strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return req, nil
+	})
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	var token tokenResponse
+	if err = json.Unmarshal(body, &token); err != nil {
+		return tokenResponse{}, fmt.Errorf("error decoding JSON response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return tokenResponse{}, fmt.Errorf("empty access token in response")
+	}
+	return token, nil
+}
@@ -0,0 +1,166 @@
+package hh
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testUserAgent = "hh-parser-test/1.0 (test@example.com)"
+
+type hhStandIn struct {
+	tokensIssued  atomic.Int32
+	tokenFailures atomic.Int32
+	expiresIn     int
+	rejectedToken string
+}
+
+func (s *hhStandIn) start(t *testing.T) *httptest.Server {
+
+	vacancy, err := os.ReadFile("testdata/get_vacancy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("client_id") != "id" ||
+			r.FormValue("client_secret") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"error": "invalid_client"}`)
+			return
+		}
+		if s.tokenFailures.Add(-1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		issued := s.tokensIssued.Add(1)
+		_, _ = fmt.Fprintf(w, `{"access_token": "token-%d", "token_type": "bearer", "expires_in": %d}`,
+			issued, s.expiresIn)
+	})
+	mux.HandleFunc("GET /vacancies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("HH-User-Agent") != testUserAgent {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = fmt.Fprint(w, `{"errors": [{"type": "bad_user_agent", "value": "blacklisted"}]}`)
+			return
+		}
+		if auth := r.Header.Get("Authorization"); auth == "" || auth == "Bearer "+s.rejectedToken {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = fmt.Fprint(w, `{"errors": [{"type": "oauth", "value": "token_expired"}]}`)
+			return
+		}
+		_, _ = w.Write(vacancy)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newStandInClient(server *httptest.Server) *Client {
+	client := NewClient()
+	client.SetHTTPClient(server.Client())
+	client.SetBaseURL(server.URL)
+	client.SetUserAgent(testUserAgent)
+	client.retry.baseDelay = time.Millisecond
+	return client
+}
+
+func Test_HHClient_WithAppToken_ShouldSendUserAgentAndToken(t *testing.T) {
+
+	standIn := &hhStandIn{}
+	client := newStandInClient(standIn.start(t))
+	client.SetAppToken("app-token")
+
+	vacancy, err := client.GetVacancy(context.Background(), "108444291")
+	assert.NoError(t, err)
+	assert.Equal(t, "108444291", vacancy.ID)
+	assert.Zero(t, standIn.tokensIssued.Load())
+}
+
+func Test_HHClient_WithoutUserAgent_ShouldFail(t *testing.T) {
+
+	standIn := &hhStandIn{}
+	client := newStandInClient(standIn.start(t))
+	client.SetUserAgent("")
+	client.SetAppToken("app-token")
+
+	_, err := client.GetVacancy(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrBadRequest)
+}
+
+func Test_HHClient_WithClientCredentials_ShouldReuseToken(t *testing.T) {
+
+	standIn := &hhStandIn{}
+	client := newStandInClient(standIn.start(t))
+	client.SetClientCredentials("id", "secret")
+
+	for i := 0; i < 3; i++ {
+		_, err := client.GetVacancy(context.Background(), "1")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), standIn.tokensIssued.Load())
+}
+
+func Test_HHClient_WhenTokenExpires_ShouldRequestNewOne(t *testing.T) {
+
+	standIn := &hhStandIn{expiresIn: 30}
+	client := newStandInClient(standIn.start(t))
+	client.SetClientCredentials("id", "secret")
+
+	for i := 0; i < 2; i++ {
+		_, err := client.GetVacancy(context.Background(), "1")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(2), standIn.tokensIssued.Load(), "token expiring within a minute should be refreshed")
+}
+
+func Test_HHClient_WhenTokenRejected_ShouldRefreshAndRetry(t *testing.T) {
+
+	standIn := &hhStandIn{rejectedToken: "token-1"}
+	client := newStandInClient(standIn.start(t))
+	client.SetClientCredentials("id", "secret")
+
+	_, err := client.GetVacancy(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), standIn.tokensIssued.Load())
+}
+
+func Test_HHClient_WhenAppTokenRejected_ShouldReturnUnauthorized(t *testing.T) {
+
+	standIn := &hhStandIn{rejectedToken: "app-token"}
+	client := newStandInClient(standIn.start(t))
+	client.SetAppToken("app-token")
+
+	_, err := client.GetVacancy(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+}
+
+func Test_HHClient_WhenClientCredentialsInvalid_ShouldReturnError(t *testing.T) {
+
+	standIn := &hhStandIn{}
+	client := newStandInClient(standIn.start(t))
+	client.SetClientCredentials("id", "wrong")
+
+	_, err := client.GetVacancy(context.Background(), "1")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	assert.Zero(t, standIn.tokensIssued.Load())
+}
+
+func Test_HHClient_WhenTokenRequestFails_ShouldRetryIt(t *testing.T) {
+
+	standIn := &hhStandIn{}
+	standIn.tokenFailures.Store(2)
+	client := newStandInClient(standIn.start(t))
+	client.SetClientCredentials("id", "secret")
+
+	_, err := client.GetVacancy(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), standIn.tokensIssued.Load())
+}
@@ -1,68 +1,52 @@
 package hh
 
 import (
+	"errors"
 	"fmt"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
-	"github.com/pkg/errors"
 	"net/url"
 	"strconv"
 	"time"
 )
 
+// MaxSearchResults is how deep hh.ru allows to page through search results
+const MaxSearchResults = 2000
+
 var ErrTooDeepPagination = errors.New("too deep pagination")
 
+// Experience, Schedule, WorkFormat, Employment, SearchField and Label values are ids from hh.ru dictionaries,
+// see GetDictionaries
 type Experience string
 
-const (
-	NoExperience Experience = "noExperience"
-	Between1and3 Experience = "between1And3"
-	Between3and6 Experience = "between3And6"
-	MoreThan6    Experience = "moreThan6"
-)
+type Schedule string
 
-func ExperienceFrom(experience models.Experience) (Experience, error) {
-	switch experience {
-	case models.NoExperience:
-		return NoExperience, nil
-	case models.Between1and3:
-		return Between1and3, nil
-	case models.Between3and6:
-		return Between3and6, nil
-	case models.MoreThan6:
-		return MoreThan6, nil
-	default:
-		return "", fmt.Errorf("invalid experience type: %v", experience)
-	}
-}
+type WorkFormat string
 
-type Schedule string
+type Employment string
 
-const (
-	FullDay  Schedule = "fullDay"
-	Flexible Schedule = "flexible"
-	Remote   Schedule = "remote"
-)
+type SearchField string
 
-func ScheduleFrom(schedule models.Schedule) (Schedule, error) {
-	switch schedule {
-	case models.FullDay:
-		return FullDay, nil
-	case models.Flexible:
-		return Flexible, nil
-	case models.Remote:
-		return Remote, nil
-	default:
-		return "", fmt.Errorf("invalid schedule type: %v", schedule)
-	}
-}
+type Label string
 
 type SearchParameters struct {
 	Text                   string
-	AreaID                 string
+	AreaIDs                []string
 	Experience             Experience
 	Schedules              []Schedule
+	WorkFormats            []WorkFormat
+	Employments            []Employment
+	ProfessionalRoles      []string
+	Industries             []string
+	EmployerIDs            []string
+	SearchFields           []SearchField
+	ExcludedText           string
+	Labels                 []Label
+	Salary                 int
+	Currency               string
+	OnlyWithSalary         bool
 	OrderByPublicationTime bool
 	DateFrom               time.Time
+	DateTo                 time.Time
 	Period                 int
 	Page                   int
 	PerPage                int
@@ -70,8 +54,24 @@ type SearchParameters struct {
 
 func (s SearchParameters) Validate() error {
 
-	if s.Period != 0 && !s.DateFrom.IsZero() {
-		return fmt.Errorf("can't use both period and dateFrom")
+	if s.Period != 0 && (!s.DateFrom.IsZero() || !s.DateTo.IsZero()) {
+		return fmt.Errorf("can't use both period and dateFrom or dateTo")
+	}
+
+	if !s.DateFrom.IsZero() && !s.DateTo.IsZero() && s.DateTo.Before(s.DateFrom) {
+		return fmt.Errorf("dateTo must not be before dateFrom")
+	}
+
+	if s.Salary < 0 {
+		return fmt.Errorf("salary must be non-negative")
+	}
+
+	if s.Currency != "" && s.Salary == 0 {
+		return fmt.Errorf("can't use currency without salary")
+	}
+
+	if err := s.validateFilters(); err != nil {
+		return err
 	}
 
 	if s.Page < 0 {
@@ -82,8 +82,7 @@ func (s SearchParameters) Validate() error {
 		return fmt.Errorf("per page must be between 0 and 100")
 	}
 
-	maxResults := 2000
-	maxPage := maxResults / s.PerPage
+	maxPage := MaxSearchResults / s.PerPage
 	if s.Page >= maxPage {
 		return ErrTooDeepPagination
 	}
@@ -91,6 +90,45 @@ func (s SearchParameters) Validate() error {
 	return nil
 }
 
+func (s SearchParameters) validateFilters() error {
+
+	ids := []struct {
+		param   string
+		values  []string
+		isValid func(id string) bool
+	}{
+		{"area", s.AreaIDs, models.IsNumericID},
+		{"professional_role", s.ProfessionalRoles, models.IsNumericID},
+		{"industry", s.Industries, models.IsIndustryID},
+		{"employer_id", s.EmployerIDs, models.IsNumericID},
+	}
+	for _, list := range ids {
+		for _, id := range list.values {
+			if !list.isValid(id) {
+				return fmt.Errorf("invalid %s: %q", list.param, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DropUnknownValues removes enum parameters missing in hh.ru dictionaries, so searches saved before hh.ru
+// removed a value keep working without it. Dropped values are returned as "dictionary: value".
+func (s *SearchParameters) DropUnknownValues(dictionaries Dictionaries) []string {
+
+	var dropped []string
+	if len(knownValues(dictionaries, ExperienceDictionary, []Experience{s.Experience}, &dropped)) == 0 {
+		s.Experience = ""
+	}
+	s.Schedules = knownValues(dictionaries, ScheduleDictionary, s.Schedules, &dropped)
+	s.WorkFormats = knownValues(dictionaries, WorkFormatDictionary, s.WorkFormats, &dropped)
+	s.Employments = knownValues(dictionaries, EmploymentDictionary, s.Employments, &dropped)
+	s.SearchFields = knownValues(dictionaries, SearchFieldDictionary, s.SearchFields, &dropped)
+	s.Labels = knownValues(dictionaries, LabelDictionary, s.Labels, &dropped)
+	return dropped
+}
+
 func (s SearchParameters) ToUrlParams() url.Values {
 
 	params := url.Values{}
@@ -100,8 +138,52 @@ func (s SearchParameters) ToUrlParams() url.Values {
 		params.Add("schedule", string(schedule))
 	}
 
-	if s.AreaID != "" {
-		params.Add("area", s.AreaID)
+	for _, workFormat := range s.WorkFormats {
+		params.Add("work_format", string(workFormat))
+	}
+
+	for _, area := range s.AreaIDs {
+		params.Add("area", area)
+	}
+
+	for _, employment := range s.Employments {
+		params.Add("employment", string(employment))
+	}
+
+	for _, role := range s.ProfessionalRoles {
+		params.Add("professional_role", role)
+	}
+
+	for _, industry := range s.Industries {
+		params.Add("industry", industry)
+	}
+
+	for _, employer := range s.EmployerIDs {
+		params.Add("employer_id", employer)
+	}
+
+	for _, field := range s.SearchFields {
+		params.Add("search_field", string(field))
+	}
+
+	if s.ExcludedText != "" {
+		params.Add("excluded_text", s.ExcludedText)
+	}
+
+	for _, label := range s.Labels {
+		params.Add("label", string(label))
+	}
+
+	if s.Salary > 0 {
+		params.Add("salary", strconv.Itoa(s.Salary))
+	}
+
+	if s.Currency != "" {
+		params.Add("currency", s.Currency)
+	}
+
+	if s.OnlyWithSalary {
+		params.Add("only_with_salary", "true")
 	}
 
 	params.Add("page", strconv.Itoa(s.Page))
@@ -119,5 +201,9 @@ func (s SearchParameters) ToUrlParams() url.Values {
 		params.Add("date_from", s.DateFrom.Format("2006-01-02T15:04:05-0700"))
 	}
 
+	if !s.DateTo.IsZero() {
+		params.Add("date_to", s.DateTo.Format("2006-01-02T15:04:05-0700"))
+	}
+
 	return params
 }
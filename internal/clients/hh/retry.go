@@ -0,0 +1,67 @@
+package hh
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 4
+	defaultBaseDelay   = 500 * time.Millisecond
+	maxBackoffDelay    = 30 * time.Second
+	maxRetryAfter      = time.Minute
+)
+
+type retryPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+func (p retryPolicy) do(ctx context.Context, attempt func() ([]byte, error)) ([]byte, error) {
+
+	var body []byte
+	var err error
+
+	for i := 0; i < p.maxAttempts; i++ {
+
+		body, err = attempt()
+		if err == nil {
+			return body, nil
+		}
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || !statusErr.isRetryable() || i == p.maxAttempts-1 {
+			return nil, err
+		}
+
+		delay := p.backoff(i)
+		if retryAfter, ok := statusErr.RetryAfter(); ok {
+			if retryAfter > maxRetryAfter {
+				return nil, err
+			}
+			delay = retryAfter
+		}
+
+		log.Warnf("hh api returned status %d, retrying in %v", statusErr.StatusCode, delay)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+
+	return nil, err
+}
+
+// backoff is exponential with jitter, so concurrent searches don't retry in lockstep
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := min(p.baseDelay<<attempt, maxBackoffDelay)
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
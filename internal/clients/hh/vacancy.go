@@ -10,19 +10,74 @@ type Vacancy struct {
 	VacancyPreview
 	Description string
 	KeySkills   []KeySkill `json:"key_skills"`
+	Languages   []Language `json:"languages"`
 }
 
 type VacancyPreview struct {
-	ID          string
-	Name        string
-	Url         string     `json:"alternate_url"`
-	PublishedAt CustomTime `json:"published_at"`
+	ID                string
+	Name              string
+	Url               string           `json:"alternate_url"`
+	PublishedAt       CustomTime       `json:"published_at"`
+	Salary            *Salary          `json:"salary"`
+	Employer          *Employer        `json:"employer"`
+	Area              *DictionaryItem  `json:"area"`
+	Address           *Address         `json:"address"`
+	Employment        *DictionaryItem  `json:"employment"`
+	Schedule          *DictionaryItem  `json:"schedule"`
+	Experience        *DictionaryItem  `json:"experience"`
+	ProfessionalRoles []DictionaryItem `json:"professional_roles"`
+	Archived          bool             `json:"archived"`
+}
+
+type DictionaryItem struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type Salary struct {
+	From     *int   `json:"from"`
+	To       *int   `json:"to"`
+	Currency string `json:"currency"`
+	Gross    *bool  `json:"gross"`
+}
+
+type Employer struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Trusted  bool      `json:"trusted"`
+	LogoUrls *LogoUrls `json:"logo_urls"`
+}
+
+type LogoUrls struct {
+	Small    string `json:"90"`
+	Medium   string `json:"240"`
+	Original string `json:"original"`
+}
+
+type Address struct {
+	City          string         `json:"city"`
+	Street        string         `json:"street"`
+	Building      string         `json:"building"`
+	Raw           string         `json:"raw"`
+	MetroStations []MetroStation `json:"metro_stations"`
+}
+
+type MetroStation struct {
+	StationID   string `json:"station_id"`
+	StationName string `json:"station_name"`
+	LineName    string `json:"line_name"`
 }
 
 type KeySkill struct {
 	Name string
 }
 
+type Language struct {
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Level DictionaryItem `json:"level"`
+}
+
 type CustomTime struct {
 	time.Time
 }
@@ -0,0 +1,251 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const ProviderName = "openai"
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type StatusError struct {
+	StatusCode      int
+	Body            string
+	RetryAfterDelay time.Duration
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %v, body: %v", e.StatusCode, e.Body)
+}
+
+func (e *StatusError) RetryAfter() (time.Duration, bool) {
+	return e.RetryAfterDelay, e.StatusCode == http.StatusTooManyRequests && e.RetryAfterDelay > 0
+}
+
+type Client struct {
+	ai.RateLimiter
+	httpClient     HTTPClient
+	baseURL        string
+	apiKey         string
+	model          string
+	embeddingModel string
+}
+
+func NewProvider(_ context.Context, cfg ai.ProviderConfig) (ai.Client, error) {
+	client, err := NewClient(cfg.BaseURL, cfg.APIKey, cfg.Model)
+	if err != nil {
+		return nil, err
+	}
+	client.SetEmbeddingModel(cfg.EmbeddingModel)
+	return client, nil
+}
+
+func NewClient(baseURL string, apiKey string, model string) (*Client, error) {
+
+	if baseURL == "" {
+		return nil, fmt.Errorf("base url is empty")
+	}
+	if model == "" {
+		return nil, fmt.Errorf("model is empty")
+	}
+
+	return &Client{
+		httpClient: &http.Client{},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		model:      model,
+	}, nil
+}
+
+func (c *Client) SetHTTPClient(client HTTPClient) {
+	c.httpClient = client
+}
+
+func (c *Client) Model() string {
+	return c.model
+}
+
+func (c *Client) SetEmbeddingModel(model string) {
+	c.embeddingModel = model
+}
+
+func (c *Client) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+
+	if c.embeddingModel == "" {
+		return nil, ai.ErrEmbeddingsNotConfigured
+	}
+
+	body, err := json.Marshal(embeddingRequest{Model: c.embeddingModel, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	respBody, err := ai.WithRetry(ctx, ProviderName, func() ([]byte, error) {
+		if err := c.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return c.sendRequest(ctx, http.MethodPost, c.baseURL+"/embeddings", bytes.NewReader(body))
+	}, isRetryable)
+	if err != nil {
+		return nil, err
+	}
+
+	var response embeddingResponse
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return nil, fmt.Errorf("error decoding JSON response: %w", err)
+	}
+
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(response.Data), len(texts))
+	}
+
+	result := make([][]float32, len(texts))
+	for _, item := range response.Data {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d is out of range", item.Index)
+		}
+		result[item.Index] = item.Embedding
+	}
+	return result, nil
+}
+
+func (c *Client) GenerateResponse(ctx context.Context, text string) (ai.Response, error) {
+	return c.generateWithRetry(ctx, chatCompletionRequest{
+		Model:    c.model,
+		Messages: []message{{Role: "user", Content: text}},
+	})
+}
+
+func (c *Client) GenerateJSONResponse(ctx context.Context, text string, schema *ai.Schema) (ai.Response, error) {
+	return c.generateWithRetry(ctx, chatCompletionRequest{
+		Model:    c.model,
+		Messages: []message{{Role: "user", Content: text}},
+		ResponseFormat: &responseFormat{
+			Type: "json_schema",
+			JSONSchema: &jsonSchemaFormat{
+				Name:   "response",
+				Schema: toJSONSchema(schema),
+			},
+		},
+	})
+}
+
+func (c *Client) generateWithRetry(ctx context.Context, request chatCompletionRequest) (ai.Response, error) {
+	return ai.WithRetry(ctx, ProviderName, func() (ai.Response, error) {
+		return c.waitAndGenerateResponse(ctx, request)
+	}, isRetryable)
+}
+
+func (c *Client) waitAndGenerateResponse(ctx context.Context, request chatCompletionRequest) (ai.Response, error) {
+
+	if err := c.Wait(ctx); err != nil {
+		return ai.Response{}, err
+	}
+
+	return c.tryGenerateResponse(ctx, request)
+}
+
+func (c *Client) tryGenerateResponse(ctx context.Context, request chatCompletionRequest) (ai.Response, error) {
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return ai.Response{}, fmt.Errorf("error encoding request: %w", err)
+	}
+
+	respBody, err := c.sendRequest(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return ai.Response{}, err
+	}
+
+	var response chatCompletionResponse
+	if err = json.Unmarshal(respBody, &response); err != nil {
+		return ai.Response{}, fmt.Errorf("error decoding JSON response: %w", err)
+	}
+
+	if len(response.Choices) == 0 {
+		return ai.Response{}, fmt.Errorf("response has no choices")
+	}
+
+	return ai.Response{
+		Text:  response.Choices[0].Message.Content,
+		Model: c.model,
+		Usage: ai.Usage{
+			PromptTokens:    response.Usage.PromptTokens,
+			CandidateTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+func (c *Client) sendRequest(ctx context.Context, method string, url string, body io.Reader) ([]byte, error) {
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{
+			StatusCode:      resp.StatusCode,
+			Body:            string(respBody),
+			RetryAfterDelay: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+
+	return respBody, nil
+}
+
+func isRetryable(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+}
+
+func parseRetryAfter(value string, now time.Time) time.Duration {
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type mockHTTPClient struct {
+	mock.Mock
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	args := m.Called(req)
+	return args.Get(0).(*http.Response), args.Error(1)
+}
+
+func response(statusCode int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: statusCode,
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+	}
+}
+
+func Test_OpenAIClient_GenerateJSONResponse_ShouldBeSuccessful(t *testing.T) {
+
+	assert := assert.New(t)
+
+	schema := &ai.Schema{
+		Type:       ai.TypeObject,
+		Properties: map[string]*ai.Schema{"score": {Type: ai.TypeInteger}},
+		Required:   []string{"score"},
+	}
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		if req.URL.String() != "http://localhost:8080/v1/chat/completions" ||
+			req.Header.Get("Authorization") != "Bearer secret" {
+			return false
+		}
+
+		var request chatCompletionRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return false
+		}
+		return request.Model == "llama" &&
+			request.Messages[0].Content == "hello" &&
+			request.ResponseFormat.Type == "json_schema" &&
+			request.ResponseFormat.JSONSchema.Schema["type"] == "object"
+	})).Return(response(200, `{"choices": [{"message": {"role": "assistant", "content": "{\"score\": 1}"}}], `+
+		`"usage": {"prompt_tokens": 12, "completion_tokens": 3}}`), nil)
+
+	client, err := NewClient("http://localhost:8080/v1/", "secret", "llama")
+	assert.NoError(err)
+	client.SetHTTPClient(mockClient)
+
+	resp, err := client.GenerateJSONResponse(context.Background(), "hello", schema)
+	assert.NoError(err)
+	assert.Equal(`{"score": 1}`, resp.Text)
+	assert.Equal(ai.Usage{PromptTokens: 12, CandidateTokens: 3}, resp.Usage)
+}
+
+func Test_OpenAIClient_WhenServerError_ShouldRetry(t *testing.T) {
+
+	assert := assert.New(t)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Return(response(503, "overloaded"), nil).Once()
+	mockClient.On("Do", mock.Anything).
+		Return(response(200, `{"choices": [{"message": {"role": "assistant", "content": "ok"}}]}`), nil).Once()
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(err)
+	client.SetHTTPClient(mockClient)
+
+	resp, err := client.GenerateResponse(context.Background(), "hello")
+	assert.NoError(err)
+	assert.Equal("ok", resp.Text)
+	mockClient.AssertExpectations(t)
+}
+
+func Test_OpenAIClient_WhenClientError_ShouldNotRetry(t *testing.T) {
+
+	assert := assert.New(t)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.Anything).Return(response(400, "bad request"), nil).Once()
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(err)
+	client.SetHTTPClient(mockClient)
+
+	_, err = client.GenerateResponse(context.Background(), "hello")
+
+	var statusErr *StatusError
+	assert.ErrorAs(err, &statusErr)
+	assert.Equal(400, statusErr.StatusCode)
+	mockClient.AssertExpectations(t)
+}
+
+func Test_OpenAIClient_WhenContextCanceled_ShouldNotSendRequest(t *testing.T) {
+
+	mockClient := &mockHTTPClient{}
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(t, err)
+	client.SetHTTPClient(mockClient)
+	client.SetMinuteRateLimit(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.GenerateResponse(ctx, "hello")
+	assert.ErrorIs(t, err, context.Canceled)
+	mockClient.AssertNotCalled(t, "Do", mock.Anything)
+}
+
+func Test_OpenAIClient_WhenRateLimitedForLong_ShouldReturnQuotaError(t *testing.T) {
+
+	mockClient := &mockHTTPClient{}
+	resp := response(429, "quota exceeded")
+	resp.Header = http.Header{"Retry-After": []string{"3600"}}
+	mockClient.On("Do", mock.Anything).Return(resp, nil).Once()
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(t, err)
+	client.SetHTTPClient(mockClient)
+
+	_, err = client.GenerateResponse(context.Background(), "hello")
+
+	var quotaErr *ai.QuotaExhaustedError
+	assert.ErrorAs(t, err, &quotaErr)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), quotaErr.ResetAt, time.Minute)
+	mockClient.AssertExpectations(t)
+}
+
+func Test_ParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30", now))
+	assert.Equal(t, time.Minute, parseRetryAfter("Mon, 10 Mar 2025 12:01:00 GMT", now))
+	assert.Zero(t, parseRetryAfter("", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+}
+
+func Test_OpenAIClient_Embed_ShouldOrderEmbeddingsByIndex(t *testing.T) {
+
+	assert := assert.New(t)
+
+	mockClient := &mockHTTPClient{}
+	mockClient.On("Do", mock.MatchedBy(func(req *http.Request) bool {
+		var request embeddingRequest
+		if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+			return false
+		}
+		return req.URL.String() == "http://localhost:8080/v1/embeddings" && request.Model == "nomic-embed" &&
+			len(request.Input) == 2
+	})).Return(response(200, `{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`), nil)
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(err)
+	client.SetHTTPClient(mockClient)
+
+	_, err = client.Embed(context.Background(), []string{"first", "second"})
+	assert.ErrorIs(err, ai.ErrEmbeddingsNotConfigured)
+
+	client.SetEmbeddingModel("nomic-embed")
+	embeddings, err := client.Embed(context.Background(), []string{"first", "second"})
+	assert.NoError(err)
+	assert.Equal([][]float32{{1, 0}, {0, 1}}, embeddings)
+}
+
+func Test_OpenAIClient_Embed_WhenContextCanceled_ShouldWaitForRateLimit(t *testing.T) {
+
+	mockClient := &mockHTTPClient{}
+
+	client, err := NewClient("http://localhost:8080/v1", "", "llama")
+	assert.NoError(t, err)
+	client.SetHTTPClient(mockClient)
+	client.SetEmbeddingModel("nomic-embed")
+	client.SetMinuteRateLimit(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err = client.Embed(ctx, []string{"first"})
+	assert.ErrorIs(t, err, context.Canceled)
+	mockClient.AssertNotCalled(t, "Do", mock.Anything)
+}
@@ -16,28 +16,79 @@ const (
 	Production  Environment = "production"
 )
 
+type AiFallback struct {
+	Provider             string  `mapstructure:"provider" validate:"required"`
+	BaseURL              string  `mapstructure:"base_url" validate:"required_if=Provider openai"`
+	APIKey               string  `mapstructure:"api_key"`
+	Model                string  `mapstructure:"model" validate:"required"`
+	MaxRequestsPerMinute float32 `mapstructure:"max_requests_per_minute"`
+	MaxRequestsPerDay    float32 `mapstructure:"max_requests_per_day"`
+}
+
 type Config struct {
 	Env                     Environment   `mapstructure:"env"`
 	TgToken                 string        `mapstructure:"tg_token" validate:"required"`
-	AIKey                   string        `mapstructure:"ai_key" validate:"required"`
+	AIKey                   string        `mapstructure:"ai_key" validate:"required_if=AiProvider gemini"`
 	AnalysisInterval        time.Duration `mapstructure:"analysis_interval" validate:"required"`
 	VacancyExpirationInDays int           `mapstructure:"vacancy_expiration_days" validate:"required"`
 	HhMaxRequestsPerSecond  float32       `mapstructure:"hh_max_requests_per_second" validate:"required"`
+	HhUserAgent             string        `mapstructure:"hh_user_agent" validate:"required,contains=@"`
+	HhAppToken              string        `mapstructure:"hh_app_token"`
+	HhClientID              string        `mapstructure:"hh_client_id" validate:"required_with=HhClientSecret"`
+	HhClientSecret          string        `mapstructure:"hh_client_secret" validate:"required_with=HhClientID"`
+	HhEmployerCacheTTL      time.Duration `mapstructure:"hh_employer_cache_ttl" validate:"required"`
+	HhDictionariesInterval  time.Duration `mapstructure:"hh_dictionaries_refresh_interval" validate:"required"`
+	HhDetailsConcurrency    int           `mapstructure:"hh_details_concurrency" validate:"min=1"`
+	HhVacancyCacheTTL       time.Duration `mapstructure:"hh_vacancy_cache_ttl"`
+	AiProvider              string        `mapstructure:"ai_provider" validate:"required"`
+	AiBaseURL               string        `mapstructure:"ai_base_url" validate:"required_if=AiProvider openai"`
 	AiModel                 string        `mapstructure:"ai_model" validate:"required"`
 	AiMaxRequestsPerMinute  float32       `mapstructure:"ai_max_requests_per_minute" validate:"required"`
 	AiMaxRequestsPerDay     float32       `mapstructure:"ai_max_requests_per_day" validate:"required"`
+	AiQuotaTimezone         string        `mapstructure:"ai_quota_timezone" validate:"required"`
+	AiFallbacks             []AiFallback  `mapstructure:"ai_fallbacks" validate:"dive"`
+	AiEmbeddingModel        string        `mapstructure:"ai_embedding_model"`
+	AiSimilarityThreshold   float64       `mapstructure:"ai_similarity_threshold" validate:"min=0,max=1"`
+	AiBatchSize             int           `mapstructure:"ai_batch_size" validate:"min=1"`
+	AiBatchMaxWait          time.Duration `mapstructure:"ai_batch_max_wait"`
+	AiVerdictCacheTTL       time.Duration `mapstructure:"ai_verdict_cache_ttl" validate:"required"`
+	AiMatchPromptPath       string        `mapstructure:"ai_match_prompt_path" validate:"required"`
+	AiBatchPromptPath       string        `mapstructure:"ai_batch_prompt_path" validate:"required"`
+	AiClarifyPromptPath     string        `mapstructure:"ai_clarify_prompt_path" validate:"required"`
+	AiWishClarification     bool          `mapstructure:"ai_wish_clarification"`
+	AiFeedbackExamples      int           `mapstructure:"ai_feedback_examples" validate:"min=0"`
+	AiUserDailyTokenLimit   int64         `mapstructure:"ai_user_daily_token_limit" validate:"min=0"`
+	AdminIDs                []int64       `mapstructure:"admin_ids"`
 	DbConnectionString      string        `mapstructure:"db_connection_string" validate:"required"`
 }
 
+// AIConfig is a part of config used by tools which only talk to AI, bot and hh.ru settings aren't required for it
+type AIConfig struct {
+	AIKey                  string  `mapstructure:"ai_key"`
+	AiProvider             string  `mapstructure:"ai_provider"`
+	AiBaseURL              string  `mapstructure:"ai_base_url"`
+	AiModel                string  `mapstructure:"ai_model"`
+	AiMaxRequestsPerMinute float32 `mapstructure:"ai_max_requests_per_minute"`
+	AiMaxRequestsPerDay    float32 `mapstructure:"ai_max_requests_per_day"`
+	AiMatchPromptPath      string  `mapstructure:"ai_match_prompt_path" validate:"required"`
+	AiBatchPromptPath      string  `mapstructure:"ai_batch_prompt_path" validate:"required"`
+}
+
 var configFile = "./configs/config.yaml"
 
 func Get() *Config {
 
-	if path, exists := os.LookupEnv("CONFIG_PATH"); exists {
-		configFile = path
+	config, err := loadConfig(getConfigFile())
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	config, err := loadConfig(configFile)
+	return config
+}
+
+func GetAI() *AIConfig {
+
+	config, err := load[AIConfig](getConfigFile())
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -45,17 +96,50 @@ func Get() *Config {
 	return config
 }
 
+func getConfigFile() string {
+	if path, exists := os.LookupEnv("CONFIG_PATH"); exists {
+		configFile = path
+	}
+	return configFile
+}
+
 func loadConfig(file string) (*Config, error) {
+	return load[Config](file)
+}
+
+func load[T any](file string) (*T, error) {
 
 	viper.SetConfigFile(file)
 	viper.AutomaticEnv()
 	viper.SetDefault("env", string(Development))
+	viper.SetDefault("hh_app_token", "")
+	viper.SetDefault("hh_client_id", "")
+	viper.SetDefault("hh_client_secret", "")
+	viper.SetDefault("hh_employer_cache_ttl", "24h")
+	viper.SetDefault("hh_dictionaries_refresh_interval", "24h")
+	viper.SetDefault("hh_details_concurrency", 4)
+	viper.SetDefault("hh_vacancy_cache_ttl", "10m")
+	viper.SetDefault("ai_provider", "gemini")
+	viper.SetDefault("ai_base_url", "")
+	viper.SetDefault("ai_quota_timezone", "America/Los_Angeles")
+	viper.SetDefault("ai_embedding_model", "")
+	viper.SetDefault("ai_similarity_threshold", 0.35)
+	viper.SetDefault("ai_batch_size", 1)
+	viper.SetDefault("ai_batch_max_wait", "5s")
+	viper.SetDefault("ai_verdict_cache_ttl", "168h")
+	viper.SetDefault("ai_match_prompt_path", "./configs/prompts/match.tmpl")
+	viper.SetDefault("ai_batch_prompt_path", "./configs/prompts/batch_match.tmpl")
+	viper.SetDefault("ai_clarify_prompt_path", "./configs/prompts/clarify_wish.tmpl")
+	viper.SetDefault("ai_wish_clarification", true)
+	viper.SetDefault("ai_feedback_examples", 5)
+	viper.SetDefault("ai_user_daily_token_limit", 0)
+	viper.SetDefault("admin_ids", []int64{})
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatalf("Error reading config file, %s", err)
 	}
 
-	config := Config{}
+	var config T
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, err
 	}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"path/filepath"
 	"strconv"
 	"testing"
 	"time"
@@ -17,9 +18,32 @@ func Test_Config_EnvironmentOverrideWorksCorrect(t *testing.T) {
 		AnalysisInterval:        3 * time.Hour,
 		VacancyExpirationInDays: 128,
 		HhMaxRequestsPerSecond:  99,
+		HhUserAgent:             "my-app/2.0 (me@example.com)",
+		HhAppToken:              "overrideAppToken",
+		HhClientID:              "overrideClientID",
+		HhClientSecret:          "overrideClientSecret",
+		HhEmployerCacheTTL:      48 * time.Hour,
+		HhDictionariesInterval:  12 * time.Hour,
+		HhDetailsConcurrency:    8,
+		HhVacancyCacheTTL:       30 * time.Minute,
+		AiProvider:              "openai",
+		AiBaseURL:               "http://localhost:8000/v1",
 		AiModel:                 "super_duper_model",
 		AiMaxRequestsPerMinute:  88,
 		AiMaxRequestsPerDay:     89,
+		AiQuotaTimezone:         "Europe/Moscow",
+		AiEmbeddingModel:        "nomic-embed-text",
+		AiSimilarityThreshold:   0.5,
+		AiBatchSize:             7,
+		AiBatchMaxWait:          10 * time.Second,
+		AiVerdictCacheTTL:       24 * time.Hour,
+		AiMatchPromptPath:       "/prompts/match.tmpl",
+		AiBatchPromptPath:       "/prompts/batch.tmpl",
+		AiClarifyPromptPath:     "/prompts/clarify.tmpl",
+		AiWishClarification:     false,
+		AiFeedbackExamples:      3,
+		AiUserDailyTokenLimit:   100000,
+		AdminIDs:                []int64{1, 2},
 		DbConnectionString:      "newConnectionString",
 	}
 	os.Setenv("CONFIG_PATH", "../../configs/config.yaml")
@@ -30,9 +54,32 @@ func Test_Config_EnvironmentOverrideWorksCorrect(t *testing.T) {
 	os.Setenv("ANALYSIS_INTERVAL", "3h")
 	os.Setenv("VACANCY_EXPIRATION_DAYS", strconv.Itoa(override.VacancyExpirationInDays))
 	os.Setenv("HH_MAX_REQUESTS_PER_SECOND", fmt.Sprintf("%f", override.HhMaxRequestsPerSecond))
+	os.Setenv("HH_USER_AGENT", override.HhUserAgent)
+	os.Setenv("HH_APP_TOKEN", override.HhAppToken)
+	os.Setenv("HH_CLIENT_ID", override.HhClientID)
+	os.Setenv("HH_CLIENT_SECRET", override.HhClientSecret)
+	os.Setenv("HH_EMPLOYER_CACHE_TTL", "48h")
+	os.Setenv("HH_DICTIONARIES_REFRESH_INTERVAL", "12h")
+	os.Setenv("HH_DETAILS_CONCURRENCY", strconv.Itoa(override.HhDetailsConcurrency))
+	os.Setenv("HH_VACANCY_CACHE_TTL", "30m")
+	os.Setenv("AI_PROVIDER", override.AiProvider)
+	os.Setenv("AI_BASE_URL", override.AiBaseURL)
 	os.Setenv("AI_MODEL", override.AiModel)
 	os.Setenv("AI_MAX_REQUESTS_PER_MINUTE", fmt.Sprintf("%f", override.AiMaxRequestsPerMinute))
 	os.Setenv("AI_MAX_REQUESTS_PER_DAY", fmt.Sprintf("%f", override.AiMaxRequestsPerDay))
+	os.Setenv("AI_QUOTA_TIMEZONE", override.AiQuotaTimezone)
+	os.Setenv("AI_EMBEDDING_MODEL", override.AiEmbeddingModel)
+	os.Setenv("AI_SIMILARITY_THRESHOLD", "0.5")
+	os.Setenv("AI_BATCH_SIZE", strconv.Itoa(override.AiBatchSize))
+	os.Setenv("AI_BATCH_MAX_WAIT", "10s")
+	os.Setenv("AI_VERDICT_CACHE_TTL", "24h")
+	os.Setenv("AI_MATCH_PROMPT_PATH", override.AiMatchPromptPath)
+	os.Setenv("AI_BATCH_PROMPT_PATH", override.AiBatchPromptPath)
+	os.Setenv("AI_CLARIFY_PROMPT_PATH", override.AiClarifyPromptPath)
+	os.Setenv("AI_WISH_CLARIFICATION", "false")
+	os.Setenv("AI_FEEDBACK_EXAMPLES", strconv.Itoa(override.AiFeedbackExamples))
+	os.Setenv("AI_USER_DAILY_TOKEN_LIMIT", "100000")
+	os.Setenv("ADMIN_IDS", "1,2")
 	os.Setenv("DB_CONNECTION_STRING", override.DbConnectionString)
 
 	cfg := Get()
@@ -43,8 +90,117 @@ func Test_Config_EnvironmentOverrideWorksCorrect(t *testing.T) {
 	assert.Equal(t, override.AnalysisInterval, cfg.AnalysisInterval)
 	assert.Equal(t, override.VacancyExpirationInDays, cfg.VacancyExpirationInDays)
 	assert.Equal(t, override.HhMaxRequestsPerSecond, cfg.HhMaxRequestsPerSecond)
+	assert.Equal(t, override.HhUserAgent, cfg.HhUserAgent)
+	assert.Equal(t, override.HhAppToken, cfg.HhAppToken)
+	assert.Equal(t, override.HhClientID, cfg.HhClientID)
+	assert.Equal(t, override.HhClientSecret, cfg.HhClientSecret)
+	assert.Equal(t, override.HhEmployerCacheTTL, cfg.HhEmployerCacheTTL)
+	assert.Equal(t, override.HhDictionariesInterval, cfg.HhDictionariesInterval)
+	assert.Equal(t, override.HhDetailsConcurrency, cfg.HhDetailsConcurrency)
+	assert.Equal(t, override.HhVacancyCacheTTL, cfg.HhVacancyCacheTTL)
+	assert.Equal(t, override.AiProvider, cfg.AiProvider)
+	assert.Equal(t, override.AiBaseURL, cfg.AiBaseURL)
 	assert.Equal(t, override.AiModel, cfg.AiModel)
 	assert.Equal(t, override.AiMaxRequestsPerMinute, cfg.AiMaxRequestsPerMinute)
 	assert.Equal(t, override.AiMaxRequestsPerDay, cfg.AiMaxRequestsPerDay)
+	assert.Equal(t, override.AiQuotaTimezone, cfg.AiQuotaTimezone)
+	assert.Equal(t, override.AiEmbeddingModel, cfg.AiEmbeddingModel)
+	assert.Equal(t, override.AiSimilarityThreshold, cfg.AiSimilarityThreshold)
+	assert.Equal(t, override.AiBatchSize, cfg.AiBatchSize)
+	assert.Equal(t, override.AiBatchMaxWait, cfg.AiBatchMaxWait)
+	assert.Equal(t, override.AiVerdictCacheTTL, cfg.AiVerdictCacheTTL)
+	assert.Equal(t, override.AiMatchPromptPath, cfg.AiMatchPromptPath)
+	assert.Equal(t, override.AiBatchPromptPath, cfg.AiBatchPromptPath)
+	assert.Equal(t, override.AiClarifyPromptPath, cfg.AiClarifyPromptPath)
+	assert.Equal(t, override.AiWishClarification, cfg.AiWishClarification)
+	assert.Equal(t, override.AiFeedbackExamples, cfg.AiFeedbackExamples)
+	assert.Equal(t, override.AiUserDailyTokenLimit, cfg.AiUserDailyTokenLimit)
+	assert.Equal(t, override.AdminIDs, cfg.AdminIDs)
 	assert.Equal(t, override.DbConnectionString, cfg.DbConnectionString)
 }
+
+func Test_Config_ShouldParseAiFallbacks(t *testing.T) {
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+tg_token: "token"
+ai_key: "key"
+analysis_interval: 1h
+vacancy_expiration_days: 7
+hh_max_requests_per_second: 1
+hh_user_agent: "hh-parser/1.0 (me@example.com)"
+ai_model: "gemini-2.0-flash"
+ai_max_requests_per_minute: 10
+ai_max_requests_per_day: 1000
+db_connection_string: "db"
+ai_fallbacks:
+  - provider: "gemini"
+    model: "gemini-2.0-flash-lite"
+    max_requests_per_day: 1500
+  - provider: "openai"
+    base_url: "http://localhost:8000/v1"
+    model: "llama3"
+`), 0o600)
+	assert.NoError(t, err)
+
+	cfg, err := loadConfig(file)
+	assert.NoError(t, err)
+	assert.Equal(t, []AiFallback{
+		{Provider: "gemini", Model: "gemini-2.0-flash-lite", MaxRequestsPerDay: 1500},
+		{Provider: "openai", BaseURL: "http://localhost:8000/v1", Model: "llama3"},
+	}, cfg.AiFallbacks)
+}
+
+func Test_Config_WhenUserAgentHasNoContact_ShouldFail(t *testing.T) {
+
+	t.Setenv("HH_USER_AGENT", "hh-parser/1.0")
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+tg_token: "token"
+ai_key: "key"
+analysis_interval: 1h
+vacancy_expiration_days: 7
+hh_max_requests_per_second: 1
+ai_model: "gemini-2.0-flash"
+ai_max_requests_per_minute: 10
+ai_max_requests_per_day: 1000
+db_connection_string: "db"
+`), 0o600)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(file)
+	assert.ErrorContains(t, err, "HhUserAgent")
+}
+
+func Test_Config_ShippedConfigShouldBeValid(t *testing.T) {
+
+	t.Setenv("HH_USER_AGENT", "")
+
+	cfg, err := loadConfig("../../configs/config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "hh-parser/1.0 (admin@example.com)", cfg.HhUserAgent)
+}
+
+func Test_Config_WhenOnlyAiSettingsAreSet_ShouldLoadAiConfig(t *testing.T) {
+
+	for _, env := range []string{"TG_TOKEN", "HH_USER_AGENT", "AI_MODEL", "AI_MATCH_PROMPT_PATH"} {
+		t.Setenv(env, "")
+	}
+
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(file, []byte(`
+ai_provider: "openai"
+ai_base_url: "http://localhost:11434/v1"
+ai_model: "qwen2.5"
+`), 0o600)
+	assert.NoError(t, err)
+
+	_, err = loadConfig(file)
+	assert.Error(t, err)
+
+	cfg, err := load[AIConfig](file)
+	assert.NoError(t, err)
+	assert.Equal(t, "qwen2.5", cfg.AiModel)
+	assert.Equal(t, "./configs/prompts/match.tmpl", cfg.AiMatchPromptPath)
+}
@@ -3,3 +3,9 @@ package errors
 import "github.com/pkg/errors"
 
 var VacancyAlreadySentToUser = errors.New("vacancy already sent to user")
+
+var NotifiedVacancyNotFound = errors.New("notified vacancy not found")
+
+var UserQuotaExceeded = errors.New("user daily AI quota exceeded")
+
+var EmployerNotFound = errors.New("employer not found")
@@ -7,7 +7,7 @@ import (
 var VacancyFoundTopic = "VacancyFoundEvent"
 
 type VacancyFound struct {
-	Search models.JobSearch
-	Name   string
-	Url    string
+	Search  models.JobSearch
+	Vacancy models.Vacancy
+	Verdict models.MatchVerdict
 }
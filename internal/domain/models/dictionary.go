@@ -0,0 +1,86 @@
+package models
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+const (
+	ExperienceDictionary  = "experience"
+	ScheduleDictionary    = "schedule"
+	WorkFormatDictionary  = "work_format"
+	EmploymentDictionary  = "employment"
+	SearchFieldDictionary = "vacancy_search_fields"
+	LabelDictionary       = "vacancy_label"
+)
+
+// DictionaryEntry is a stored value of hh.ru dictionary, Position keeps the order hh.ru returns values in
+type DictionaryEntry struct {
+	Dictionary string `gorm:"primaryKey"`
+	ID         string `gorm:"primaryKey"`
+	Name       string
+	Position   int
+	UpdatedAt  time.Time
+}
+
+type Dictionaries map[string][]DictionaryItem
+
+// DefaultDictionaries are used until dictionaries are loaded from hh.ru
+var DefaultDictionaries = Dictionaries{
+	ExperienceDictionary: {
+		{ID: string(NoExperience), Name: "Нет опыта"},
+		{ID: string(Between1and3), Name: "От 1 года до 3 лет"},
+		{ID: string(Between3and6), Name: "От 3 до 6 лет"},
+		{ID: string(MoreThan6), Name: "Более 6 лет"},
+	},
+	ScheduleDictionary: {
+		{ID: string(FullDay), Name: "Полный день"},
+		{ID: string(Flexible), Name: "Гибкий график"},
+		{ID: string(Remote), Name: "Удаленная работа"},
+	},
+	EmploymentDictionary: {
+		{ID: string(FullEmployment), Name: "Полная занятость"},
+		{ID: string(PartEmployment), Name: "Частичная занятость"},
+		{ID: string(ProjectEmployment), Name: "Проектная работа"},
+		{ID: string(VolunteerEmployment), Name: "Волонтерство"},
+		{ID: string(ProbationEmployment), Name: "Стажировка"},
+	},
+	SearchFieldDictionary: {
+		{ID: string(SearchFieldName), Name: "В названии вакансии"},
+		{ID: string(SearchFieldCompanyName), Name: "В названии компании"},
+		{ID: string(SearchFieldDescription), Name: "В описании вакансии"},
+	},
+	LabelDictionary: {
+		{ID: string(LabelWithAddress), Name: "С адресом"},
+		{ID: string(LabelAcceptHandicapped), Name: "Доступные людям с инвалидностью"},
+		{ID: string(LabelNotFromAgency), Name: "Без вакансий от кадровых агентств"},
+		{ID: string(LabelAcceptKids), Name: "Доступные для соискателей от 14 лет"},
+		{ID: string(LabelAccreditedIT), Name: "От аккредитованных ИТ-компаний"},
+		{ID: string(LabelLowPerformance), Name: "Мало откликов"},
+	},
+}
+
+// Merge returns dictionaries with values from other replacing whole dictionaries with the same name
+func (d Dictionaries) Merge(other Dictionaries) Dictionaries {
+	merged := maps.Clone(d)
+	if merged == nil {
+		merged = Dictionaries{}
+	}
+	for name, items := range other {
+		if len(items) > 0 {
+			merged[name] = items
+		}
+	}
+	return merged
+}
+
+func (d Dictionaries) NameOf(dictionary string, id string) (string, bool) {
+	idx := slices.IndexFunc(d[dictionary], func(item DictionaryItem) bool {
+		return item.ID == id
+	})
+	if idx == -1 {
+		return "", false
+	}
+	return d[dictionary][idx].Name, true
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type EmbeddingKind string
+
+const (
+	EmbeddingKindWish    EmbeddingKind = "wish"
+	EmbeddingKindVacancy EmbeddingKind = "vacancy"
+)
+
+type Embedding struct {
+	Kind      EmbeddingKind `gorm:"primaryKey"`
+	TextHash  string        `gorm:"primaryKey"`
+	Model     string        `gorm:"primaryKey"`
+	Vector    []float32     `gorm:"serializer:json"`
+	CreatedAt time.Time     `gorm:"index"`
+}
+
+// SimilarityRejection is a vacancy text rejected by similarity to a search wish, stored to not compare them again
+type SimilarityRejection struct {
+	WishHash   string `gorm:"primaryKey"`
+	TextHash   string `gorm:"primaryKey"`
+	Model      string `gorm:"primaryKey"`
+	VacancyID  string
+	Similarity float64
+	Threshold  float64
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func EmbeddingTextHash(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+type Employer struct {
+	ID            string
+	Name          string
+	Trusted       bool
+	LogoUrl       string
+	SiteUrl       string
+	Industries    []DictionaryItem `gorm:"serializer:json"`
+	OpenVacancies int
+}
+
+// CachedEmployer keeps employer card from hh.ru to not request it for every vacancy
+type CachedEmployer struct {
+	Employer  `gorm:"embedded"`
+	ExpiresAt time.Time `gorm:"index"`
+	UpdatedAt time.Time
+}
+
+type EmployerListKind string
+
+const (
+	EmployerBlocked EmployerListKind = "blocked"
+	EmployerAllowed EmployerListKind = "allowed"
+)
+
+type EmployerListEntry struct {
+	UserID       int64  `gorm:"primaryKey"`
+	EmployerID   string `gorm:"primaryKey"`
+	EmployerName string
+	Kind         EmployerListKind
+	CreatedAt    time.Time
+}
+
+// EmployerLists are set by user for all his searches. If allowed list isn't empty, only its employers pass
+type EmployerLists struct {
+	Blocked []EmployerListEntry
+	Allowed []EmployerListEntry
+}
+
+func NewEmployerLists(entries []EmployerListEntry) EmployerLists {
+	var lists EmployerLists
+	for _, entry := range entries {
+		switch entry.Kind {
+		case EmployerBlocked:
+			lists.Blocked = append(lists.Blocked, entry)
+		case EmployerAllowed:
+			lists.Allowed = append(lists.Allowed, entry)
+		}
+	}
+	return lists
+}
+
+func (l EmployerLists) IsEmpty() bool {
+	return len(l.Blocked) == 0 && len(l.Allowed) == 0
+}
+
+func (l EmployerLists) Check(vacancy Vacancy) RuleViolation {
+
+	hasEmployer := func(entry EmployerListEntry) bool {
+		return entry.EmployerID == vacancy.Employer.ID
+	}
+
+	if slices.ContainsFunc(l.Blocked, hasEmployer) {
+		return BlockedEmployerViolation
+	}
+	if len(l.Allowed) > 0 && !slices.ContainsFunc(l.Allowed, hasEmployer) {
+		return NotAllowedEmployerViolation
+	}
+	return NoRuleViolation
+}
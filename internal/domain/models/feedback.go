@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type VacancyFeedback struct {
+	ID                 int
+	UserID             int64  `gorm:"uniqueIndex:idx_feedback_user_search_vacancy"`
+	SearchID           int    `gorm:"uniqueIndex:idx_feedback_user_search_vacancy;index"`
+	VacancyID          string `gorm:"uniqueIndex:idx_feedback_user_search_vacancy"`
+	VacancyName        string
+	VacancySkills      []string `gorm:"serializer:json"`
+	VacancyDescription string
+	Verdict            MatchVerdict `gorm:"embedded;embeddedPrefix:verdict_"`
+	Positive           bool
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
@@ -1,13 +1,12 @@
 package models
 
 import (
-	"errors"
 	"github.com/samber/lo"
-	log "github.com/sirupsen/logrus"
 	"strings"
 	"time"
 )
 
+// Experience and Schedule are ids from hh.ru dictionaries, constants are only the defaults
 type Experience string
 
 const (
@@ -25,27 +24,22 @@ const (
 	Remote   Schedule = "remote"
 )
 
-func ToSchedule(s string) (Schedule, error) {
-	switch s {
-	case string(FullDay):
-		return FullDay, nil
-	case string(Flexible):
-		return Flexible, nil
-	case string(Remote):
-		return Remote, nil
-	default:
-		return "", errors.New("invalid schedule type")
-	}
-}
-
 type JobSearch struct {
 	ID                     int
 	UserID                 int64
 	SearchText             string
 	Schedules              string
+	WorkFormats            []string `gorm:"serializer:json"`
 	RegionID               string
 	Experience             Experience
+	Salary                 int
+	Currency               string
+	OnlyWithSalary         bool
 	UserWish               string
+	Rules                  SearchRules   `gorm:"serializer:json"`
+	Filters                SearchFilters `gorm:"serializer:json"`
+	Strictness             Strictness
+	SimilarityThreshold    *float64
 	InitialSearchPeriod    int
 	LastCheckedVacancyTime time.Time
 	CreatedAt              time.Time
@@ -81,10 +75,6 @@ func (s *JobSearch) SchedulesAsArray() []Schedule {
 	}
 
 	return lo.Map(strings.Split(s.Schedules, ","), func(item string, _ int) Schedule {
-		schedule, err := ToSchedule(item)
-		if err != nil {
-			log.Error(err)
-		}
-		return schedule
+		return Schedule(item)
 	})
 }
@@ -5,10 +5,14 @@ import (
 	"strings"
 )
 
+// Region is hh.ru area, searching by it includes all areas below it in hierarchy
 type Region struct {
 	ID             string
+	ParentID       string `gorm:"index"`
 	Name           string
 	NormalizedName string
+	Level          int
+	ParentName     string `gorm:"-"`
 }
 
 func NewRegion(id, name string) Region {
@@ -19,6 +23,14 @@ func NewRegion(id, name string) Region {
 	}
 }
 
+// FullName adds parent area to the name, so regions with the same names can be told apart
+func (r Region) FullName() string {
+	if r.ParentName == "" {
+		return r.Name
+	}
+	return r.Name + ", " + r.ParentName
+}
+
 func NormalizeRegionName(name string) string {
 	str := strings.ToLower(name)
 	str = strings.ReplaceAll(str, "ё", "е")
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type Employment string
+
+const (
+	FullEmployment      Employment = "full"
+	PartEmployment      Employment = "part"
+	ProjectEmployment   Employment = "project"
+	VolunteerEmployment Employment = "volunteer"
+	ProbationEmployment Employment = "probation"
+)
+
+type SearchField string
+
+const (
+	SearchFieldName        SearchField = "name"
+	SearchFieldCompanyName SearchField = "company_name"
+	SearchFieldDescription SearchField = "description"
+)
+
+type VacancyLabel string
+
+const (
+	LabelWithAddress       VacancyLabel = "with_address"
+	LabelAcceptHandicapped VacancyLabel = "accept_handicapped"
+	LabelNotFromAgency     VacancyLabel = "not_from_agency"
+	LabelAcceptKids        VacancyLabel = "accept_kids"
+	LabelAccreditedIT      VacancyLabel = "accredited_it"
+	LabelLowPerformance    VacancyLabel = "low_performance"
+)
+
+var (
+	numericIDRegexp  = regexp.MustCompile(`^\d+$`)
+	industryIDRegexp = regexp.MustCompile(`^\d+(\.\d+)?$`)
+)
+
+// IsNumericID checks ids of hh.ru areas, professional roles and employers
+func IsNumericID(id string) bool {
+	return numericIDRegexp.MatchString(id)
+}
+
+// IsIndustryID checks ids of hh.ru industries, they may have a subindustry part like "7.540"
+func IsIndustryID(id string) bool {
+	return industryIDRegexp.MatchString(id)
+}
+
+// SearchFilters are passed to hh.ru search as is, unlike SearchRules which are checked locally
+type SearchFilters struct {
+	AreaIDs           []string       `json:",omitempty"`
+	ProfessionalRoles []string       `json:",omitempty"`
+	Industries        []string       `json:",omitempty"`
+	Employments       []Employment   `json:",omitempty"`
+	SearchFields      []SearchField  `json:",omitempty"`
+	ExcludedText      string         `json:",omitempty"`
+	Labels            []VacancyLabel `json:",omitempty"`
+	EmployerIDs       []string       `json:",omitempty"`
+}
+
+func (f SearchFilters) IsEmpty() bool {
+	return len(f.AreaIDs) == 0 && len(f.ProfessionalRoles) == 0 && len(f.Industries) == 0 &&
+		len(f.Employments) == 0 && len(f.SearchFields) == 0 && f.ExcludedText == "" &&
+		len(f.Labels) == 0 && len(f.EmployerIDs) == 0
+}
+
+func (f SearchFilters) Validate() error {
+
+	ids := []struct {
+		name    string
+		values  []string
+		isValid func(id string) bool
+	}{
+		{"area", f.AreaIDs, IsNumericID},
+		{"professional role", f.ProfessionalRoles, IsNumericID},
+		{"industry", f.Industries, IsIndustryID},
+		{"employer", f.EmployerIDs, IsNumericID},
+	}
+	for _, list := range ids {
+		for _, id := range list.values {
+			if !list.isValid(id) {
+				return fmt.Errorf("invalid %s id: %q", list.name, id)
+			}
+		}
+	}
+
+	//employments, search fields and labels come from hh.ru dictionaries which may change, so they aren't checked here
+	return nil
+}
@@ -0,0 +1,94 @@
+package models
+
+import "strings"
+
+type RuleViolation string
+
+const (
+	NoRuleViolation             RuleViolation = ""
+	NameIncludeViolation        RuleViolation = "name_include"
+	NameExcludeViolation        RuleViolation = "name_exclude"
+	DescriptionIncludeViolation RuleViolation = "description_include"
+	DescriptionExcludeViolation RuleViolation = "description_exclude"
+	SkillsIncludeViolation      RuleViolation = "skills_include"
+	SkillsExcludeViolation      RuleViolation = "skills_exclude"
+	SalaryViolation             RuleViolation = "salary"
+	EmployerViolation           RuleViolation = "employer"
+	ArchivedViolation           RuleViolation = "archived"
+	BlockedEmployerViolation    RuleViolation = "employer_blocked"
+	NotAllowedEmployerViolation RuleViolation = "employer_not_allowed"
+)
+
+type SearchRules struct {
+	NameInclude        []string `json:",omitempty"`
+	NameExclude        []string `json:",omitempty"`
+	DescriptionInclude []string `json:",omitempty"`
+	DescriptionExclude []string `json:",omitempty"`
+	SkillsInclude      []string `json:",omitempty"`
+	SkillsExclude      []string `json:",omitempty"`
+	MinSalary          int      `json:",omitempty"`
+	ExcludedEmployers  []string `json:",omitempty"`
+}
+
+func (r SearchRules) IsEmpty() bool {
+	return len(r.NameInclude) == 0 && len(r.NameExclude) == 0 &&
+		len(r.DescriptionInclude) == 0 && len(r.DescriptionExclude) == 0 &&
+		len(r.SkillsInclude) == 0 && len(r.SkillsExclude) == 0 &&
+		r.MinSalary == 0 && len(r.ExcludedEmployers) == 0
+}
+
+func (r SearchRules) Check(vacancy Vacancy) RuleViolation {
+
+	if vacancy.Archived {
+		return ArchivedViolation
+	}
+
+	name := strings.ToLower(vacancy.Name)
+	description := strings.ToLower(vacancy.Description)
+	skills := strings.ToLower(strings.Join(vacancy.KeySkills, "\n"))
+
+	checks := []struct {
+		text      string
+		terms     []string
+		include   bool
+		violation RuleViolation
+	}{
+		{name, r.NameInclude, true, NameIncludeViolation},
+		{name, r.NameExclude, false, NameExcludeViolation},
+		{description, r.DescriptionInclude, true, DescriptionIncludeViolation},
+		{description, r.DescriptionExclude, false, DescriptionExcludeViolation},
+		{skills, r.SkillsInclude, true, SkillsIncludeViolation},
+		{skills, r.SkillsExclude, false, SkillsExcludeViolation},
+	}
+
+	for _, check := range checks {
+		if len(check.terms) == 0 {
+			continue
+		}
+		if containsAny(check.text, check.terms) != check.include {
+			return check.violation
+		}
+	}
+
+	if r.MinSalary > 0 && vacancy.Salary != nil && vacancy.Salary.Currency == CurrencyRUR &&
+		vacancy.Salary.Max() > 0 && vacancy.Salary.Max() < r.MinSalary {
+		return SalaryViolation
+	}
+
+	for _, employer := range r.ExcludedEmployers {
+		if employer == vacancy.Employer.ID || strings.EqualFold(employer, vacancy.Employer.Name) {
+			return EmployerViolation
+		}
+	}
+
+	return NoRuleViolation
+}
+
+func containsAny(text string, terms []string) bool {
+	for _, term := range terms {
+		if strings.Contains(text, strings.ToLower(term)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const usageDayLayout = "2006-01-02"
+
+type AIUsage struct {
+	Day             string `gorm:"primaryKey"`
+	UserID          int64  `gorm:"primaryKey"`
+	SearchID        int    `gorm:"primaryKey"`
+	Requests        int64
+	PromptTokens    int64
+	CandidateTokens int64
+	UpdatedAt       time.Time
+}
+
+func (u AIUsage) TotalTokens() int64 {
+	return u.PromptTokens + u.CandidateTokens
+}
+
+func UsageDay(t time.Time) string {
+	return t.UTC().Format(usageDayLayout)
+}
+
+type AIQuotaUsage struct {
+	Key       string `gorm:"primaryKey"`
+	Day       string `gorm:"primaryKey"`
+	Requests  int
+	UpdatedAt time.Time
+}
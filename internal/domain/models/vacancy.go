@@ -3,21 +3,65 @@ package models
 import "time"
 
 type Vacancy struct {
-	ID          string
-	Url         string
-	Name        string
-	Description string
-	KeySkills   []string
-	PublishedAt time.Time
+	ID                string
+	Url               string
+	Name              string
+	Description       string
+	KeySkills         []string
+	Salary            *Salary
+	Employer          Employer
+	Area              DictionaryItem
+	Address           *Address
+	Employment        DictionaryItem
+	Schedule          DictionaryItem
+	Experience        DictionaryItem
+	ProfessionalRoles []DictionaryItem
+	Languages         []Language
+	Archived          bool
+	PublishedAt       time.Time
 }
 
+// DictionaryItem is a value from hh.ru dictionaries, Name is human-readable and can be shown to user or AI
+type DictionaryItem struct {
+	ID   string
+	Name string
+}
+
+const CurrencyRUR = "RUR"
+
+type Salary struct {
+	From     int
+	To       int
+	Currency string
+	Gross    bool
+}
+
+func (s Salary) Max() int {
+	return max(s.From, s.To)
+}
+
+type Address struct {
+	Raw           string
+	MetroStations []string
+}
+
+type Language struct {
+	Name  string
+	Level string
+}
+
+// NotifiedVacancy keeps skills and shortened description of sent vacancy to show it in feedback examples
 type NotifiedVacancy struct {
-	ID              int
-	UserID          int64
-	VacancyID       string
-	DescriptionHash []byte
-	LastCheckedAt   time.Time
-	CreatedAt       time.Time
+	ID                 int
+	UserID             int64
+	VacancyID          string
+	VacancyName        string
+	VacancySkills      []string `gorm:"serializer:json"`
+	VacancyDescription string
+	DescriptionHash    []byte
+	Verdict            MatchVerdict `gorm:"embedded;embeddedPrefix:verdict_"`
+	LastCheckedAt      time.Time
+	CreatedAt          time.Time
 }
 
 type NotifiedVacancyID struct {
@@ -27,10 +71,11 @@ type NotifiedVacancyID struct {
 }
 
 type FailedVacancy struct {
-	SearchID  int    `gorm:"primaryKey"`
-	VacancyID string `gorm:"primaryKey"`
-	Error     string
-	Attempts  int `gorm:"default:1"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	SearchID    int    `gorm:"primaryKey"`
+	VacancyID   string `gorm:"primaryKey"`
+	Error       string
+	Attempts    int `gorm:"default:1"`
+	ParkedUntil *time.Time
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
 }
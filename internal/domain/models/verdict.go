@@ -0,0 +1,100 @@
+package models
+
+type Confidence string
+
+const (
+	ConfidenceLow    Confidence = "low"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceHigh   Confidence = "high"
+)
+
+func ToConfidence(s string) (Confidence, bool) {
+	switch Confidence(s) {
+	case ConfidenceLow, ConfidenceMedium, ConfidenceHigh:
+		return Confidence(s), true
+	default:
+		return "", false
+	}
+}
+
+type WorkFormat string
+
+const (
+	WorkFormatUnknown WorkFormat = "unknown"
+	WorkFormatRemote  WorkFormat = "remote"
+	WorkFormatOffice  WorkFormat = "office"
+	WorkFormatHybrid  WorkFormat = "hybrid"
+)
+
+func ToWorkFormat(s string) (WorkFormat, bool) {
+	switch WorkFormat(s) {
+	case WorkFormatUnknown, WorkFormatRemote, WorkFormatOffice, WorkFormatHybrid:
+		return WorkFormat(s), true
+	default:
+		return "", false
+	}
+}
+
+const MatchScoreThreshold = 50
+
+type Strictness string
+
+const (
+	StrictnessStrict  Strictness = "strict"
+	StrictnessNormal  Strictness = "normal"
+	StrictnessLenient Strictness = "lenient"
+)
+
+const (
+	strictMatchScoreThreshold  = 70
+	lenientMatchScoreThreshold = 35
+)
+
+func ToStrictness(s string) (Strictness, bool) {
+	switch Strictness(s) {
+	case StrictnessStrict, StrictnessNormal, StrictnessLenient:
+		return Strictness(s), true
+	default:
+		return "", false
+	}
+}
+
+type VacancySummary struct {
+	Salary      string
+	Stack       []string `gorm:"serializer:json"`
+	WorkFormat  WorkFormat
+	CompanySize string
+	RedFlags    []string `gorm:"serializer:json"`
+}
+
+func (s VacancySummary) IsEmpty() bool {
+	return s.Salary == "" && len(s.Stack) == 0 && (s.WorkFormat == "" || s.WorkFormat == WorkFormatUnknown) &&
+		s.CompanySize == "" && len(s.RedFlags) == 0
+}
+
+type MatchVerdict struct {
+	Score           int
+	Confidence      Confidence
+	MatchedCriteria []string `gorm:"serializer:json"`
+	MissedCriteria  []string `gorm:"serializer:json"`
+	Rationale       string
+	PromptVersion   string
+	Model           string
+	Summary         VacancySummary `gorm:"embedded;embeddedPrefix:summary_"`
+}
+
+func (v MatchVerdict) IsMatch() bool {
+	return v.Score >= MatchScoreThreshold
+}
+
+// IsMatchFor treats an empty strictness as normal, as it is for searches created before it was introduced
+func (v MatchVerdict) IsMatchFor(strictness Strictness) bool {
+	switch strictness {
+	case StrictnessStrict:
+		return v.Score >= strictMatchScoreThreshold && v.Confidence != ConfidenceLow
+	case StrictnessLenient:
+		return v.Score >= lenientMatchScoreThreshold
+	default:
+		return v.IsMatch()
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type VerdictCacheKey struct {
+	WishHash        string `gorm:"primaryKey"`
+	DescriptionHash string `gorm:"primaryKey"`
+	PromptVersion   string `gorm:"primaryKey"`
+	Model           string `gorm:"primaryKey"`
+}
+
+func NewVerdictCacheKey(wish string, descriptionHash []byte, promptVersion, model string) VerdictCacheKey {
+	wishHash := sha256.Sum256([]byte(NormalizeWish(wish)))
+	return VerdictCacheKey{
+		WishHash:        hex.EncodeToString(wishHash[:]),
+		DescriptionHash: hex.EncodeToString(descriptionHash),
+		PromptVersion:   promptVersion,
+		Model:           model,
+	}
+}
+
+type CachedVerdict struct {
+	VerdictCacheKey
+	Verdict   MatchVerdict `gorm:"embedded;embeddedPrefix:verdict_"`
+	ExpiresAt time.Time    `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func NormalizeWish(wish string) string {
+	str := strings.ToLower(wish)
+	str = strings.ReplaceAll(str, "ё", "е")
+
+	re := regexp.MustCompile(`[^\wа-я]+`) // пунктуация и пробелы не влияют на смысл пожелания
+	str = re.ReplaceAllString(str, " ")
+	return strings.TrimSpace(str)
+}
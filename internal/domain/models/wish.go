@@ -0,0 +1,7 @@
+package models
+
+type WishClarification struct {
+	IsClear      bool
+	ImprovedWish string
+	Questions    []string
+}
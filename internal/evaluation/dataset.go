@@ -0,0 +1,46 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	"os"
+)
+
+type Case struct {
+	Name          string     `json:"name"`
+	Wish          string     `json:"wish"`
+	Vacancy       hh.Vacancy `json:"vacancy"`
+	ExpectedMatch bool       `json:"expected_match"`
+}
+
+func LoadDataset(path string) ([]Case, error) {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read dataset: %w", err)
+	}
+
+	var cases []Case
+	if err = json.Unmarshal(data, &cases); err != nil {
+		return nil, fmt.Errorf("can't parse dataset %s: %w", path, err)
+	}
+
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("dataset %s is empty", path)
+	}
+
+	for i, c := range cases {
+		if c.Wish == "" {
+			return nil, fmt.Errorf("case #%d has no wish", i+1)
+		}
+		if c.Vacancy.Description == "" && c.Vacancy.Name == "" {
+			return nil, fmt.Errorf("case #%d has no vacancy", i+1)
+		}
+		if c.Name == "" {
+			cases[i].Name = fmt.Sprintf("#%d %s", i+1, c.Vacancy.Name)
+		}
+	}
+
+	return cases, nil
+}
@@ -0,0 +1,36 @@
+package evaluation
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/services"
+	log "github.com/sirupsen/logrus"
+)
+
+type matcher interface {
+	DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, prompt services.MatchPrompt,
+		vacancy models.Vacancy) (models.MatchVerdict, error)
+	MatchPrompt(ctx context.Context, search models.JobSearch) services.MatchPrompt
+}
+
+func Evaluate(ctx context.Context, matcher matcher, cases []Case) Report {
+
+	report := Report{Results: make([]Result, 0, len(cases))}
+
+	for _, c := range cases {
+		if ctx.Err() != nil {
+			break
+		}
+
+		search := models.JobSearch{UserWish: c.Wish}
+		verdict, err := matcher.DoesVacancyMatchSearch(ctx, search, matcher.MatchPrompt(ctx, search),
+			services.PrepareVacancy(services.VacancyFromHH(c.Vacancy)))
+		if err != nil {
+			log.Warnf("case %q failed: %v", c.Name, err)
+		}
+
+		report.Results = append(report.Results, Result{Case: c, Verdict: verdict, Err: err})
+	}
+
+	return report
+}
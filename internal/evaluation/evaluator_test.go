@@ -0,0 +1,103 @@
+package evaluation
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	"github.com/maxaizer/hh-parser/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type mockAiClient struct {
+	mock.Mock
+}
+
+func (m *mockAiClient) GenerateJSONResponse(ctx context.Context, request string, schema *ai.Schema) (ai.Response, error) {
+	args := m.Called(ctx, request, schema)
+	return ai.Response{Text: args.String(0)}, args.Error(1)
+}
+
+func (m *mockAiClient) Model() string {
+	return "mock-model"
+}
+
+func verdictResponse(score int) string {
+	return `{"score": ` + strconv.Itoa(score) +
+		`, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": "тест"}`
+}
+
+func forVacancy(name string) any {
+	return mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Название вакансии: "+name+"\n")
+	})
+}
+
+func Test_Evaluate_ShouldBuildConfusionMatrixAndDiffs(t *testing.T) {
+
+	assert := assert.New(t)
+
+	cases, err := LoadDataset("testdata/dataset.json")
+	assert.NoError(err)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, forVacancy("Go разработчик"), mock.Anything).
+		Return(verdictResponse(90), nil)
+	aiClient.On("GenerateJSONResponse", mock.Anything, forVacancy("PHP разработчик"), mock.Anything).
+		Return(verdictResponse(10), nil)
+	aiClient.On("GenerateJSONResponse", mock.Anything, forVacancy("Go разработчик (гибрид)"), mock.Anything).
+		Return(verdictResponse(70), nil)
+	aiClient.On("GenerateJSONResponse", mock.Anything, forVacancy("Backend разработчик"), mock.Anything).
+		Return("", errors.New("boom"))
+
+	prompts, err := services.LoadPromptTemplates("../../configs/prompts/match.tmpl", "../../configs/prompts/batch_match.tmpl")
+	assert.NoError(err)
+
+	report := Evaluate(context.Background(), services.NewAIService(&aiClient, prompts), cases)
+	matrix := report.ConfusionMatrix()
+
+	assert.Len(report.Results, 4)
+	assert.Equal(ConfusionMatrix{TruePositive: 1, FalsePositive: 1, TrueNegative: 1}, matrix)
+	assert.Equal(1, report.Errors())
+	assert.InDelta(0.5, matrix.Precision(), 0.001)
+	assert.InDelta(1.0, matrix.Recall(), 0.001)
+
+	var out bytes.Buffer
+	assert.NoError(report.Write(&out))
+	assert.Contains(out.String(), "precision: 0.500")
+	assert.Contains(out.String(), "- hybrid go")
+	assert.Contains(out.String(), "- remote go with php")
+	assert.Contains(out.String(), "error: boom")
+	assert.NotContains(out.String(), "- office php")
+}
+
+func Test_Evaluate_ShouldSendCleanedDescriptionAsAnalyzerDoes(t *testing.T) {
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "Описание: Удалённо, Go\n")
+	}), mock.Anything).Return(verdictResponse(90), nil).Once()
+
+	prompts, err := services.LoadPromptTemplates("../../configs/prompts/match.tmpl", "../../configs/prompts/batch_match.tmpl")
+	assert.NoError(t, err)
+
+	cases := []Case{{Name: "html", Wish: "удалёнка", ExpectedMatch: true,
+		Vacancy: hh.Vacancy{VacancyPreview: hh.VacancyPreview{Name: "Go разработчик"},
+			Description: "<p>Удалённо,</p>   <strong>Go</strong>"}}}
+	report := Evaluate(context.Background(), services.NewAIService(&aiClient, prompts), cases)
+
+	assert.Equal(t, 0, report.Errors())
+	aiClient.AssertExpectations(t)
+}
+
+func Test_ConfusionMatrix_WhenNoPositives_ShouldReturnZero(t *testing.T) {
+	matrix := ConfusionMatrix{TrueNegative: 3}
+	assert.Equal(t, 0.0, matrix.Precision())
+	assert.Equal(t, 0.0, matrix.Recall())
+	assert.Equal(t, 1.0, matrix.Accuracy())
+}
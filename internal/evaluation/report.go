@@ -0,0 +1,126 @@
+package evaluation
+
+import (
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"io"
+	"strings"
+)
+
+type Result struct {
+	Case    Case
+	Verdict models.MatchVerdict
+	Err     error
+}
+
+func (r Result) IsCorrect() bool {
+	return r.Err == nil && r.Verdict.IsMatch() == r.Case.ExpectedMatch
+}
+
+type ConfusionMatrix struct {
+	TruePositive  int
+	FalsePositive int
+	TrueNegative  int
+	FalseNegative int
+}
+
+type Report struct {
+	Results []Result
+}
+
+func (r Report) ConfusionMatrix() ConfusionMatrix {
+
+	var matrix ConfusionMatrix
+	for _, result := range r.Results {
+		if result.Err != nil {
+			continue
+		}
+
+		switch predicted, expected := result.Verdict.IsMatch(), result.Case.ExpectedMatch; {
+		case predicted && expected:
+			matrix.TruePositive++
+		case predicted && !expected:
+			matrix.FalsePositive++
+		case !predicted && !expected:
+			matrix.TrueNegative++
+		default:
+			matrix.FalseNegative++
+		}
+	}
+	return matrix
+}
+
+func (r Report) Errors() int {
+	errorsCount := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			errorsCount++
+		}
+	}
+	return errorsCount
+}
+
+func (m ConfusionMatrix) Precision() float64 {
+	return ratio(m.TruePositive, m.TruePositive+m.FalsePositive)
+}
+
+func (m ConfusionMatrix) Recall() float64 {
+	return ratio(m.TruePositive, m.TruePositive+m.FalseNegative)
+}
+
+func (m ConfusionMatrix) Accuracy() float64 {
+	return ratio(m.TruePositive+m.TrueNegative, m.TruePositive+m.TrueNegative+m.FalsePositive+m.FalseNegative)
+}
+
+func ratio(numerator, denominator int) float64 {
+	if denominator == 0 {
+		return 0
+	}
+	return float64(numerator) / float64(denominator)
+}
+
+func (r Report) Write(w io.Writer) error {
+
+	matrix := r.ConfusionMatrix()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "cases: %d, errors: %d\n", len(r.Results), r.Errors())
+	fmt.Fprintf(&b, "precision: %.3f\n", matrix.Precision())
+	fmt.Fprintf(&b, "recall:    %.3f\n", matrix.Recall())
+	fmt.Fprintf(&b, "accuracy:  %.3f\n\n", matrix.Accuracy())
+
+	fmt.Fprintf(&b, "%-18s %-12s %-12s\n", "", "predicted +", "predicted -")
+	fmt.Fprintf(&b, "%-18s %-12d %-12d\n", "expected +", matrix.TruePositive, matrix.FalseNegative)
+	fmt.Fprintf(&b, "%-18s %-12d %-12d\n", "expected -", matrix.FalsePositive, matrix.TrueNegative)
+
+	var diffs []Result
+	for _, result := range r.Results {
+		if !result.IsCorrect() {
+			diffs = append(diffs, result)
+		}
+	}
+
+	if len(diffs) > 0 {
+		fmt.Fprintf(&b, "\nmismatches:\n")
+	}
+	for _, result := range diffs {
+		fmt.Fprintf(&b, "- %s\n", result.Case.Name)
+		fmt.Fprintf(&b, "  wish: %s\n", result.Case.Wish)
+		if result.Err != nil {
+			fmt.Fprintf(&b, "  error: %v\n", result.Err)
+			continue
+		}
+		fmt.Fprintf(&b, "  expected match: %t, got: %t (score %d, confidence %s)\n",
+			result.Case.ExpectedMatch, result.Verdict.IsMatch(), result.Verdict.Score, result.Verdict.Confidence)
+		if len(result.Verdict.MatchedCriteria) > 0 {
+			fmt.Fprintf(&b, "  matched: %s\n", strings.Join(result.Verdict.MatchedCriteria, "; "))
+		}
+		if len(result.Verdict.MissedCriteria) > 0 {
+			fmt.Fprintf(&b, "  missed: %s\n", strings.Join(result.Verdict.MissedCriteria, "; "))
+		}
+		fmt.Fprintf(&b, "  rationale: %s\n", result.Verdict.Rationale)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -47,6 +47,71 @@ var (
 			Help: "Total number of vacancies that were rejected by AI.",
 		},
 	)
+	RejectedByRulesVacanciesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_vacancies_rules_rejected_total",
+			Help: "Total number of vacancies that were rejected by search rules before AI.",
+		},
+		[]string{"rule"},
+	)
+	AiTokensCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_ai_tokens_total",
+			Help: "Total number of AI tokens consumed by user searches.",
+		},
+		[]string{"user_id", "type"},
+	)
+	AiUserQuotaExceededCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bot_ai_user_quota_exceeded_total",
+			Help: "Total number of vacancies postponed because the user exceeded the daily AI quota.",
+		},
+	)
+	ParkedVacanciesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bot_vacancies_parked_total",
+			Help: "Total number of vacancies postponed until the next AI quota window.",
+		},
+	)
+	AiModelSwitchesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_ai_model_switches_total",
+			Help: "Total number of switches between AI models of the fallback chain.",
+		},
+		[]string{"from", "to"},
+	)
+	RejectedBySimilarityVacanciesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bot_vacancies_rejected_by_similarity_total",
+			Help: "Total number of vacancies rejected by embedding similarity without a generative AI check.",
+		},
+	)
+	WishClarificationsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_wish_clarifications_total",
+			Help: "Total number of AI wish clarifications by outcome.",
+		},
+		[]string{"result"},
+	)
+	FeedbackCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "bot_vacancies_feedback_total",
+			Help: "Total number of user feedback on found vacancies.",
+		},
+		[]string{"positive"},
+	)
+	AiVerdictCacheHitsCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bot_ai_verdict_cache_hits_total",
+			Help: "Total number of AI verdicts that were taken from the cache.",
+		},
+	)
+	AiVerdictCacheMissesCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "bot_ai_verdict_cache_misses_total",
+			Help: "Total number of AI verdicts that were not found in the cache.",
+		},
+	)
 )
 
 func StartMetricsServer() {
@@ -58,6 +123,16 @@ func StartMetricsServer() {
 	prometheus.MustRegister(HandledVacanciesCounter)
 	prometheus.MustRegister(ApprovedByAiVacanciesCounter)
 	prometheus.MustRegister(RejectedByAiVacanciesCounter)
+	prometheus.MustRegister(RejectedByRulesVacanciesCounter)
+	prometheus.MustRegister(RejectedBySimilarityVacanciesCounter)
+	prometheus.MustRegister(AiVerdictCacheHitsCounter)
+	prometheus.MustRegister(AiVerdictCacheMissesCounter)
+	prometheus.MustRegister(FeedbackCounter)
+	prometheus.MustRegister(AiModelSwitchesCounter)
+	prometheus.MustRegister(ParkedVacanciesCounter)
+	prometheus.MustRegister(AiTokensCounter)
+	prometheus.MustRegister(AiUserQuotaExceededCounter)
+	prometheus.MustRegister(WishClarificationsCounter)
 
 	http.Handle("/metrics", promhttp.Handler())
 	go func() {
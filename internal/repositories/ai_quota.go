@@ -0,0 +1,36 @@
+package repositories
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type AIQuota struct {
+	db *gorm.DB
+}
+
+func NewAIQuotaRepository(db *gorm.DB) *AIQuota {
+	return &AIQuota{db: db}
+}
+
+func (repo *AIQuota) TryAcquire(ctx context.Context, key string, window string, limit int) (bool, error) {
+
+	acquired := false
+	err := repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.AIQuotaUsage{Key: key, Day: window}).Error
+		if err != nil {
+			return err
+		}
+
+		res := tx.Model(&models.AIQuotaUsage{}).
+			Where("key = ? AND day = ? AND requests < ?", key, window, limit).
+			Updates(map[string]any{"requests": gorm.Expr("requests + 1"), "updated_at": time.Now().UTC()})
+		acquired = res.RowsAffected == 1
+		return res.Error
+	})
+	return acquired, err
+}
@@ -2,12 +2,17 @@ package repositories
 
 import (
 	"context"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
 	gocache "github.com/patrickmn/go-cache"
+	"slices"
 	"time"
 )
 
 type regionRepository interface {
 	GetIdByName(ctx context.Context, name string) (string, error)
+	GetByID(ctx context.Context, id string) (*models.Region, error)
+	Search(ctx context.Context, name string, limit int) ([]models.Region, error)
 }
 
 type CachedRegions struct {
@@ -31,3 +36,21 @@ func (c CachedRegions) GetIdByName(ctx context.Context, name string) (string, er
 
 	return id, err
 }
+
+func (c CachedRegions) GetByID(ctx context.Context, id string) (*models.Region, error) {
+	return c.repo.GetByID(ctx, id)
+}
+
+func (c CachedRegions) Search(ctx context.Context, name string, limit int) ([]models.Region, error) {
+	key := fmt.Sprintf("search:%s:%d", models.NormalizeRegionName(name), limit)
+	if value, found := c.cache.Get(key); found {
+		return slices.Clone(value.([]models.Region)), nil
+	}
+
+	regions, err := c.repo.Search(ctx, name, limit)
+	if err == nil {
+		c.cache.Set(key, slices.Clone(regions), gocache.DefaultExpiration)
+	}
+
+	return regions, err
+}
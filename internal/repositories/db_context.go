@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"fmt"
 	"github.com/glebarez/sqlite"
 	"github.com/maxaizer/hh-parser/internal/clients/hh"
@@ -24,7 +25,11 @@ func NewDbContext(connectionString string) (*DbContext, error) {
 	return &DbContext{DB: db}, nil
 }
 
-func (c *DbContext) Migrate() error {
+type areasSource interface {
+	GetAreas(ctx context.Context) ([]hh.Area, error)
+}
+
+func (c *DbContext) Migrate(areas areasSource) error {
 	err := c.DB.AutoMigrate(models.Region{})
 	if err != nil {
 		return fmt.Errorf("failed to migrate Region entity: %w", err)
@@ -50,13 +55,62 @@ func (c *DbContext) Migrate() error {
 		return fmt.Errorf("failed to migrate ArbitraryData entity: %w", err)
 	}
 
-	var regionsCount int64
+	err = c.DB.AutoMigrate(models.CachedVerdict{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate CachedVerdict entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.VacancyFeedback{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate VacancyFeedback entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.AIUsage{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate AIUsage entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.AIQuotaUsage{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate AIQuotaUsage entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.Embedding{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate Embedding entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.SimilarityRejection{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate SimilarityRejection entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.CachedEmployer{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate CachedEmployer entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.EmployerListEntry{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate EmployerListEntry entity: %w", err)
+	}
+
+	err = c.DB.AutoMigrate(models.DictionaryEntry{})
+	if err != nil {
+		return fmt.Errorf("failed to migrate DictionaryEntry entity: %w", err)
+	}
+
+	var regionsCount, nestedRegionsCount int64
 	if err = c.DB.Model(models.Region{}).Count(&regionsCount).Error; err != nil {
 		return fmt.Errorf("failed to count regions: %w", err)
 	}
+	if err = c.DB.Model(models.Region{}).Where("parent_id <> ''").Count(&nestedRegionsCount).Error; err != nil {
+		return fmt.Errorf("failed to count regions: %w", err)
+	}
 
-	if regionsCount == 0 {
-		if err = c.PopulateRegions(); err != nil {
+	//regions stored before hierarchy was added are reloaded
+	if regionsCount == 0 || nestedRegionsCount == 0 {
+		if err = c.PopulateRegions(areas); err != nil {
 			return fmt.Errorf("failed to populate regions: %w", err)
 		}
 	}
@@ -70,9 +124,8 @@ func (c *DbContext) Migrate() error {
 	return nil
 }
 
-func (c *DbContext) PopulateRegions() error {
-	client := hh.NewClient()
-	areas, err := client.GetAreas()
+func (c *DbContext) PopulateRegions(source areasSource) error {
+	areas, err := source.GetAreas(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get areas from client: %w", err)
 	}
@@ -81,10 +134,18 @@ func (c *DbContext) PopulateRegions() error {
 
 	for _, area := range areas {
 		region := models.NewRegion(area.ID, area.Name)
+		region.ParentID = area.ParentID
+		region.Level = area.Level
 		regions = append(regions, region)
 	}
 
-	if err = c.DB.Create(regions).Error; err != nil {
+	err = c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM regions").Error; err != nil {
+			return err
+		}
+		return tx.CreateInBatches(regions, 500).Error
+	})
+	if err != nil {
 		return fmt.Errorf("failed to create regions in the database: %w", err)
 	}
 	return nil
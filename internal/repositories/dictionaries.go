@@ -0,0 +1,54 @@
+package repositories
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+)
+
+type Dictionaries struct {
+	db *gorm.DB
+}
+
+func NewDictionariesRepository(db *gorm.DB) *Dictionaries {
+	return &Dictionaries{db: db}
+}
+
+func (repo *Dictionaries) GetAll(ctx context.Context) (models.Dictionaries, error) {
+
+	var entries []models.DictionaryEntry
+	if err := repo.db.WithContext(ctx).Order("dictionary, position").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	dictionaries := make(models.Dictionaries)
+	for _, entry := range entries {
+		dictionaries[entry.Dictionary] = append(dictionaries[entry.Dictionary],
+			models.DictionaryItem{ID: entry.ID, Name: entry.Name})
+	}
+	return dictionaries, nil
+}
+
+// Replace overwrites values of the given dictionaries, other stored dictionaries are kept
+func (repo *Dictionaries) Replace(ctx context.Context, dictionaries models.Dictionaries) error {
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for name, items := range dictionaries {
+
+			if err := tx.Delete(&models.DictionaryEntry{}, "dictionary = ?", name).Error; err != nil {
+				return err
+			}
+			if len(items) == 0 {
+				continue
+			}
+
+			entries := make([]models.DictionaryEntry, 0, len(items))
+			for i, item := range items {
+				entries = append(entries, models.DictionaryEntry{Dictionary: name, ID: item.ID, Name: item.Name, Position: i})
+			}
+			if err := tx.Create(&entries).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
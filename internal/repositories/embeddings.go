@@ -0,0 +1,78 @@
+package repositories
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Embeddings struct {
+	db *gorm.DB
+}
+
+func NewEmbeddingsRepository(db *gorm.DB) *Embeddings {
+	return &Embeddings{db: db}
+}
+
+func (repo *Embeddings) Get(ctx context.Context, kind models.EmbeddingKind, model string,
+	textHashes []string) (map[string][]float32, error) {
+
+	var embeddings []models.Embedding
+	err := repo.db.WithContext(ctx).
+		Where("kind = ? AND model = ? AND text_hash IN ?", kind, model, textHashes).
+		Find(&embeddings).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]float32, len(embeddings))
+	for _, embedding := range embeddings {
+		result[embedding.TextHash] = embedding.Vector
+	}
+	return result, nil
+}
+
+func (repo *Embeddings) Save(ctx context.Context, embeddings []models.Embedding) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&embeddings).Error
+}
+
+// GetRejections returns similarities of rejected vacancy texts to the wish by text hash
+func (repo *Embeddings) GetRejections(ctx context.Context, model string, wishHash string,
+	textHashes []string) (map[string]float64, error) {
+
+	var rejections []models.SimilarityRejection
+	err := repo.db.WithContext(ctx).
+		Where("model = ? AND wish_hash = ? AND text_hash IN ?", model, wishHash, textHashes).
+		Find(&rejections).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64, len(rejections))
+	for _, rejection := range rejections {
+		result[rejection.TextHash] = rejection.Similarity
+	}
+	return result, nil
+}
+
+func (repo *Embeddings) SaveRejections(ctx context.Context, rejections []models.SimilarityRejection) error {
+	if len(rejections) == 0 {
+		return nil
+	}
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&rejections).Error
+}
+
+func (repo *Embeddings) RemoveRejectionsOlderThan(ctx context.Context, createdBefore time.Time) (int64, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.SimilarityRejection{}, "created_at < ?", createdBefore.UTC())
+	return res.RowsAffected, res.Error
+}
+
+func (repo *Embeddings) RemoveOlderThan(ctx context.Context, kind models.EmbeddingKind, createdBefore time.Time) (int64, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.Embedding{}, "kind = ? AND created_at < ?", kind, createdBefore.UTC())
+	return res.RowsAffected, res.Error
+}
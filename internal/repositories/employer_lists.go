@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EmployerLists struct {
+	db *gorm.DB
+}
+
+func NewEmployerListsRepository(db *gorm.DB) *EmployerLists {
+	return &EmployerLists{db: db}
+}
+
+// Save puts employer to the list, an employer can be only in one list of the user
+func (repo *EmployerLists) Save(ctx context.Context, entry models.EmployerListEntry) error {
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "employer_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"employer_name", "kind"}),
+	}).Create(&entry).Error
+}
+
+func (repo *EmployerLists) Remove(ctx context.Context, userID int64, employerID string) (bool, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.EmployerListEntry{}, "user_id = ? AND employer_id = ?",
+		userID, employerID)
+	return res.RowsAffected > 0, res.Error
+}
+
+func (repo *EmployerLists) GetByUser(ctx context.Context, userID int64) (models.EmployerLists, error) {
+	var entries []models.EmployerListEntry
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&entries).Error
+	if err != nil {
+		return models.EmployerLists{}, err
+	}
+	return models.NewEmployerLists(entries), nil
+}
@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Employers struct {
+	db *gorm.DB
+}
+
+func NewEmployersRepository(db *gorm.DB) *Employers {
+	return &Employers{db: db}
+}
+
+func (repo *Employers) Get(ctx context.Context, ID string) (*models.Employer, error) {
+
+	var cached models.CachedEmployer
+	err := repo.db.WithContext(ctx).
+		Where("id = ? AND expires_at > ?", ID, time.Now().UTC()).
+		First(&cached).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cached.Employer, nil
+}
+
+func (repo *Employers) Save(ctx context.Context, employer models.Employer, ttl time.Duration) error {
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.CachedEmployer{
+		Employer:  employer,
+		ExpiresAt: time.Now().Add(ttl).UTC(),
+	}).Error
+}
+
+func (repo *Employers) RemoveExpired(ctx context.Context) (int64, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.CachedEmployer{}, "expires_at < ?", time.Now().UTC())
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,70 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Feedback struct {
+	db *gorm.DB
+}
+
+func NewFeedbackRepository(db *gorm.DB) *Feedback {
+	return &Feedback{db: db}
+}
+
+func (repo *Feedback) Save(ctx context.Context, userID int64, searchID int, vacancyID string, positive bool) error {
+
+	var notified models.NotifiedVacancy
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ? AND vacancy_id = ?", userID, vacancyID).
+		First(&notified).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errs.NotifiedVacancyNotFound
+		}
+		return err
+	}
+
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "search_id"}, {Name: "vacancy_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"positive", "updated_at"}),
+	}).Create(&models.VacancyFeedback{
+		UserID:             userID,
+		SearchID:           searchID,
+		VacancyID:          vacancyID,
+		VacancyName:        notified.VacancyName,
+		VacancySkills:      notified.VacancySkills,
+		VacancyDescription: notified.VacancyDescription,
+		Verdict:            notified.Verdict,
+		Positive:           positive,
+	}).Error
+}
+
+func (repo *Feedback) GetByUser(ctx context.Context, userID int64) ([]models.VacancyFeedback, error) {
+	var feedback []models.VacancyFeedback
+	err := repo.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("search_id, updated_at DESC").
+		Find(&feedback).Error
+	return feedback, err
+}
+
+func (repo *Feedback) GetRecentBySearch(ctx context.Context, searchID int, limit int) ([]models.VacancyFeedback, error) {
+	var feedback []models.VacancyFeedback
+	err := repo.db.WithContext(ctx).
+		Where("search_id = ?", searchID).
+		Order("updated_at DESC").
+		Limit(limit).
+		Find(&feedback).Error
+	return feedback, err
+}
+
+func (repo *Feedback) RemoveByUser(ctx context.Context, userID int64) (int64, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.VacancyFeedback{}, "user_id = ?", userID)
+	return res.RowsAffected, res.Error
+}
@@ -1,14 +1,23 @@
 package repositories
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"gorm.io/gorm"
+	"slices"
+	"strings"
+	"sync"
 )
 
 type Regions struct {
 	db *gorm.DB
+
+	//hierarchy is loaded once, regions are only populated on migration
+	mu        sync.Mutex
+	hierarchy []models.Region
+	byID      map[string]models.Region
 }
 
 func NewRegionsRepository(db *gorm.DB) *Regions {
@@ -27,3 +36,147 @@ func (repo *Regions) GetIdByName(ctx context.Context, name string) (string, erro
 	}
 	return region.ID, nil
 }
+
+// GetByID returns region with its parent name or nil if there is no such region
+func (repo *Regions) GetByID(ctx context.Context, id string) (*models.Region, error) {
+
+	_, byID, err := repo.loadHierarchy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	region, ok := byID[id]
+	if !ok {
+		return nil, nil
+	}
+	region.ParentName = byID[region.ParentID].Name
+	return &region, nil
+}
+
+// Search returns up to limit regions which names match name exactly, start with it or differ by a few typos.
+// The area containing the best match is suggested too, so user can search in it with all its children.
+func (repo *Regions) Search(ctx context.Context, name string, limit int) ([]models.Region, error) {
+
+	query := []rune(models.NormalizeRegionName(name))
+	if len(query) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	regions, byID, err := repo.loadHierarchy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		region models.Region
+		score  int
+	}
+
+	var matches []match
+	for _, region := range regions {
+		if score, ok := regionMatchScore(query, []rune(region.NormalizedName)); ok {
+			matches = append(matches, match{region: region, score: score})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b match) int {
+		return cmp.Or(
+			cmp.Compare(a.score, b.score),
+			cmp.Compare(a.region.Level, b.region.Level),
+			cmp.Compare(len(a.region.NormalizedName), len(b.region.NormalizedName)),
+			strings.Compare(a.region.Name, b.region.Name),
+		)
+	})
+
+	var result []models.Region
+	for _, m := range matches[:min(limit, len(matches))] {
+		result = append(result, m.region)
+	}
+
+	if len(result) > 0 {
+		parent, ok := byID[result[0].ParentID]
+		containsParent := slices.ContainsFunc(result, func(r models.Region) bool { return r.ID == parent.ID })
+		if ok && !containsParent {
+			result = slices.Insert(result, 1, parent)
+			result = result[:min(limit, len(result))]
+		}
+	}
+
+	for i := range result {
+		result[i].ParentName = byID[result[i].ParentID].Name
+	}
+	return result, nil
+}
+
+func (repo *Regions) loadHierarchy(ctx context.Context) ([]models.Region, map[string]models.Region, error) {
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if repo.byID != nil {
+		return repo.hierarchy, repo.byID, nil
+	}
+
+	var regions []models.Region
+	if err := repo.db.WithContext(ctx).Find(&regions).Error; err != nil {
+		return nil, nil, err
+	}
+
+	repo.hierarchy = regions
+	repo.byID = make(map[string]models.Region, len(regions))
+	for _, region := range regions {
+		repo.byID[region.ID] = region
+	}
+	return repo.hierarchy, repo.byID, nil
+}
+
+// regionMatchScore is 0 for exact match, 1 for prefix match and grows with number of typos,
+// typos in the whole name are preferred to typos in its prefix
+func regionMatchScore(query, name []rune) (int, bool) {
+
+	if slices.Equal(query, name) {
+		return 0, true
+	}
+	if len(name) > len(query) && slices.Equal(query, name[:len(query)]) {
+		return 1, true
+	}
+
+	//distance is at least the length difference, so most names are skipped without computing it
+	maxTypos := len(query) / 4
+	if maxTypos == 0 || len(name) < len(query)-maxTypos {
+		return 0, false
+	}
+	if len(name) <= len(query)+maxTypos {
+		if distance := levenshtein(query, name); distance <= maxTypos {
+			return 1 + 2*distance, true
+		}
+	}
+	if len(name) > len(query) {
+		if distance := levenshtein(query, name[:len(query)]); distance <= maxTypos {
+			return 2 + 2*distance, true
+		}
+	}
+	return 0, false
+}
+
+func levenshtein(a, b []rune) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			substitution := previous[j-1]
+			if a[i-1] != b[j-1] {
+				substitution++
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, substitution)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
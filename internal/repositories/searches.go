@@ -47,7 +47,8 @@ func (repo *Searches) GetCountByUser(ctx context.Context, userID int64) (int64,
 }
 
 func (repo *Searches) Update(ctx context.Context, jobSearch models.JobSearch) error {
-	return repo.db.WithContext(ctx).Model(&models.JobSearch{}).Where("id = ?", jobSearch.ID).Updates(jobSearch).Error
+	return repo.db.WithContext(ctx).Model(&models.JobSearch{}).Where("id = ?", jobSearch.ID).
+		Select("*").Omit("id", "created_at", "last_checked_vacancy_time").Updates(jobSearch).Error
 }
 
 func (repo *Searches) UpdateLastCheckedVacancy(ctx context.Context, id int, vacancy models.Vacancy) error {
@@ -70,6 +71,10 @@ func (repo *Searches) Get(ctx context.Context, limit int, offset int) ([]models.
 }
 
 func (repo *Searches) Remove(ctx context.Context, jobSearchID int) error {
-	err := repo.db.WithContext(ctx).Delete(&models.JobSearch{ID: jobSearchID}).Error
-	return err
+	return repo.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.VacancyFeedback{}, "search_id = ?", jobSearchID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.JobSearch{ID: jobSearchID}).Error
+	})
 }
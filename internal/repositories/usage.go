@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Usage struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) *Usage {
+	return &Usage{db: db}
+}
+
+func (repo *Usage) Add(ctx context.Context, usage models.AIUsage) error {
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "day"}, {Name: "user_id"}, {Name: "search_id"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"requests":         gorm.Expr("ai_usages.requests + excluded.requests"),
+			"prompt_tokens":    gorm.Expr("ai_usages.prompt_tokens + excluded.prompt_tokens"),
+			"candidate_tokens": gorm.Expr("ai_usages.candidate_tokens + excluded.candidate_tokens"),
+			"updated_at":       gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&usage).Error
+}
+
+func (repo *Usage) GetUserTokens(ctx context.Context, userID int64, day string) (int64, error) {
+	var tokens int64
+	err := repo.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("COALESCE(SUM(prompt_tokens + candidate_tokens), 0)").
+		Where("user_id = ? AND day = ?", userID, day).
+		Scan(&tokens).Error
+	return tokens, err
+}
+
+func (repo *Usage) GetReport(ctx context.Context, fromDay string) ([]models.AIUsage, error) {
+	var usage []models.AIUsage
+	err := repo.db.WithContext(ctx).Model(&models.AIUsage{}).
+		Select("user_id, search_id, SUM(requests) AS requests, SUM(prompt_tokens) AS prompt_tokens, "+
+			"SUM(candidate_tokens) AS candidate_tokens").
+		Where("day >= ?", fromDay).
+		Group("user_id, search_id").
+		Order("user_id, search_id").
+		Scan(&usage).Error
+	return usage, err
+}
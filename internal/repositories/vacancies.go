@@ -39,13 +39,18 @@ func (v *Vacancies) IsSentToUser(ctx context.Context, vacancy models.NotifiedVac
 	return true, err
 }
 
-func (v *Vacancies) RecordAsSentToUser(ctx context.Context, vacancy models.NotifiedVacancyID) error {
+func (v *Vacancies) RecordAsSentToUser(ctx context.Context, vacancyID models.NotifiedVacancyID, vacancy models.Vacancy,
+	verdict models.MatchVerdict) error {
 
 	err := v.db.WithContext(ctx).Create(&models.NotifiedVacancy{
-		UserID:          vacancy.UserID,
-		VacancyID:       vacancy.VacancyID,
-		DescriptionHash: vacancy.DescriptionHash,
-		LastCheckedAt:   time.Now().UTC(),
+		UserID:             vacancyID.UserID,
+		VacancyID:          vacancyID.VacancyID,
+		VacancyName:        vacancy.Name,
+		VacancySkills:      vacancy.KeySkills,
+		VacancyDescription: vacancy.Description,
+		DescriptionHash:    vacancyID.DescriptionHash,
+		Verdict:            verdict,
+		LastCheckedAt:      time.Now().UTC(),
 	}).Error
 	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
 		return errs.VacancyAlreadySentToUser
@@ -70,14 +75,29 @@ func (v *Vacancies) AddFailedToAnalyze(ctx context.Context, searchID int, vacanc
     `, searchID, vacancyID, error, time.Now().UTC(), time.Now().UTC()).Error
 }
 
+func (v *Vacancies) ParkForAnalysis(ctx context.Context, searchID int, vacancyID string, reason string, until time.Time) error {
+	return v.db.WithContext(ctx).Exec(`
+        INSERT INTO failed_vacancies (search_id, vacancy_id, error, attempts, parked_until, created_at, updated_at) 
+        VALUES (?, ?, ?, 0, ?, ?, ?) 
+        ON CONFLICT(search_id, vacancy_id) 
+        DO UPDATE SET 
+                      error = excluded.error,
+                      parked_until = excluded.parked_until,
+        			  updated_at = excluded.updated_at;
+    `, searchID, vacancyID, reason, until.UTC(), time.Now().UTC(), time.Now().UTC()).Error
+}
+
 func (v *Vacancies) RemoveFailedToAnalyze(ctx context.Context, maxAttempts int, minUpdateTime time.Time) (int64, error) {
-	res := v.db.WithContext(ctx).Delete(&models.FailedVacancy{}, "attempts > ? OR updated_at < ?",
-		maxAttempts, minUpdateTime.UTC())
+	res := v.db.WithContext(ctx).Delete(&models.FailedVacancy{},
+		"(attempts > ? OR updated_at < ?) AND (parked_until IS NULL OR parked_until <= ?)",
+		maxAttempts, minUpdateTime.UTC(), time.Now().UTC())
 	return res.RowsAffected, res.Error
 }
 
 func (v *Vacancies) GetFailedToAnalyze(ctx context.Context) ([]models.FailedVacancy, error) {
 	var vacancies []models.FailedVacancy
-	err := v.db.WithContext(ctx).Find(&vacancies).Error
+	err := v.db.WithContext(ctx).
+		Where("parked_until IS NULL OR parked_until <= ?", time.Now().UTC()).
+		Find(&vacancies).Error
 	return vacancies, err
 }
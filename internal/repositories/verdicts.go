@@ -0,0 +1,48 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type Verdicts struct {
+	db *gorm.DB
+}
+
+func NewVerdictsRepository(db *gorm.DB) *Verdicts {
+	return &Verdicts{db: db}
+}
+
+func (repo *Verdicts) Get(ctx context.Context, key models.VerdictCacheKey) (*models.MatchVerdict, error) {
+
+	var cached models.CachedVerdict
+	err := repo.db.WithContext(ctx).
+		Where("wish_hash = ? AND description_hash = ? AND prompt_version = ? AND model = ? AND expires_at > ?",
+			key.WishHash, key.DescriptionHash, key.PromptVersion, key.Model, time.Now().UTC()).
+		First(&cached).Error
+
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &cached.Verdict, nil
+}
+
+func (repo *Verdicts) Save(ctx context.Context, key models.VerdictCacheKey, verdict models.MatchVerdict, ttl time.Duration) error {
+	return repo.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.CachedVerdict{
+		VerdictCacheKey: key,
+		Verdict:         verdict,
+		ExpiresAt:       time.Now().Add(ttl).UTC(),
+	}).Error
+}
+
+func (repo *Verdicts) RemoveExpired(ctx context.Context) (int64, error) {
+	res := repo.db.WithContext(ctx).Delete(&models.CachedVerdict{}, "expires_at < ?", time.Now().UTC())
+	return res.RowsAffected, res.Error
+}
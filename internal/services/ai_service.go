@@ -2,62 +2,397 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/logger"
+	"github.com/maxaizer/hh-parser/internal/metrics"
 	log "github.com/sirupsen/logrus"
+	"strconv"
 	"strings"
+	"time"
 )
 
 type aiClient interface {
-	GenerateResponse(ctx context.Context, request string) (string, error)
+	GenerateJSONResponse(ctx context.Context, request string, schema *ai.Schema) (ai.Response, error)
+	Model() string
+}
+
+type feedbackExamplesRepository interface {
+	GetRecentBySearch(ctx context.Context, searchID int, limit int) ([]models.VacancyFeedback, error)
+}
+
+type usageRecorder interface {
+	Add(ctx context.Context, usage models.AIUsage) error
 }
 
 type AIService struct {
-	aiClient aiClient
+	aiClient          aiClient
+	prompts           PromptTemplates
+	feedback          feedbackExamplesRepository
+	feedbackExamples  int
+	usage             usageRecorder
+	clarifyWishPrompt *PromptTemplate
+}
+
+var matchVerdictSchema = &ai.Schema{
+	Type: ai.TypeObject,
+	Properties: map[string]*ai.Schema{
+		"score": {
+			Type:        ai.TypeInteger,
+			Description: "Степень соответствия вакансии пожеланию от 0 до 100",
+		},
+		"confidence": {
+			Type: ai.TypeString,
+			Enum: []string{string(models.ConfidenceLow), string(models.ConfidenceMedium), string(models.ConfidenceHigh)},
+		},
+		"matched_criteria": {
+			Type:  ai.TypeArray,
+			Items: &ai.Schema{Type: ai.TypeString},
+		},
+		"missed_criteria": {
+			Type:  ai.TypeArray,
+			Items: &ai.Schema{Type: ai.TypeString},
+		},
+		"rationale": {
+			Type:        ai.TypeString,
+			Description: "Краткое обоснование в одном-двух предложениях",
+		},
+		"summary": vacancySummarySchema,
+	},
+	Required: []string{"score", "confidence", "matched_criteria", "missed_criteria", "rationale", "summary"},
+}
+
+var vacancySummarySchema = &ai.Schema{
+	Type:        ai.TypeObject,
+	Description: "Краткая выжимка из вакансии",
+	Properties: map[string]*ai.Schema{
+		"salary": {
+			Type:        ai.TypeString,
+			Description: "Зарплата как указана в вакансии, пустая строка если не указана",
+		},
+		"stack": {
+			Type:        ai.TypeArray,
+			Description: "Основные технологии и инструменты",
+			Items:       &ai.Schema{Type: ai.TypeString},
+		},
+		"work_format": {
+			Type: ai.TypeString,
+			Enum: []string{string(models.WorkFormatRemote), string(models.WorkFormatOffice),
+				string(models.WorkFormatHybrid), string(models.WorkFormatUnknown)},
+		},
+		"company_size": {
+			Type:        ai.TypeString,
+			Description: "Размер компании по упоминаниям в тексте, пустая строка если неизвестен",
+		},
+		"red_flags": {
+			Type:        ai.TypeArray,
+			Description: "Настораживающие условия: переработки, серая зарплата, размытые обязанности и т.п.",
+			Items:       &ai.Schema{Type: ai.TypeString},
+		},
+	},
+	Required: []string{"salary", "stack", "work_format", "company_size", "red_flags"},
+}
+
+var batchMatchVerdictSchema = &ai.Schema{
+	Type: ai.TypeObject,
+	Properties: map[string]*ai.Schema{
+		"verdicts": {
+			Type:  ai.TypeArray,
+			Items: batchVerdictItemSchema(),
+		},
+	},
+	Required: []string{"verdicts"},
+}
+
+func batchVerdictItemSchema() *ai.Schema {
+	properties := map[string]*ai.Schema{"vacancy_id": {Type: ai.TypeString}}
+	for name, property := range matchVerdictSchema.Properties {
+		properties[name] = property
+	}
+	return &ai.Schema{
+		Type:       ai.TypeObject,
+		Properties: properties,
+		Required:   append([]string{"vacancy_id"}, matchVerdictSchema.Required...),
+	}
+}
+
+type batchMatchVerdictResponse struct {
+	Verdicts []json.RawMessage `json:"verdicts"`
+}
+
+type matchVerdictResponse struct {
+	Score           *int     `json:"score"`
+	Confidence      string   `json:"confidence"`
+	MatchedCriteria []string `json:"matched_criteria"`
+	MissedCriteria  []string `json:"missed_criteria"`
+	Rationale       string   `json:"rationale"`
+	Summary         *struct {
+		Salary      string   `json:"salary"`
+		Stack       []string `json:"stack"`
+		WorkFormat  string   `json:"work_format"`
+		CompanySize string   `json:"company_size"`
+		RedFlags    []string `json:"red_flags"`
+	} `json:"summary"`
+}
+
+func NewAIService(aiClient aiClient, prompts PromptTemplates) *AIService {
+	return &AIService{aiClient: aiClient, prompts: prompts}
+}
+
+func (a *AIService) WithFeedbackExamples(feedback feedbackExamplesRepository, limit int) {
+	a.feedback = feedback
+	a.feedbackExamples = limit
+}
+
+func (a *AIService) WithUsageRecorder(usage usageRecorder) {
+	a.usage = usage
 }
 
-func NewAIService(aiClient aiClient) *AIService {
-	return &AIService{aiClient: aiClient}
+func (a *AIService) Model() string {
+	return a.aiClient.Model()
 }
 
-func (a *AIService) DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, vacancy models.Vacancy) (bool, error) {
-	response, err := a.aiClient.GenerateResponse(ctx, a.vacancyMatchSearchRequest(search, vacancy))
+// MatchPrompt returns the part of match prompts shared by all vacancies of the search, it's prepared once
+// per batch to not query feedback examples for every vacancy
+func (a *AIService) MatchPrompt(ctx context.Context, search models.JobSearch) MatchPrompt {
+
+	prompt := MatchPrompt{Examples: a.examples(ctx, search)}
+	if len(prompt.Examples) > 0 {
+		hash := sha256.New()
+		for _, example := range prompt.Examples {
+			_, _ = fmt.Fprintf(hash, "%s:%t;", example.VacancyID, example.Positive)
+		}
+		prompt.feedbackHash = hex.EncodeToString(hash.Sum(nil))[:16]
+	}
+
+	prompt.Version = prompt.withFeedback(a.prompts.Match.Version() + "/" + a.prompts.BatchMatch.Version())
+	return prompt
+}
+
+func (a *AIService) DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, prompt MatchPrompt,
+	vacancy models.Vacancy) (models.MatchVerdict, error) {
+	request, err := a.prompts.Match.Execute(matchPromptData{Search: search, Vacancy: vacancy,
+		Examples: prompt.Examples})
 	if err != nil {
-		return false, err
+		return models.MatchVerdict{}, fmt.Errorf("can't build prompt: %w", err)
+	}
+
+	response, model, err := a.generate(ctx, search, request, matchVerdictSchema)
+	if err != nil {
+		return models.MatchVerdict{}, err
 	}
 
 	log.Infof("got response \"%v\" for vacancy %v", response, vacancy.Url)
-	response = strings.ReplaceAll(strings.ToLower(response), "*", "") //т.к. иногда может ответить **скорее нет**
 
-	if hasPrefixes(response, []string{"скорее да", "да"}) {
-		return true, nil
-	} else if hasPrefixes(response, []string{"скорее нет", "нет"}) {
-		return false, nil
-	} else {
-		return false, fmt.Errorf("unexpected response \"%v\" for vacancy %v", response, vacancy.Url)
+	verdict, err := parseMatchVerdict(response)
+	if err != nil {
+		return models.MatchVerdict{}, fmt.Errorf("unexpected response \"%v\" for vacancy %v: %w", response, vacancy.Url, err)
 	}
+	verdict.PromptVersion = prompt.withFeedback(a.prompts.Match.Version())
+	verdict.Model = model
+	return verdict, nil
 }
 
-func (a *AIService) vacancyMatchSearchRequest(search models.JobSearch, vacancy models.Vacancy) (request string) {
+func (a *AIService) DoVacanciesMatchSearch(ctx context.Context, search models.JobSearch, prompt MatchPrompt,
+	vacancies []models.Vacancy) (map[string]models.MatchVerdict, map[string]error) {
+
+	verdicts := make(map[string]models.MatchVerdict, len(vacancies))
+	failures := make(map[string]error)
+
+	if len(vacancies) > 1 {
+		response, model, err := a.requestBatchMatch(ctx, search, prompt, vacancies)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ai.ErrQuotaExhausted) {
+				for _, vacancy := range vacancies {
+					failures[vacancy.ID] = err
+				}
+				return verdicts, failures
+			}
+			log.Warnf("batch request for search %v failed, falling back to single requests: %v", search.ID, err)
+		} else {
+			log.Infof("got batch response \"%v\" for search %v", response, search.ID)
+			verdicts = parseBatchMatchVerdicts(response, vacancies)
+			for id, verdict := range verdicts {
+				verdict.PromptVersion = prompt.withFeedback(a.prompts.BatchMatch.Version())
+				verdict.Model = model
+				verdicts[id] = verdict
+			}
+		}
+	}
 
-	request = "Название вакансии: " + vacancy.Name
-	request += " Описание: " + vacancy.Description
+	var quotaErr error
+	for _, vacancy := range vacancies {
+		if _, ok := verdicts[vacancy.ID]; ok {
+			continue
+		}
+		if quotaErr != nil {
+			failures[vacancy.ID] = quotaErr
+			continue
+		}
 
-	if len(vacancy.KeySkills) != 0 {
-		request += " Ключевые навыки: " + strings.Join(vacancy.KeySkills, ", ")
+		verdict, err := a.DoesVacancyMatchSearch(ctx, search, prompt, vacancy)
+		if err != nil {
+			if errors.Is(err, ai.ErrQuotaExhausted) {
+				quotaErr = err
+			}
+			failures[vacancy.ID] = err
+			continue
+		}
+		verdicts[vacancy.ID] = verdict
 	}
 
-	request += " Пожелание к вакансии: " + search.UserWish
-	request += " Ты фильтруешь вакансии на основе пожелания пользователя. Соответствует ли вакансия его запросу? " +
-		"Тщательно проанализируй. Можешь отвечать в качестве степени уверенности (по нарастающей) только \"нет\",\"скорее нет\",\"скорее да\", \"да\""
-	return request
+	return verdicts, failures
+}
+
+func (a *AIService) requestBatchMatch(ctx context.Context, search models.JobSearch, prompt MatchPrompt,
+	vacancies []models.Vacancy) (string, string, error) {
+
+	request, err := a.prompts.BatchMatch.Execute(batchMatchPromptData{Search: search, Vacancies: vacancies,
+		Examples: prompt.Examples})
+	if err != nil {
+		return "", "", fmt.Errorf("can't build prompt: %w", err)
+	}
+	return a.generate(ctx, search, request, batchMatchVerdictSchema)
 }
 
-func hasPrefixes(str string, prefixes []string) bool {
-	for _, prefix := range prefixes {
-		if strings.HasPrefix(str, prefix) {
-			return true
+func (a *AIService) generate(ctx context.Context, search models.JobSearch, request string,
+	schema *ai.Schema) (string, string, error) {
+
+	response, err := a.aiClient.GenerateJSONResponse(ctx, request, schema)
+	if err != nil {
+		return "", "", err
+	}
+
+	a.recordUsage(context.WithoutCancel(ctx), search, response.Usage)
+
+	model := response.Model
+	if model == "" {
+		model = a.aiClient.Model()
+	}
+	return response.Text, model, nil
+}
+
+func (a *AIService) recordUsage(ctx context.Context, search models.JobSearch, usage ai.Usage) {
+
+	userID := strconv.FormatInt(search.UserID, 10)
+	metrics.AiTokensCounter.WithLabelValues(userID, "prompt").Add(float64(usage.PromptTokens))
+	metrics.AiTokensCounter.WithLabelValues(userID, "candidate").Add(float64(usage.CandidateTokens))
+
+	if a.usage == nil {
+		return
+	}
+
+	err := a.usage.Add(ctx, models.AIUsage{
+		Day:             models.UsageDay(time.Now()),
+		UserID:          search.UserID,
+		SearchID:        search.ID,
+		Requests:        1,
+		PromptTokens:    int64(usage.PromptTokens),
+		CandidateTokens: int64(usage.CandidateTokens),
+	})
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).
+			Errorf("failed to record AI usage for search %d: %v", search.ID, err)
+	}
+}
+
+func (a *AIService) examples(ctx context.Context, search models.JobSearch) []models.VacancyFeedback {
+
+	if a.feedback == nil || a.feedbackExamples <= 0 || search.ID == 0 {
+		return nil
+	}
+
+	examples, err := a.feedback.GetRecentBySearch(ctx, search.ID, a.feedbackExamples)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).
+			Errorf("failed to get feedback examples for search %d: %v", search.ID, err)
+		return nil
+	}
+	return examples
+}
+
+func parseBatchMatchVerdicts(response string, vacancies []models.Vacancy) map[string]models.MatchVerdict {
+
+	verdicts := make(map[string]models.MatchVerdict, len(vacancies))
+
+	var parsed batchMatchVerdictResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		log.Warnf("malformed batch response: %v", err)
+		return verdicts
+	}
+
+	requested := make(map[string]struct{}, len(vacancies))
+	for _, vacancy := range vacancies {
+		requested[vacancy.ID] = struct{}{}
+	}
+
+	for _, item := range parsed.Verdicts {
+		var id struct {
+			VacancyID string `json:"vacancy_id"`
+		}
+		if err := json.Unmarshal(item, &id); err != nil {
+			continue
+		}
+		if _, ok := requested[id.VacancyID]; !ok {
+			continue
+		}
+
+		verdict, err := parseMatchVerdict(string(item))
+		if err != nil {
+			log.Warnf("malformed verdict for vacancy %v in batch response: %v", id.VacancyID, err)
+			continue
+		}
+		verdicts[id.VacancyID] = verdict
+	}
+
+	return verdicts
+}
+
+func parseMatchVerdict(response string) (models.MatchVerdict, error) {
+
+	var parsed matchVerdictResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return models.MatchVerdict{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if parsed.Score == nil {
+		return models.MatchVerdict{}, fmt.Errorf("score is missing")
+	}
+	if *parsed.Score < 0 || *parsed.Score > 100 {
+		return models.MatchVerdict{}, fmt.Errorf("score %d is out of range", *parsed.Score)
+	}
+
+	confidence, ok := models.ToConfidence(strings.ToLower(parsed.Confidence))
+	if !ok {
+		return models.MatchVerdict{}, fmt.Errorf("invalid confidence %q", parsed.Confidence)
+	}
+
+	verdict := models.MatchVerdict{
+		Score:           *parsed.Score,
+		Confidence:      confidence,
+		MatchedCriteria: parsed.MatchedCriteria,
+		MissedCriteria:  parsed.MissedCriteria,
+		Rationale:       parsed.Rationale,
+	}
+
+	// summary is informational only, so a missing or partially invalid one doesn't fail the verdict
+	if parsed.Summary != nil {
+		workFormat, ok := models.ToWorkFormat(strings.ToLower(parsed.Summary.WorkFormat))
+		if !ok {
+			workFormat = models.WorkFormatUnknown
+		}
+		verdict.Summary = models.VacancySummary{
+			Salary:      strings.TrimSpace(parsed.Summary.Salary),
+			Stack:       parsed.Summary.Stack,
+			WorkFormat:  workFormat,
+			CompanySize: strings.TrimSpace(parsed.Summary.CompanySize),
+			RedFlags:    parsed.Summary.RedFlags,
 		}
 	}
-	return false
+	return verdict, nil
 }
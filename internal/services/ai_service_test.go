@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_AIService_DoesVacancyMatchSearch_ShouldParseVerdict(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(`{"score": 72, "confidence": "Medium", "matched_criteria": ["удалёнка"], `+
+			`"missed_criteria": ["вкусняшки"], "rationale": "есть удалёнка, но нет вкусняшек"}`, nil)
+
+	verdict, err := NewAIService(&aiClient, testPrompts(t)).DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(err)
+	assert.Equal(72, verdict.Score)
+	assert.Equal(models.ConfidenceMedium, verdict.Confidence)
+	assert.Equal([]string{"удалёнка"}, verdict.MatchedCriteria)
+	assert.Equal([]string{"вкусняшки"}, verdict.MissedCriteria)
+	assert.Equal("есть удалёнка, но нет вкусняшек", verdict.Rationale)
+	assert.True(verdict.IsMatch())
+}
+
+func Test_AIService_DoesVacancyMatchSearch_WhenMalformedResponse_ShouldReturnError(t *testing.T) {
+
+	responses := []string{
+		`да`,
+		`{"confidence": "high", "rationale": "нет оценки"}`,
+		`{"score": 150, "confidence": "high"}`,
+		`{"score": 50, "confidence": "absolutely"}`,
+	}
+
+	for _, response := range responses {
+		aiClient := mockAiClient{}
+		aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything).Return(response, nil)
+
+		_, err := NewAIService(&aiClient, testPrompts(t)).DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+		assert.Error(t, err, response)
+	}
+}
+
+func Test_AIService_DoVacanciesMatchSearch_WhenPartialResponse_ShouldFallbackToSingleRequests(t *testing.T) {
+
+	assert := assert.New(t)
+
+	vacancies := []models.Vacancy{{ID: "1"}, {ID: "2"}, {ID: "3"}}
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, batchMatchVerdictSchema).
+		Return(`{"verdicts": [`+
+			`{"vacancy_id": "1", "score": 80, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""},`+
+			`{"vacancy_id": "2", "score": 900, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""},`+
+			`{"vacancy_id": "42", "score": 80, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`+
+			`]}`, nil).Once()
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(`{"score": 10, "confidence": "low", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`, nil).
+		Twice()
+
+	verdicts, failures := NewAIService(&aiClient, testPrompts(t)).DoVacanciesMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, vacancies)
+	assert.Empty(failures)
+	assert.Len(verdicts, 3)
+	assert.Equal(80, verdicts["1"].Score)
+	assert.Equal(10, verdicts["2"].Score)
+	assert.Equal(10, verdicts["3"].Score)
+	aiClient.AssertExpectations(t)
+}
+
+func Test_AIService_DoVacanciesMatchSearch_WhenMalformedResponse_ShouldFallbackToSingleRequests(t *testing.T) {
+
+	assert := assert.New(t)
+
+	vacancies := []models.Vacancy{{ID: "1"}, {ID: "2"}}
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, batchMatchVerdictSchema).
+		Return(`[{"vacancy_id": "1"`, nil).Once()
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(`{"score": 70, "confidence": "medium", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`, nil).
+		Twice()
+
+	verdicts, failures := NewAIService(&aiClient, testPrompts(t)).DoVacanciesMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, vacancies)
+	assert.Empty(failures)
+	assert.Len(verdicts, 2)
+	aiClient.AssertExpectations(t)
+}
+
+type mockFeedbackExamples struct {
+	mock.Mock
+}
+
+func (m *mockFeedbackExamples) GetRecentBySearch(ctx context.Context, searchID int, limit int) ([]models.VacancyFeedback, error) {
+	args := m.Called(ctx, searchID, limit)
+	return args.Get(0).([]models.VacancyFeedback), args.Error(1)
+}
+
+func Test_AIService_WhenSearchHasFeedback_ShouldAddExamplesToPromptAndVersion(t *testing.T) {
+
+	assert := assert.New(t)
+
+	search := models.JobSearch{ID: 3, UserWish: "удалёнка"}
+	feedback := &mockFeedbackExamples{}
+	feedback.On("GetRecentBySearch", mock.Anything, 3, 2).Return([]models.VacancyFeedback{
+		{VacancyID: "1", VacancyName: "Go разработчик", VacancySkills: []string{"Go", "Kafka"},
+			VacancyDescription: "Пишем платёжный шлюз…", Positive: true},
+		{VacancyID: "2", VacancyName: "PHP разработчик", Positive: false},
+	}, nil)
+	feedback.On("GetRecentBySearch", mock.Anything, 4, 2).Return([]models.VacancyFeedback{}, nil)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "«Go разработчик» (оценка ИИ 0/100): подходит\n"+
+			"  Ключевые навыки: Go, Kafka\n  Описание: Пишем платёжный шлюз…") &&
+			strings.Contains(request, "«PHP разработчик» (оценка ИИ 0/100): не подходит")
+	}), matchVerdictSchema).Return(matchedVerdictResponse, nil).Once()
+
+	service := NewAIService(&aiClient, testPrompts(t))
+	versionWithoutFeedback := service.MatchPrompt(context.Background(), search).Version
+
+	service.WithFeedbackExamples(feedback, 2)
+	prompt := service.MatchPrompt(context.Background(), search)
+	verdict, err := service.DoesVacancyMatchSearch(context.Background(), search, prompt, models.Vacancy{})
+	assert.NoError(err)
+	aiClient.AssertExpectations(t)
+	assert.True(strings.HasPrefix(verdict.PromptVersion, "match-"))
+	assert.Contains(verdict.PromptVersion, "+feedback:")
+	assert.True(strings.HasSuffix(prompt.Version, verdict.PromptVersion[strings.Index(verdict.PromptVersion, "+"):]))
+
+	assert.NotEqual(versionWithoutFeedback, prompt.Version)
+	assert.Equal(versionWithoutFeedback, service.MatchPrompt(context.Background(), models.JobSearch{ID: 4}).Version)
+}
+
+func Test_AIService_ShouldRecordUsagePerSearch(t *testing.T) {
+
+	search := models.JobSearch{ID: 3, UserID: 7}
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(ai.Response{Text: matchedVerdictResponse, Usage: ai.Usage{PromptTokens: 120, CandidateTokens: 30}}, nil)
+
+	usage := &mockUsage{}
+	usage.On("Add", mock.Anything, models.AIUsage{
+		Day:             models.UsageDay(time.Now()),
+		UserID:          7,
+		SearchID:        3,
+		Requests:        1,
+		PromptTokens:    120,
+		CandidateTokens: 30,
+	}).Return(nil).Once()
+
+	service := NewAIService(&aiClient, testPrompts(t))
+	service.WithUsageRecorder(usage)
+
+	_, err := service.DoesVacancyMatchSearch(context.Background(), search, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(t, err)
+	usage.AssertExpectations(t)
+}
+
+func Test_AIService_DoVacanciesMatchSearch_WhenQuotaExhausted_ShouldNotFallback(t *testing.T) {
+
+	assert := assert.New(t)
+
+	quotaErr := &ai.QuotaExhaustedError{Provider: "mock", ResetAt: time.Now().Add(time.Hour)}
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, batchMatchVerdictSchema).
+		Return("", quotaErr).Once()
+
+	vacancies := []models.Vacancy{{ID: "1"}, {ID: "2"}}
+	verdicts, failures := NewAIService(&aiClient, testPrompts(t)).DoVacanciesMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, vacancies)
+
+	assert.Empty(verdicts)
+	assert.Len(failures, 2)
+	assert.ErrorIs(failures["1"], ai.ErrQuotaExhausted)
+	aiClient.AssertNumberOfCalls(t, "GenerateJSONResponse", 1)
+}
+
+func Test_AIService_ShouldRecordModelThatProducedVerdict(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(ai.Response{Text: matchedVerdictResponse, Model: "fallback-model"}, nil).Once()
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(matchedVerdictResponse, nil).Once()
+
+	service := NewAIService(&aiClient, testPrompts(t))
+
+	verdict, err := service.DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(err)
+	assert.Equal("fallback-model", verdict.Model)
+
+	verdict, err = service.DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(err)
+	assert.Equal("mock-model", verdict.Model)
+}
+
+func Test_AIService_DoesVacancyMatchSearch_ShouldParseSummary(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(`{"score": 72, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": "", `+
+			`"summary": {"salary": " от 300 000 ₽ ", "stack": ["Go", "Kafka"], "work_format": "Remote", `+
+			`"company_size": "", "red_flags": ["серая зарплата"]}}`, nil).Once()
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, matchVerdictSchema).
+		Return(`{"score": 72, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": "", `+
+			`"summary": {"work_format": "somewhere"}}`, nil).Once()
+
+	service := NewAIService(&aiClient, testPrompts(t))
+
+	verdict, err := service.DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(err)
+	assert.Equal(models.VacancySummary{
+		Salary:     "от 300 000 ₽",
+		Stack:      []string{"Go", "Kafka"},
+		WorkFormat: models.WorkFormatRemote,
+		RedFlags:   []string{"серая зарплата"},
+	}, verdict.Summary)
+
+	verdict, err = service.DoesVacancyMatchSearch(context.Background(), models.JobSearch{}, MatchPrompt{}, models.Vacancy{})
+	assert.NoError(err)
+	assert.Equal(models.WorkFormatUnknown, verdict.Summary.WorkFormat)
+	assert.True(verdict.Summary.IsEmpty())
+}
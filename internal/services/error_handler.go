@@ -2,7 +2,12 @@ package services
 
 import (
 	"context"
+	"errors"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
+	"github.com/maxaizer/hh-parser/internal/metrics"
 	log "github.com/sirupsen/logrus"
+	"time"
 )
 
 type errorHandler struct {
@@ -18,6 +23,12 @@ func (e *errorHandler) Run(errors <-chan analysisError) {
 	total := 0
 	for err := range errors {
 		total++
+
+		if until, ok := parkUntil(err.error); ok {
+			e.park(err, until)
+			continue
+		}
+
 		dbErr := e.vacancies.AddFailedToAnalyze(context.Background(), err.searchID, err.vacancyID, err.error.Error())
 		if dbErr != nil {
 			log.Errorf("couldn't add vacancy as failed to analyze: %v", dbErr)
@@ -28,3 +39,29 @@ func (e *errorHandler) Run(errors <-chan analysisError) {
 	log.Infof("saved %v vacancies as failed to analyze", total)
 	e.Done <- struct{}{}
 }
+
+func (e *errorHandler) park(err analysisError, until time.Time) {
+	dbErr := e.vacancies.ParkForAnalysis(context.Background(), err.searchID, err.vacancyID, err.error.Error(), until)
+	if dbErr != nil {
+		log.Errorf("couldn't park vacancy for analysis: %v", dbErr)
+		return
+	}
+	metrics.ParkedVacanciesCounter.Inc()
+	log.Infof("vacancy parked until %v, searchID: %v vacancyID: %v, reason: %v",
+		until, err.searchID, err.vacancyID, err.error)
+}
+
+func parkUntil(err error) (time.Time, bool) {
+
+	var quotaErr *ai.QuotaExhaustedError
+	if errors.As(err, &quotaErr) {
+		return quotaErr.ResetAt, true
+	}
+
+	if errors.Is(err, errs.UserQuotaExceeded) {
+		year, month, day := time.Now().UTC().Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC), true
+	}
+
+	return time.Time{}, false
+}
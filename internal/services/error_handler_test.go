@@ -0,0 +1,34 @@
+package services
+
+import (
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
+	"github.com/stretchr/testify/mock"
+	"testing"
+	"time"
+)
+
+func Test_ErrorHandler_WhenQuotaExhausted_ShouldParkVacancy(t *testing.T) {
+
+	resetAt := time.Now().Add(5 * time.Hour)
+	quotaErr := fmt.Errorf("analysis failed: %w", &ai.QuotaExhaustedError{Provider: "gemini", ResetAt: resetAt})
+
+	vacancies := &mockVacancies{}
+	vacancies.On("ParkForAnalysis", mock.Anything, 1, "10", quotaErr.Error(), resetAt).Return(nil).Once()
+	vacancies.On("ParkForAnalysis", mock.Anything, 1, "11", errs.UserQuotaExceeded.Error(), mock.Anything).
+		Return(nil).Once()
+	vacancies.On("AddFailedToAnalyze", mock.Anything, 1, "12", "boom").Return(nil).Once()
+
+	errChan := make(chan analysisError, 3)
+	errChan <- analysisError{vacancyID: "10", searchID: 1, error: quotaErr}
+	errChan <- analysisError{vacancyID: "11", searchID: 1, error: errs.UserQuotaExceeded}
+	errChan <- analysisError{vacancyID: "12", searchID: 1, error: fmt.Errorf("boom")}
+	close(errChan)
+
+	handler := newErrorHandler(vacancies)
+	go handler.Run(errChan)
+	<-handler.Done
+
+	vacancies.AssertExpectations(t)
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+type dictionarySource interface {
+	GetDictionaries(ctx context.Context) (hh.Dictionaries, error)
+}
+
+type dictionaryRepository interface {
+	GetAll(ctx context.Context) (models.Dictionaries, error)
+	Replace(ctx context.Context, dictionaries models.Dictionaries) error
+}
+
+// HHDictionaries keeps hh.ru dictionaries stored in db and refreshes them, so enums changed by hh.ru
+// don't require a new release
+type HHDictionaries struct {
+	source  dictionarySource
+	repo    dictionaryRepository
+	mu      sync.RWMutex
+	current models.Dictionaries
+	cron    *cron.Cron
+}
+
+func NewHHDictionaries(source dictionarySource, repo dictionaryRepository) *HHDictionaries {
+	return &HHDictionaries{source: source, repo: repo, current: models.DefaultDictionaries}
+}
+
+// Load reads stored dictionaries, they are requested from hh.ru if nothing is stored yet
+func (d *HHDictionaries) Load(ctx context.Context) error {
+
+	stored, err := d.repo.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stored dictionaries: %w", err)
+	}
+
+	if len(stored) == 0 {
+		return d.Sync(ctx)
+	}
+
+	d.set(stored)
+	return nil
+}
+
+func (d *HHDictionaries) Sync(ctx context.Context) error {
+
+	fetched, err := d.source.GetDictionaries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get dictionaries from hh: %w", err)
+	}
+
+	dictionaries := make(models.Dictionaries, len(fetched))
+	for name, items := range fetched {
+		for _, item := range items {
+			dictionaries[name] = append(dictionaries[name], models.DictionaryItem{ID: item.ID, Name: item.Name})
+		}
+	}
+
+	if err = d.repo.Replace(ctx, dictionaries); err != nil {
+		return fmt.Errorf("failed to store dictionaries: %w", err)
+	}
+
+	d.set(dictionaries)
+	log.Infof("synced %d hh dictionaries", len(dictionaries))
+	return nil
+}
+
+func (d *HHDictionaries) RunSync(interval time.Duration) error {
+
+	d.cron = cron.New()
+	_, err := d.cron.AddFunc("@every "+interval.String(), func() {
+		if err := d.Sync(context.Background()); err != nil {
+			log.Errorf("failed to sync hh dictionaries: %v", err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	d.cron.Start()
+	log.Infof("hh dictionaries sync started, interval: %v", interval)
+	return nil
+}
+
+func (d *HHDictionaries) Stop() {
+	if d.cron != nil {
+		d.cron.Stop()
+	}
+}
+
+func (d *HHDictionaries) Get() models.Dictionaries {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.current
+}
+
+func (d *HHDictionaries) set(dictionaries models.Dictionaries) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.current = models.DefaultDictionaries.Merge(dictionaries)
+}
+
+func dictionariesToHH(dictionaries models.Dictionaries) hh.Dictionaries {
+	result := make(hh.Dictionaries, len(dictionaries))
+	for name, items := range dictionaries {
+		for _, item := range items {
+			result[name] = append(result[name], hh.DictionaryItem{ID: item.ID, Name: item.Name})
+		}
+	}
+	return result
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"testing"
+)
+
+type mockDictionarySource struct {
+	mock.Mock
+}
+
+func (m *mockDictionarySource) GetDictionaries(ctx context.Context) (hh.Dictionaries, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(hh.Dictionaries), args.Error(1)
+}
+
+type mockDictionaryRepository struct {
+	mock.Mock
+}
+
+func (m *mockDictionaryRepository) GetAll(ctx context.Context) (models.Dictionaries, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(models.Dictionaries), args.Error(1)
+}
+
+func (m *mockDictionaryRepository) Replace(ctx context.Context, dictionaries models.Dictionaries) error {
+	args := m.Called(ctx, dictionaries)
+	return args.Error(0)
+}
+
+func Test_HHDictionaries_Load_WhenNothingStored_ShouldSync(t *testing.T) {
+
+	ctx := context.Background()
+	source := &mockDictionarySource{}
+	source.On("GetDictionaries", ctx).Return(hh.Dictionaries{
+		models.WorkFormatDictionary: {{ID: "REMOTE", Name: "Удалённо"}},
+	}, nil)
+	repo := &mockDictionaryRepository{}
+	repo.On("GetAll", ctx).Return(models.Dictionaries{}, nil)
+	repo.On("Replace", ctx, models.Dictionaries{
+		models.WorkFormatDictionary: {{ID: "REMOTE", Name: "Удалённо"}},
+	}).Return(nil)
+
+	dictionaries := NewHHDictionaries(source, repo)
+	assert.NoError(t, dictionaries.Load(ctx))
+
+	current := dictionaries.Get()
+	assert.Equal(t, []models.DictionaryItem{{ID: "REMOTE", Name: "Удалённо"}}, current[models.WorkFormatDictionary])
+	assert.Equal(t, models.DefaultDictionaries[models.ExperienceDictionary], current[models.ExperienceDictionary])
+	repo.AssertExpectations(t)
+}
+
+func Test_HHDictionaries_Load_ShouldUseStored(t *testing.T) {
+
+	ctx := context.Background()
+	source := &mockDictionarySource{}
+	repo := &mockDictionaryRepository{}
+	repo.On("GetAll", ctx).Return(models.Dictionaries{
+		models.ExperienceDictionary: {{ID: "noExperience", Name: "Без опыта"}},
+	}, nil)
+
+	dictionaries := NewHHDictionaries(source, repo)
+	assert.NoError(t, dictionaries.Load(ctx))
+
+	name, ok := dictionaries.Get().NameOf(models.ExperienceDictionary, "noExperience")
+	assert.True(t, ok)
+	assert.Equal(t, "Без опыта", name)
+	source.AssertNotCalled(t, "GetDictionaries", mock.Anything)
+}
+
+func Test_HHDictionaries_WhenSyncFailed_ShouldKeepCurrent(t *testing.T) {
+
+	ctx := context.Background()
+	source := &mockDictionarySource{}
+	source.On("GetDictionaries", ctx).Return(hh.Dictionaries(nil), errors.New("hh is down"))
+	repo := &mockDictionaryRepository{}
+
+	dictionaries := NewHHDictionaries(source, repo)
+	assert.Error(t, dictionaries.Sync(ctx))
+	assert.Equal(t, models.DefaultDictionaries, dictionaries.Get())
+	repo.AssertNotCalled(t, "Replace", mock.Anything, mock.Anything)
+}
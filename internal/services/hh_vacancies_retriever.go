@@ -1,99 +1,482 @@
 package services
 
 import (
+	"context"
+	"fmt"
 	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/logger"
+	gocache "github.com/patrickmn/go-cache"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
+	"strings"
+	"sync"
 	"time"
 )
 
+type employerCache interface {
+	Get(ctx context.Context, ID string) (*models.Employer, error)
+	Save(ctx context.Context, employer models.Employer, ttl time.Duration) error
+}
+
+type dictionaryProvider interface {
+	Get() models.Dictionaries
+}
+
+// VacancyError is a failure to get details of one vacancy found by search
+type VacancyError struct {
+	VacancyID string
+	Err       error
+}
+
+func (e VacancyError) Error() string {
+	return fmt.Sprintf("vacancy %s: %v", e.VacancyID, e.Err)
+}
+
+func (e VacancyError) Unwrap() error {
+	return e.Err
+}
+
+// minSearchWindow limits splitting of searches, vacancies beyond hh.ru pagination limit in such window are lost
+const minSearchWindow = time.Hour
+
+// unknownFound is Found of a window which wasn't checked before paging, it's known after the first page
+const unknownFound = -1
+
+// SearchWindow is a part of search by publication time small enough to be paged through on hh.ru,
+// Found is a number of vacancies in it at the moment of split
+type SearchWindow struct {
+	DateFrom time.Time
+	DateTo   time.Time
+	Found    int
+}
+
+// WindowPage is a page of search window, Vacancies are the ones which details were fetched and Failed are errors
+// for the rest of them. Found is a number of vacancies in the window at the moment of request.
+type WindowPage struct {
+	Vacancies []models.Vacancy
+	Failed    []VacancyError
+	Found     int
+}
+
+// searchRate is how many vacancies a search found for a period, it tells whether the next split is needed
+type searchRate struct {
+	found  int
+	period time.Duration
+}
+
 type HHVacanciesRetriever struct {
-	client *hh.Client
+	client             *hh.Client
+	employers          employerCache
+	employerTTL        time.Duration
+	dictionaries       dictionaryProvider
+	detailsConcurrency int
+	details            *gocache.Cache
+	searchRates        sync.Map
 }
 
 func NewHHVacanciesRetriever(client *hh.Client) *HHVacanciesRetriever {
-	return &HHVacanciesRetriever{client: client}
+	return &HHVacanciesRetriever{client: client, detailsConcurrency: 1}
+}
+
+// WithDetailsConcurrency sets how many vacancies are fetched at once, requests still wait for the client rate limiter
+func (r *HHVacanciesRetriever) WithDetailsConcurrency(concurrency int) {
+	r.detailsConcurrency = max(concurrency, 1)
+}
+
+// WithDetailsCache keeps fetched vacancies in memory for ttl, so vacancies found by several searches are fetched once
+func (r *HHVacanciesRetriever) WithDetailsCache(ttl time.Duration) {
+	r.details = gocache.New(ttl, 2*ttl)
+}
+
+// WithEmployerCache makes retriever fill vacancies with employer cards, which are cached for ttl
+func (r *HHVacanciesRetriever) WithEmployerCache(cache employerCache, ttl time.Duration) {
+	r.employers = cache
+	r.employerTTL = ttl
 }
 
-func (r *HHVacanciesRetriever) GetVacancies(search *models.JobSearch, dateFrom time.Time, page, pageSize int) ([]models.Vacancy, error) {
+// WithDictionaries makes retriever check search parameters against hh.ru dictionaries before the request
+func (r *HHVacanciesRetriever) WithDictionaries(dictionaries dictionaryProvider) {
+	r.dictionaries = dictionaries
+}
+
+// SplitSearch splits vacancies published since dateFrom into windows, each of them fits hh.ru pagination limit.
+// Windows are ordered from the newest to the oldest, as vacancies in them. Searches which found few vacancies
+// last time aren't checked, their only window has unknownFound.
+func (r *HHVacanciesRetriever) SplitSearch(ctx context.Context, search *models.JobSearch,
+	dateFrom time.Time) ([]SearchWindow, error) {
 
-	params, err := createHhSearchParams(search, dateFrom, page, pageSize)
+	window := SearchWindow{DateFrom: dateFrom, DateTo: time.Now().Truncate(time.Second), Found: unknownFound}
+	if r.expectedToFitLimit(search.ID, window) {
+		return []SearchWindow{window}, nil
+	}
+
+	windows, err := r.splitWindow(ctx, search, r.getDictionaries(), window)
+	if err != nil {
+		return nil, err
+	}
+
+	found := 0
+	for _, w := range windows {
+		found += w.Found
+	}
+	r.rememberRate(search.ID, window, found)
+
+	if len(windows) > 1 {
+		log.Infof("search with id %d is split into %d windows", search.ID, len(windows))
+	}
+	return windows, nil
+}
+
+// expectedToFitLimit estimates number of vacancies in window by the previous run of the search
+func (r *HHVacanciesRetriever) expectedToFitLimit(searchID int, window SearchWindow) bool {
+
+	value, ok := r.searchRates.Load(searchID)
+	if !ok || window.DateFrom.IsZero() {
+		return false
+	}
+
+	rate := value.(searchRate)
+	if rate.period <= 0 {
+		return false
+	}
+
+	//half of the limit leaves room for a burst of new vacancies
+	expected := float64(rate.found) * float64(window.DateTo.Sub(window.DateFrom)) / float64(rate.period)
+	return expected <= hh.MaxSearchResults/2
+}
+
+func (r *HHVacanciesRetriever) rememberRate(searchID int, window SearchWindow, found int) {
+	if window.DateFrom.IsZero() {
+		r.searchRates.Delete(searchID)
+		return
+	}
+	r.searchRates.Store(searchID, searchRate{found: found, period: window.DateTo.Sub(window.DateFrom)})
+}
+
+func (r *HHVacanciesRetriever) splitWindow(ctx context.Context, search *models.JobSearch,
+	dictionaries models.Dictionaries, window SearchWindow) ([]SearchWindow, error) {
+
+	params, err := createHhSearchParams(search, dictionaries, window, 0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := r.client.SearchVacancies(ctx, *params)
+	if err != nil {
+		return nil, err
+	}
+
+	window.Found = result.Found
+	if window.Found <= hh.MaxSearchResults {
+		return []SearchWindow{window}, nil
+	}
+
+	if window.DateFrom.IsZero() || window.DateTo.Sub(window.DateFrom) <= minSearchWindow {
+		log.Warnf("search with id %d has %d vacancies published from %v to %v, window can't be split further, "+
+			"only %d of them will be fetched", search.ID, window.Found, window.DateFrom, window.DateTo, hh.MaxSearchResults)
+		return []SearchWindow{window}, nil
+	}
+
+	//hh.ru compares dates up to seconds, so windows don't overlap
+	middle := window.DateFrom.Add(window.DateTo.Sub(window.DateFrom) / 2).Truncate(time.Second)
+	newer, err := r.splitWindow(ctx, search, dictionaries, SearchWindow{DateFrom: middle, DateTo: window.DateTo})
+	if err != nil {
+		return nil, err
+	}
+	older, err := r.splitWindow(ctx, search, dictionaries,
+		SearchWindow{DateFrom: window.DateFrom, DateTo: middle.Add(-time.Second)})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(newer, older...), nil
+}
+
+// GetVacancies returns page of window, vacancies removed after search are skipped
+func (r *HHVacanciesRetriever) GetVacancies(ctx context.Context, search *models.JobSearch, window SearchWindow,
+	page, pageSize int) (WindowPage, error) {
+
+	params, err := createHhSearchParams(search, r.getDictionaries(), window, page, pageSize)
 	if err != nil {
 		if errors.Is(err, hh.ErrTooDeepPagination) {
 			log.Warningf("too deep pagination for search with id %d, page: %d, per page: %d", search.ID, page, pageSize)
-			return []models.Vacancy{}, nil
+			return WindowPage{Found: window.Found}, nil
 		}
 		log.Error(err)
-		return nil, err
+		return WindowPage{}, err
 	}
 
-	previews, err := r.client.GetVacancies(*params)
+	result, err := r.client.SearchVacancies(ctx, *params)
 	if err != nil {
-		return nil, err
+		return WindowPage{}, err
+	}
+
+	if window.Found == unknownFound {
+		r.rememberRate(search.ID, window, result.Found)
+		if result.Found > hh.MaxSearchResults {
+			log.Warnf("search with id %d has %d vacancies published from %v to %v, only %d of them will be fetched "+
+				"until the next check splits it", search.ID, result.Found, window.DateFrom, window.DateTo, hh.MaxSearchResults)
+		}
 	}
 
+	vacancies, failed := r.getVacanciesDetails(ctx, result.Vacancies)
+	if ctx.Err() != nil {
+		return WindowPage{}, ctx.Err()
+	}
+	if len(vacancies) == 0 && len(failed) > 0 {
+		//nothing to return, so it's better to fail whole page and retry it later
+		return WindowPage{}, fmt.Errorf("failed to get details of all %d vacancies: %w", len(failed), failed[0])
+	}
+
+	return WindowPage{Vacancies: vacancies, Failed: failed, Found: result.Found}, nil
+}
+
+func (r *HHVacanciesRetriever) getDictionaries() models.Dictionaries {
+	if r.dictionaries == nil {
+		return nil
+	}
+	return r.dictionaries.Get()
+}
+
+func (r *HHVacanciesRetriever) getVacanciesDetails(ctx context.Context,
+	previews []hh.VacancyPreview) ([]models.Vacancy, []VacancyError) {
+
+	details := make([]*models.Vacancy, len(previews))
+	detailErrors := make([]error, len(previews))
+
+	wg := sync.WaitGroup{}
+	semaphore := make(chan struct{}, r.detailsConcurrency)
+	for i, preview := range previews {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			details[i], detailErrors[i] = r.GetVacancy(ctx, preview.ID)
+		}()
+	}
+	wg.Wait()
+
+	//order of previews is kept, analyzer relies on the first vacancy being the latest one
 	var vacancies []models.Vacancy
-	for _, preview := range previews {
-		vacancy, err := r.GetVacancy(preview.ID)
-		if err != nil {
+	var failed []VacancyError
+	for i, preview := range previews {
+		switch err := detailErrors[i]; {
+		case err == nil:
+			vacancies = append(vacancies, *details[i])
+		case errors.Is(err, hh.ErrNotFound):
+			log.Infof("vacancy %v was removed after search", preview.ID)
+		default:
+			failed = append(failed, VacancyError{VacancyID: preview.ID, Err: err})
+		}
+	}
+	return vacancies, failed
+}
+
+func (r *HHVacanciesRetriever) GetVacancy(ctx context.Context, ID string) (*models.Vacancy, error) {
+
+	if r.details != nil {
+		if cached, found := r.details.Get(ID); found {
+			vacancy := cached.(models.Vacancy)
+			return &vacancy, nil
+		}
+	}
+
+	vacancy, err := r.client.GetVacancy(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := VacancyFromHH(vacancy)
+
+	if r.employers != nil && result.Employer.ID != "" {
+		employer, err := r.GetEmployer(ctx, result.Employer.ID)
+		if errors.Is(err, context.Canceled) {
 			return nil, err
 		}
-		vacancies = append(vacancies, *vacancy)
+		if err != nil {
+			//vacancy is still usable with employer from it
+			log.Warnf("failed to get employer %v of vacancy %v: %v", result.Employer.ID, ID, err)
+		} else {
+			result.Employer = *employer
+		}
 	}
 
-	return vacancies, nil
+	if r.details != nil {
+		r.details.SetDefault(ID, result)
+	}
+	return &result, nil
 }
 
-func (r *HHVacanciesRetriever) GetVacancy(ID string) (*models.Vacancy, error) {
+func (r *HHVacanciesRetriever) GetEmployer(ctx context.Context, ID string) (*models.Employer, error) {
 
-	vacancy, err := r.client.GetVacancy(ID)
+	if r.employers != nil {
+		cached, err := r.employers.Get(ctx, ID)
+		if err != nil {
+			log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get cached employer: %v", err)
+		}
+		if cached != nil {
+			return cached, nil
+		}
+	}
+
+	details, err := r.client.GetEmployer(ctx, ID)
+	if errors.Is(err, hh.ErrNotFound) {
+		return nil, errs.EmployerNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	employer := EmployerFromHH(details)
+	if r.employers != nil {
+		if err = r.employers.Save(ctx, employer, r.employerTTL); err != nil {
+			log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to cache employer: %v", err)
+		}
+	}
+	return &employer, nil
+}
+
+func EmployerFromHH(employer hh.EmployerDetails) models.Employer {
+
+	result := models.Employer{
+		ID:            employer.ID,
+		Name:          employer.Name,
+		Trusted:       employer.Trusted,
+		SiteUrl:       employer.SiteUrl,
+		OpenVacancies: employer.OpenVacancies,
+	}
+	for _, industry := range employer.Industries {
+		result.Industries = append(result.Industries, dictionaryItemFromHH(&industry))
+	}
+	if employer.LogoUrls != nil {
+		result.LogoUrl = employer.LogoUrls.Original
+	}
+	return result
+}
+
+func VacancyFromHH(vacancy hh.Vacancy) models.Vacancy {
+
 	var skills []string
 	for _, skill := range vacancy.KeySkills {
 		skills = append(skills, skill.Name)
 	}
 
-	return &models.Vacancy{
+	result := models.Vacancy{
 		ID:          vacancy.ID,
 		Url:         vacancy.Url,
 		Name:        vacancy.Name,
 		Description: vacancy.Description,
 		KeySkills:   skills,
+		Area:        dictionaryItemFromHH(vacancy.Area),
+		Employment:  dictionaryItemFromHH(vacancy.Employment),
+		Schedule:    dictionaryItemFromHH(vacancy.Schedule),
+		Experience:  dictionaryItemFromHH(vacancy.Experience),
+		Archived:    vacancy.Archived,
 		PublishedAt: vacancy.PublishedAt.Time,
-	}, nil
-}
+	}
 
-func createHhSearchParams(search *models.JobSearch, dateFrom time.Time, page, pageSize int) (*hh.SearchParameters, error) {
-	var err error
-	schedules := lo.Map(search.SchedulesAsArray(), func(s models.Schedule, _ int) hh.Schedule {
-		schedule, _err := hh.ScheduleFrom(s)
-		if _err != nil {
-			err = _err
+	for _, role := range vacancy.ProfessionalRoles {
+		result.ProfessionalRoles = append(result.ProfessionalRoles, dictionaryItemFromHH(&role))
+	}
+	for _, language := range vacancy.Languages {
+		result.Languages = append(result.Languages, models.Language{Name: language.Name, Level: language.Level.Name})
+	}
+
+	if vacancy.Salary != nil {
+		result.Salary = &models.Salary{
+			From:     lo.FromPtr(vacancy.Salary.From),
+			To:       lo.FromPtr(vacancy.Salary.To),
+			Currency: vacancy.Salary.Currency,
+			Gross:    lo.FromPtr(vacancy.Salary.Gross),
 		}
-		return schedule
-	})
+	}
 
-	if err != nil {
-		return nil, errors.New("error map schedules")
+	if vacancy.Employer != nil {
+		result.Employer = models.Employer{
+			ID:      vacancy.Employer.ID,
+			Name:    vacancy.Employer.Name,
+			Trusted: vacancy.Employer.Trusted,
+		}
+		if vacancy.Employer.LogoUrls != nil {
+			result.Employer.LogoUrl = vacancy.Employer.LogoUrls.Original
+		}
 	}
 
+	if vacancy.Address != nil {
+		result.Address = &models.Address{
+			Raw: vacancy.Address.Raw,
+			MetroStations: lo.Uniq(lo.Map(vacancy.Address.MetroStations, func(station hh.MetroStation, _ int) string {
+				return station.StationName
+			})),
+		}
+	}
+
+	return result
+}
+
+func dictionaryItemFromHH(item *hh.DictionaryItem) models.DictionaryItem {
+	if item == nil {
+		return models.DictionaryItem{}
+	}
+	return models.DictionaryItem{ID: item.ID, Name: item.Name}
+}
+
+func createHhSearchParams(search *models.JobSearch, dictionaries models.Dictionaries, window SearchWindow,
+	page, pageSize int) (*hh.SearchParameters, error) {
+
 	params := hh.SearchParameters{
 		Text:                   search.SearchText,
 		Experience:             hh.Experience(search.Experience),
-		Schedules:              schedules,
-		DateFrom:               dateFrom,
-		AreaID:                 search.RegionID,
+		Salary:                 search.Salary,
+		Currency:               search.Currency,
+		OnlyWithSalary:         search.OnlyWithSalary,
+		DateFrom:               window.DateFrom,
+		DateTo:                 window.DateTo,
+		AreaIDs:                searchAreas(search),
+		ProfessionalRoles:      search.Filters.ProfessionalRoles,
+		Industries:             search.Filters.Industries,
+		EmployerIDs:            search.Filters.EmployerIDs,
+		ExcludedText:           search.Filters.ExcludedText,
 		OrderByPublicationTime: true,
 		Page:                   page,
 		PerPage:                pageSize,
 	}
-	if err = params.Validate(); err != nil {
+
+	for _, schedule := range search.SchedulesAsArray() {
+		params.Schedules = append(params.Schedules, hh.Schedule(schedule))
+	}
+	for _, workFormat := range search.WorkFormats {
+		params.WorkFormats = append(params.WorkFormats, hh.WorkFormat(workFormat))
+	}
+	for _, employment := range search.Filters.Employments {
+		params.Employments = append(params.Employments, hh.Employment(employment))
+	}
+	for _, field := range search.Filters.SearchFields {
+		params.SearchFields = append(params.SearchFields, hh.SearchField(field))
+	}
+	for _, label := range search.Filters.Labels {
+		params.Labels = append(params.Labels, hh.Label(label))
+	}
+
+	if err := params.Validate(); err != nil {
 		return nil, err
 	}
+	//hh.ru may remove enum values, saved searches should keep working without them
+	if dropped := params.DropUnknownValues(dictionariesToHH(dictionaries)); len(dropped) > 0 {
+		log.Warnf("search with id %d has values unknown to hh.ru, they are ignored: %s", search.ID,
+			strings.Join(dropped, ", "))
+	}
 	return &params, nil
 }
+
+func searchAreas(search *models.JobSearch) []string {
+	var areas []string
+	if search.RegionID != "" {
+		areas = append(areas, search.RegionID)
+	}
+	return lo.Uniq(append(areas, search.Filters.AreaIDs...))
+}
@@ -0,0 +1,364 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type mockEmployerCache struct {
+	mock.Mock
+}
+
+func (m *mockEmployerCache) Get(ctx context.Context, ID string) (*models.Employer, error) {
+	args := m.Called(ctx, ID)
+	return args.Get(0).(*models.Employer), args.Error(1)
+}
+
+func (m *mockEmployerCache) Save(ctx context.Context, employer models.Employer, ttl time.Duration) error {
+	args := m.Called(ctx, employer, ttl)
+	return args.Error(0)
+}
+
+func Test_CreateHhSearchParams_ShouldPassFilters(t *testing.T) {
+
+	assert := assert.New(t)
+
+	search := models.NewJobSearch(0, "golang", "1", models.NoExperience, []models.Schedule{models.Remote}, "", 1)
+	search.Salary = 250000
+	search.Filters = models.SearchFilters{
+		AreaIDs:      []string{"2", "1"},
+		Employments:  []models.Employment{models.FullEmployment},
+		SearchFields: []models.SearchField{models.SearchFieldName},
+		Labels:       []models.VacancyLabel{models.LabelNotFromAgency},
+		EmployerIDs:  []string{"1740"},
+	}
+
+	params, err := createHhSearchParams(search, nil, SearchWindow{}, 0, 10)
+	assert.NoError(err)
+	assert.Equal([]string{"1", "2"}, params.AreaIDs)
+	assert.Equal(250000, params.Salary)
+	assert.Equal([]hh.Employment{"full"}, params.Employments)
+	assert.Equal([]hh.SearchField{"name"}, params.SearchFields)
+	assert.Equal([]hh.Label{"not_from_agency"}, params.Labels)
+	assert.Equal([]string{"1740"}, params.EmployerIDs)
+
+	search.Filters.ProfessionalRoles = []string{"программист"}
+	_, err = createHhSearchParams(search, nil, SearchWindow{}, 0, 10)
+	assert.Error(err)
+}
+
+func Test_CreateHhSearchParams_ShouldDropValuesRemovedFromDictionaries(t *testing.T) {
+
+	assert := assert.New(t)
+
+	search := models.NewJobSearch(0, "golang", "1", models.NoExperience, nil, "", 1)
+	search.WorkFormats = []string{"REMOTE"}
+	dictionaries := models.Dictionaries{
+		models.WorkFormatDictionary: {{ID: "ON_SITE", Name: "На месте работодателя"}, {ID: "REMOTE", Name: "Удалённо"}},
+	}
+
+	params, err := createHhSearchParams(search, dictionaries, SearchWindow{}, 0, 10)
+	assert.NoError(err)
+	assert.Equal([]hh.WorkFormat{"REMOTE"}, params.WorkFormats)
+
+	//stored search with values hh.ru removed since it was saved
+	search.Experience = "moreThan10"
+	search.WorkFormats = []string{"FIELD_WORK", "REMOTE"}
+	dictionaries[models.ExperienceDictionary] = []models.DictionaryItem{{ID: "noExperience", Name: "Нет опыта"}}
+	params, err = createHhSearchParams(search, dictionaries, SearchWindow{}, 0, 10)
+	assert.NoError(err)
+	assert.Empty(params.Experience)
+	assert.Equal([]hh.WorkFormat{"REMOTE"}, params.WorkFormats)
+	assert.Equal("golang", params.Text)
+}
+
+func Test_GetVacancy_WithEmployerCache_ShouldFillEmployerCard(t *testing.T) {
+
+	assert := assert.New(t)
+
+	var employerRequests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintf(w, `{"id": "%s", "name": "Go developer", "published_at": "2024-10-11T10:00:00+0300",
+			"employer": {"id": "370421", "name": "Роболайн"}}`, r.PathValue("id"))
+	})
+	mux.HandleFunc("GET /employers/370421", func(w http.ResponseWriter, r *http.Request) {
+		employerRequests.Add(1)
+		_, _ = fmt.Fprint(w, `{"id": "370421", "name": "Роболайн", "trusted": true, "site_url": "https://roboline.ru",
+			"industries": [{"id": "7.540", "name": "Разработка программного обеспечения"}], "open_vacancies": 12}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := hh.NewClient()
+	client.SetBaseURL(server.URL)
+
+	employer := models.Employer{ID: "370421", Name: "Роболайн", Trusted: true, SiteUrl: "https://roboline.ru",
+		Industries: []models.DictionaryItem{{ID: "7.540", Name: "Разработка программного обеспечения"}}, OpenVacancies: 12}
+
+	cache := &mockEmployerCache{}
+	cache.On("Get", mock.Anything, "370421").Return((*models.Employer)(nil), nil).Once()
+	cache.On("Save", mock.Anything, employer, 24*time.Hour).Return(nil).Once()
+	cache.On("Get", mock.Anything, "370421").Return(&employer, nil)
+
+	retriever := NewHHVacanciesRetriever(client)
+	retriever.WithEmployerCache(cache, 24*time.Hour)
+
+	for _, id := range []string{"1", "2"} {
+		vacancy, err := retriever.GetVacancy(context.Background(), id)
+		assert.NoError(err)
+		assert.Equal(employer, vacancy.Employer)
+	}
+	assert.Equal(int32(1), employerRequests.Load())
+	cache.AssertExpectations(t)
+}
+
+func Test_GetVacancy_WhenEmployerUnavailable_ShouldKeepEmployerFromVacancy(t *testing.T) {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies/1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": "1", "name": "Go developer", "published_at": "2024-10-11T10:00:00+0300",
+			"employer": {"id": "370421", "name": "Роболайн"}}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := hh.NewClient()
+	client.SetBaseURL(server.URL)
+
+	cache := &mockEmployerCache{}
+	cache.On("Get", mock.Anything, "370421").Return((*models.Employer)(nil), nil)
+
+	retriever := NewHHVacanciesRetriever(client)
+	retriever.WithEmployerCache(cache, time.Hour)
+
+	vacancy, err := retriever.GetVacancy(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, models.Employer{ID: "370421", Name: "Роболайн"}, vacancy.Employer)
+}
+
+type vacanciesStandIn struct {
+	searches    atomic.Int32
+	requests    atomic.Int32
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (s *vacanciesStandIn) start(t *testing.T, ids []string) *hh.Client {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies", func(w http.ResponseWriter, r *http.Request) {
+		s.searches.Add(1)
+		items := lo.Map(ids, func(id string, _ int) string { return `{"id": "` + id + `"}` })
+		_, _ = fmt.Fprintf(w, `{"items": [%s], "found": %d}`, strings.Join(items, ","), len(ids))
+	})
+	mux.HandleFunc("GET /vacancies/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		inFlight := s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		for maxInFlight := s.maxInFlight.Load(); inFlight > maxInFlight; maxInFlight = s.maxInFlight.Load() {
+			if s.maxInFlight.CompareAndSwap(maxInFlight, inFlight) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		switch id := r.PathValue("id"); id {
+		case "removed":
+			w.WriteHeader(http.StatusNotFound)
+		case "broken":
+			w.WriteHeader(http.StatusBadRequest)
+		default:
+			_, _ = fmt.Fprintf(w, `{"id": "%s", "name": "Go developer", "published_at": "2024-10-11T10:00:00+0300"}`, id)
+		}
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := hh.NewClient()
+	client.SetBaseURL(server.URL)
+	return client
+}
+
+func Test_GetVacancies_WhenSomeDetailsFailed_ShouldReturnPartialResult(t *testing.T) {
+
+	assert := assert.New(t)
+
+	standIn := &vacanciesStandIn{}
+	retriever := NewHHVacanciesRetriever(standIn.start(t, []string{"1", "removed", "2", "broken", "3"}))
+	retriever.WithDetailsConcurrency(3)
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	page, err := retriever.GetVacancies(context.Background(), search, SearchWindow{}, 0, 20)
+	assert.NoError(err)
+	assert.Equal([]string{"1", "2", "3"}, lo.Map(page.Vacancies, func(v models.Vacancy, _ int) string { return v.ID }))
+	assert.Len(page.Failed, 1)
+	assert.Equal("broken", page.Failed[0].VacancyID)
+	assert.ErrorIs(page.Failed[0], hh.ErrBadRequest)
+	assert.Equal(5, page.Found)
+	assert.LessOrEqual(standIn.maxInFlight.Load(), int32(3))
+}
+
+func Test_GetVacancies_WhenAllDetailsFailed_ShouldReturnError(t *testing.T) {
+
+	standIn := &vacanciesStandIn{}
+	retriever := NewHHVacanciesRetriever(standIn.start(t, []string{"broken", "broken"}))
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	_, err := retriever.GetVacancies(context.Background(), search, SearchWindow{}, 0, 20)
+	assert.ErrorIs(t, err, hh.ErrBadRequest)
+}
+
+func Test_GetVacancies_WithDetailsCache_ShouldFetchVacancyOnce(t *testing.T) {
+
+	assert := assert.New(t)
+
+	standIn := &vacanciesStandIn{}
+	retriever := NewHHVacanciesRetriever(standIn.start(t, []string{"1", "2"}))
+	retriever.WithDetailsConcurrency(2)
+	retriever.WithDetailsCache(time.Minute)
+
+	for _, text := range []string{"golang", "go developer"} {
+		search := models.NewJobSearch(0, text, "", models.NoExperience, nil, "", 1)
+		page, err := retriever.GetVacancies(context.Background(), search, SearchWindow{}, 0, 20)
+		assert.NoError(err)
+		assert.Empty(page.Failed)
+		assert.Len(page.Vacancies, 2)
+	}
+	assert.Equal(int32(2), standIn.requests.Load())
+}
+
+func Test_SplitSearch_WhenTooManyVacancies_ShouldSplitIntoWindowsFittingLimit(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dateTo := time.Now().Truncate(time.Second)
+	dateFrom := dateTo.Add(-100 * time.Hour)
+
+	//5000 vacancies, one per 72 seconds
+	var published []time.Time
+	for i := 0; i < 5000; i++ {
+		published = append(published, dateFrom.Add(time.Duration(i)*72*time.Second))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies", func(w http.ResponseWriter, r *http.Request) {
+		from, _ := time.Parse("2006-01-02T15:04:05-0700", r.URL.Query().Get("date_from"))
+		to, _ := time.Parse("2006-01-02T15:04:05-0700", r.URL.Query().Get("date_to"))
+		found := lo.CountBy(published, func(p time.Time) bool { return !p.Before(from) && !p.After(to) })
+		_, _ = fmt.Fprintf(w, `{"items": [], "found": %d}`, found)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := hh.NewClient()
+	client.SetBaseURL(server.URL)
+	retriever := NewHHVacanciesRetriever(client)
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	windows, err := retriever.SplitSearch(context.Background(), search, dateFrom)
+	assert.NoError(err)
+	assert.Greater(len(windows), 2)
+
+	total := 0
+	for i, window := range windows {
+		assert.LessOrEqual(window.Found, hh.MaxSearchResults)
+		total += window.Found
+		if i > 0 {
+			assert.Equal(windows[i-1].DateFrom.Add(-time.Second), window.DateTo, "windows should be adjacent")
+		}
+	}
+	assert.Equal(5000, total)
+	assert.True(windows[len(windows)-1].DateFrom.Equal(dateFrom))
+	assert.False(windows[0].DateTo.Before(dateTo))
+}
+
+func Test_SplitSearch_WhenVacanciesFitLimit_ShouldReturnOneWindow(t *testing.T) {
+
+	standIn := &vacanciesStandIn{}
+	retriever := NewHHVacanciesRetriever(standIn.start(t, []string{"1", "2"}))
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	windows, err := retriever.SplitSearch(context.Background(), search, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, windows, 1)
+	assert.Equal(t, 2, windows[0].Found)
+}
+
+func Test_SplitSearch_WhenPreviousRunFoundFewVacancies_ShouldSkipCheck(t *testing.T) {
+
+	assert := assert.New(t)
+
+	standIn := &vacanciesStandIn{}
+	retriever := NewHHVacanciesRetriever(standIn.start(t, []string{"1", "2"}))
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	search.ID = 7
+	windows, err := retriever.SplitSearch(context.Background(), search, time.Now().Add(-time.Hour))
+	assert.NoError(err)
+	assert.Equal(2, windows[0].Found)
+	assert.Equal(int32(1), standIn.searches.Load())
+
+	windows, err = retriever.SplitSearch(context.Background(), search, time.Now().Add(-time.Hour))
+	assert.NoError(err)
+	assert.Len(windows, 1)
+	assert.Equal(unknownFound, windows[0].Found)
+	assert.Equal(int32(1), standIn.searches.Load())
+
+	page, err := retriever.GetVacancies(context.Background(), search, windows[0], 0, 20)
+	assert.NoError(err)
+	assert.Equal(2, page.Found)
+
+	//first check of a search or one since its creation is always done
+	_, err = retriever.SplitSearch(context.Background(), search, time.Time{})
+	assert.NoError(err)
+	assert.Equal(int32(3), standIn.searches.Load())
+}
+
+func Test_SplitSearch_WhenMinimalWindowExceedsLimit_ShouldStopSplitting(t *testing.T) {
+
+	assert := assert.New(t)
+
+	dateTo := time.Now().Truncate(time.Second)
+	dateFrom := dateTo.Add(-10 * time.Hour)
+
+	//2500 vacancies published during last 30 minutes
+	var published []time.Time
+	for i := 0; i < 2500; i++ {
+		published = append(published, dateTo.Add(-time.Duration(i)*720*time.Millisecond))
+	}
+
+	var searches atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /vacancies", func(w http.ResponseWriter, r *http.Request) {
+		searches.Add(1)
+		from, _ := time.Parse("2006-01-02T15:04:05-0700", r.URL.Query().Get("date_from"))
+		to, _ := time.Parse("2006-01-02T15:04:05-0700", r.URL.Query().Get("date_to"))
+		found := lo.CountBy(published, func(p time.Time) bool { return !p.Before(from) && !p.After(to) })
+		_, _ = fmt.Fprintf(w, `{"items": [], "found": %d}`, found)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := hh.NewClient()
+	client.SetBaseURL(server.URL)
+	retriever := NewHHVacanciesRetriever(client)
+
+	search := models.NewJobSearch(0, "golang", "", models.NoExperience, nil, "", 1)
+	windows, err := retriever.SplitSearch(context.Background(), search, dateFrom)
+	assert.NoError(err)
+	assert.Equal(2500, windows[0].Found)
+	assert.LessOrEqual(windows[0].DateTo.Sub(windows[0].DateFrom), minSearchWindow)
+	assert.LessOrEqual(searches.Load(), int32(12))
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"os"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+const promptVersionTemplate = "version"
+
+var promptFuncs = template.FuncMap{
+	"join": strings.Join,
+}
+
+type PromptTemplate struct {
+	version  string
+	template *template.Template
+}
+
+type PromptTemplates struct {
+	Match      *PromptTemplate
+	BatchMatch *PromptTemplate
+}
+
+// MatchPrompt is a part of match prompt which doesn't depend on vacancy, Version identifies cached verdicts
+type MatchPrompt struct {
+	Version      string
+	Examples     []models.VacancyFeedback
+	feedbackHash string
+}
+
+// withFeedback adds examples to template version, verdicts made with different examples are made by different prompts
+func (p MatchPrompt) withFeedback(version string) string {
+	if p.feedbackHash == "" {
+		return version
+	}
+	return version + "+feedback:" + p.feedbackHash
+}
+
+type matchPromptData struct {
+	Search   models.JobSearch
+	Vacancy  models.Vacancy
+	Examples []models.VacancyFeedback
+}
+
+type batchMatchPromptData struct {
+	Search    models.JobSearch
+	Vacancies []models.Vacancy
+	Examples  []models.VacancyFeedback
+}
+
+var (
+	matchPromptFields      = []string{".Search.UserWish", ".Vacancy.Name", ".Vacancy.Description"}
+	batchMatchPromptFields = []string{".Search.UserWish", ".Vacancies", ".ID", ".Name", ".Description"}
+)
+
+func LoadPromptTemplates(matchPath, batchMatchPath string) (PromptTemplates, error) {
+
+	match, err := LoadPromptTemplate(matchPath, matchPromptFields)
+	if err != nil {
+		return PromptTemplates{}, err
+	}
+
+	batchMatch, err := LoadPromptTemplate(batchMatchPath, batchMatchPromptFields)
+	if err != nil {
+		return PromptTemplates{}, err
+	}
+
+	return PromptTemplates{Match: match, BatchMatch: batchMatch}, nil
+}
+
+func LoadPromptTemplate(path string, requiredFields []string) (*PromptTemplate, error) {
+
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't read prompt template: %w", err)
+	}
+
+	prompt, err := NewPromptTemplate(string(text), requiredFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	return prompt, nil
+}
+
+func NewPromptTemplate(text string, requiredFields []string) (*PromptTemplate, error) {
+
+	tmpl, err := template.New("prompt").Funcs(promptFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	versionTmpl := tmpl.Lookup(promptVersionTemplate)
+	if versionTmpl == nil {
+		return nil, fmt.Errorf("template %q is not defined", promptVersionTemplate)
+	}
+
+	var version bytes.Buffer
+	if err = versionTmpl.Execute(&version, nil); err != nil {
+		return nil, fmt.Errorf("can't execute %q template: %w", promptVersionTemplate, err)
+	}
+	if strings.TrimSpace(version.String()) == "" {
+		return nil, errors.New("prompt version is empty")
+	}
+
+	fields := make(map[string]struct{})
+	collectFields(tmpl.Tree.Root, fields)
+	for _, field := range requiredFields {
+		if _, ok := fields[field]; !ok {
+			return nil, fmt.Errorf("required placeholder {{%s}} is missing", field)
+		}
+	}
+
+	return &PromptTemplate{version: strings.TrimSpace(version.String()), template: tmpl}, nil
+}
+
+func (p *PromptTemplate) Version() string {
+	return p.version
+}
+
+func (p *PromptTemplate) Execute(data any) (string, error) {
+	var result bytes.Buffer
+	if err := p.template.Execute(&result, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(result.String()), nil
+}
+
+func collectFields(node parse.Node, fields map[string]struct{}) {
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, fields)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, fields)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectFields(arg, fields)
+			}
+		}
+	case *parse.FieldNode:
+		fields["."+strings.Join(n.Ident, ".")] = struct{}{}
+	case *parse.IfNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.RangeNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.WithNode:
+		collectBranchFields(&n.BranchNode, fields)
+	case *parse.TemplateNode:
+		collectFields(n.Pipe, fields)
+	}
+}
+
+func collectBranchFields(node *parse.BranchNode, fields map[string]struct{}) {
+	collectFields(node.Pipe, fields)
+	collectFields(node.List, fields)
+	collectFields(node.ElseList, fields)
+}
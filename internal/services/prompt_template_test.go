@@ -0,0 +1,94 @@
+package services
+
+import (
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func testPrompts(t *testing.T) PromptTemplates {
+	prompts, err := LoadPromptTemplates("../../configs/prompts/match.tmpl", "../../configs/prompts/batch_match.tmpl")
+	if err != nil {
+		t.Fatalf("can't load prompts: %v", err)
+	}
+	return prompts
+}
+
+func Test_PromptTemplates_ShouldRenderVacancyAndWish(t *testing.T) {
+
+	assert := assert.New(t)
+	prompts := testPrompts(t)
+
+	search := models.JobSearch{UserWish: "удалёнка"}
+	vacancy := models.Vacancy{ID: "42", Name: "Go разработчик", Description: "пишем на Go", KeySkills: []string{"Go", "SQL"}}
+
+	prompt, err := prompts.Match.Execute(matchPromptData{Search: search, Vacancy: vacancy})
+	assert.NoError(err)
+	assert.Contains(prompt, "Go разработчик")
+	assert.Contains(prompt, "пишем на Go")
+	assert.Contains(prompt, "Go, SQL")
+	assert.Contains(prompt, "удалёнка")
+	assert.NotEmpty(prompts.Match.Version())
+
+	prompt, err = prompts.BatchMatch.Execute(batchMatchPromptData{Search: search, Vacancies: []models.Vacancy{vacancy}})
+	assert.NoError(err)
+	assert.Contains(prompt, "ID вакансии: 42")
+	assert.Contains(prompt, "удалёнка")
+	assert.NotEmpty(prompts.BatchMatch.Version())
+}
+
+func Test_PromptTemplates_ShouldRenderVacancyDetails(t *testing.T) {
+
+	assert := assert.New(t)
+	prompts := testPrompts(t)
+
+	vacancy := models.Vacancy{
+		ID:                "42",
+		Name:              "Go разработчик",
+		Employer:          models.Employer{Name: "Роболайн", Trusted: true},
+		Area:              models.DictionaryItem{ID: "1", Name: "Москва"},
+		Salary:            &models.Salary{From: 80000, Currency: models.CurrencyRUR, Gross: true},
+		Schedule:          models.DictionaryItem{ID: "remote", Name: "Удаленная работа"},
+		ProfessionalRoles: []models.DictionaryItem{{ID: "96", Name: "Программист, разработчик"}},
+		Languages:         []models.Language{{Name: "Английский", Level: "B1 — Средний"}},
+	}
+	expected := []string{
+		"Работодатель: Роболайн (проверен hh.ru)",
+		"Регион: Москва",
+		"Зарплата: от 80000 RUR до вычета налогов",
+		"График: Удаленная работа",
+		"Профессиональные роли: Программист, разработчик",
+		"Языки: Английский (B1 — Средний)",
+	}
+
+	prompt, err := prompts.Match.Execute(matchPromptData{Vacancy: vacancy})
+	assert.NoError(err)
+	for _, line := range expected {
+		assert.Contains(prompt, line)
+	}
+	assert.NotContains(prompt, "Занятость")
+
+	prompt, err = prompts.BatchMatch.Execute(batchMatchPromptData{Vacancies: []models.Vacancy{vacancy}})
+	assert.NoError(err)
+	for _, line := range expected {
+		assert.Contains(prompt, line)
+	}
+}
+
+func Test_NewPromptTemplate_WhenVersionIsMissing_ShouldReturnError(t *testing.T) {
+	_, err := NewPromptTemplate("{{.Search.UserWish}}", []string{".Search.UserWish"})
+	assert.Error(t, err)
+}
+
+func Test_NewPromptTemplate_WhenRequiredPlaceholderIsMissing_ShouldReturnError(t *testing.T) {
+	_, err := NewPromptTemplate(`{{define "version"}}v1{{end}}{{.Vacancy.Description}}`, matchPromptFields)
+	assert.Error(t, err)
+}
+
+func Test_NewPromptTemplate_WhenPlaceholderIsNested_ShouldFindIt(t *testing.T) {
+	text := `{{define "version"}}v1{{end}}{{if .Search.UserWish}}{{.Search.UserWish}}{{end}}` +
+		`{{with .Vacancy}}{{.Name}}{{end}}{{.Vacancy.Name}} {{.Vacancy.Description}}`
+	prompt, err := NewPromptTemplate(text, matchPromptFields)
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", prompt.Version())
+}
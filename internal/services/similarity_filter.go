@@ -0,0 +1,206 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/logger"
+	"github.com/maxaizer/hh-parser/internal/metrics"
+	log "github.com/sirupsen/logrus"
+	"slices"
+	"strings"
+)
+
+const maxEmbeddingTextLength = 4000
+
+type embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	EmbeddingModel() string
+}
+
+type embeddingRepository interface {
+	Get(ctx context.Context, kind models.EmbeddingKind, model string, textHashes []string) (map[string][]float32, error)
+	Save(ctx context.Context, embeddings []models.Embedding) error
+	GetRejections(ctx context.Context, model string, wishHash string, textHashes []string) (map[string]float64, error)
+	SaveRejections(ctx context.Context, rejections []models.SimilarityRejection) error
+}
+
+type SimilarityFilter struct {
+	embedder         embedder
+	embeddings       embeddingRepository
+	defaultThreshold float64
+}
+
+func NewSimilarityFilter(embedder embedder, embeddings embeddingRepository, defaultThreshold float64) *SimilarityFilter {
+	return &SimilarityFilter{embedder: embedder, embeddings: embeddings, defaultThreshold: defaultThreshold}
+}
+
+func (f *SimilarityFilter) Threshold(search models.JobSearch) float64 {
+	if search.SimilarityThreshold != nil {
+		return *search.SimilarityThreshold
+	}
+	return f.defaultThreshold
+}
+
+// Filter returns vacancies whose description is close enough to the search wish. If embeddings can't be
+// computed, all vacancies are returned and the generative check decides.
+func (f *SimilarityFilter) Filter(ctx context.Context, search models.JobSearch, vacancies []models.Vacancy) []models.Vacancy {
+
+	threshold := f.Threshold(search)
+	if threshold <= 0 || len(vacancies) == 0 {
+		return vacancies
+	}
+
+	similarities, err := f.getSimilarities(ctx, search, vacancies, threshold)
+	if err != nil {
+		log.Warnf("can't compare vacancies with wish of search %v, skipping similarity check: %v", search.ID, err)
+		return vacancies
+	}
+
+	passed := make([]models.Vacancy, 0, len(vacancies))
+	for i, vacancy := range vacancies {
+		if similarities[i] < threshold {
+			log.Debugf("vacancy %v rejected by similarity %.3f < %.3f for search %v",
+				vacancy.ID, similarities[i], threshold, search.ID)
+			metrics.RejectedBySimilarityVacanciesCounter.Inc()
+			continue
+		}
+		passed = append(passed, vacancy)
+	}
+	return passed
+}
+
+// getSimilarities returns similarity of each vacancy to the search wish. Vacancies rejected before are taken
+// from the repository without computing embeddings, new rejections are saved there.
+func (f *SimilarityFilter) getSimilarities(ctx context.Context, search models.JobSearch, vacancies []models.Vacancy,
+	threshold float64) ([]float64, error) {
+
+	model := f.embedder.EmbeddingModel()
+	wishHash := models.EmbeddingTextHash(search.UserWish)
+
+	texts := make([]string, 0, len(vacancies))
+	hashes := make([]string, 0, len(vacancies))
+	for _, vacancy := range vacancies {
+		text := vacancyEmbeddingText(vacancy)
+		texts = append(texts, text)
+		hashes = append(hashes, models.EmbeddingTextHash(text))
+	}
+
+	rejected, err := f.embeddings.GetRejections(ctx, model, wishHash, hashes)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get similarity rejections: %v", err)
+		rejected = map[string]float64{}
+	}
+
+	similarities := make([]float64, len(vacancies))
+	var unknownTexts []string
+	var unknown []int
+	for i, hash := range hashes {
+		if similarity, ok := rejected[hash]; ok {
+			similarities[i] = similarity
+			continue
+		}
+		unknownTexts = append(unknownTexts, texts[i])
+		unknown = append(unknown, i)
+	}
+
+	if len(unknown) == 0 {
+		return similarities, nil
+	}
+
+	wish, err := f.getEmbeddings(ctx, models.EmbeddingKindWish, []string{search.UserWish})
+	if err != nil {
+		return nil, fmt.Errorf("can't get wish embedding: %w", err)
+	}
+
+	embeddings, err := f.getEmbeddings(ctx, models.EmbeddingKindVacancy, unknownTexts)
+	if err != nil {
+		return nil, fmt.Errorf("can't get vacancy embeddings: %w", err)
+	}
+
+	var rejections []models.SimilarityRejection
+	for j, i := range unknown {
+		similarities[i] = ai.CosineSimilarity(wish[0], embeddings[j])
+		if similarities[i] < threshold {
+			rejections = append(rejections, models.SimilarityRejection{
+				WishHash:   wishHash,
+				TextHash:   hashes[i],
+				Model:      model,
+				VacancyID:  vacancies[i].ID,
+				Similarity: similarities[i],
+				Threshold:  threshold,
+			})
+		}
+	}
+
+	if err = f.embeddings.SaveRejections(ctx, rejections); err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to save similarity rejections: %v", err)
+	}
+	return similarities, nil
+}
+
+func (f *SimilarityFilter) getEmbeddings(ctx context.Context, kind models.EmbeddingKind, texts []string) ([][]float32, error) {
+
+	model := f.embedder.EmbeddingModel()
+
+	hashes := make([]string, 0, len(texts))
+	for _, text := range texts {
+		hashes = append(hashes, models.EmbeddingTextHash(text))
+	}
+
+	stored, err := f.embeddings.Get(ctx, kind, model, hashes)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get stored embeddings: %v", err)
+		stored = map[string][]float32{}
+	}
+
+	var missingTexts []string
+	var missingHashes []string
+	for i, hash := range hashes {
+		if _, ok := stored[hash]; !ok && !slices.Contains(missingHashes, hash) {
+			missingTexts = append(missingTexts, texts[i])
+			missingHashes = append(missingHashes, hash)
+		}
+	}
+
+	if len(missingTexts) > 0 {
+		vectors, err := f.embedder.Embed(ctx, missingTexts)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != len(missingTexts) {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(vectors), len(missingTexts))
+		}
+
+		computed := make([]models.Embedding, 0, len(vectors))
+		for i, vector := range vectors {
+			stored[missingHashes[i]] = vector
+			computed = append(computed, models.Embedding{Kind: kind, TextHash: missingHashes[i], Model: model, Vector: vector})
+		}
+
+		if err = f.embeddings.Save(ctx, computed); err != nil {
+			log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to save embeddings: %v", err)
+		}
+	}
+
+	result := make([][]float32, 0, len(hashes))
+	for _, hash := range hashes {
+		result = append(result, stored[hash])
+	}
+	return result, nil
+}
+
+func vacancyEmbeddingText(vacancy models.Vacancy) string {
+
+	text := vacancy.Name
+	if len(vacancy.KeySkills) > 0 {
+		text += "\n" + strings.Join(vacancy.KeySkills, ", ")
+	}
+	text += "\n" + vacancy.Description
+
+	if runes := []rune(text); len(runes) > maxEmbeddingTextLength {
+		text = string(runes[:maxEmbeddingTextLength])
+	}
+	return text
+}
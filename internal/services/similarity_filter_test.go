@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"github.com/asaskevich/EventBus"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubEmbedder struct {
+	vectors map[string][]float32
+	err     error
+	texts   []string
+}
+
+func (s *stubEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.texts = append(s.texts, texts...)
+
+	result := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vector := []float32{0, 1}
+		for prefix, v := range s.vectors {
+			if strings.HasPrefix(text, prefix) {
+				vector = v
+			}
+		}
+		result = append(result, vector)
+	}
+	return result, nil
+}
+
+func (s *stubEmbedder) EmbeddingModel() string {
+	return "embedding-model"
+}
+
+type memoryEmbeddings struct {
+	stored     map[string][]float32
+	rejections map[string]float64
+	gets       int
+}
+
+func (m *memoryEmbeddings) Get(_ context.Context, kind models.EmbeddingKind, model string,
+	textHashes []string) (map[string][]float32, error) {
+
+	m.gets++
+	result := make(map[string][]float32)
+	for _, hash := range textHashes {
+		if vector, ok := m.stored[string(kind)+model+hash]; ok {
+			result[hash] = vector
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryEmbeddings) Save(_ context.Context, embeddings []models.Embedding) error {
+	for _, embedding := range embeddings {
+		m.stored[string(embedding.Kind)+embedding.Model+embedding.TextHash] = embedding.Vector
+	}
+	return nil
+}
+
+func (m *memoryEmbeddings) GetRejections(_ context.Context, model string, wishHash string,
+	textHashes []string) (map[string]float64, error) {
+
+	result := make(map[string]float64)
+	for _, hash := range textHashes {
+		if similarity, ok := m.rejections[model+wishHash+hash]; ok {
+			result[hash] = similarity
+		}
+	}
+	return result, nil
+}
+
+func (m *memoryEmbeddings) SaveRejections(_ context.Context, rejections []models.SimilarityRejection) error {
+	if m.rejections == nil {
+		m.rejections = make(map[string]float64)
+	}
+	for _, rejection := range rejections {
+		m.rejections[rejection.Model+rejection.WishHash+rejection.TextHash] = rejection.Similarity
+	}
+	return nil
+}
+
+func Test_SimilarityFilter_ShouldRejectDissimilarAndReuseStoredEmbeddings(t *testing.T) {
+
+	assert := assert.New(t)
+
+	embedder := &stubEmbedder{vectors: map[string][]float32{
+		"golang":    {1, 0},
+		"Go":        {0.9, 0.1},
+		"Бухгалтер": {0, 1},
+	}}
+	filter := NewSimilarityFilter(embedder, &memoryEmbeddings{stored: map[string][]float32{}}, 0.5)
+
+	search := models.JobSearch{ID: 1, UserWish: "golang backend"}
+	vacancies := []models.Vacancy{{ID: "1", Name: "Go developer"}, {ID: "2", Name: "Бухгалтер"}}
+
+	passed := filter.Filter(context.Background(), search, vacancies)
+	assert.Equal([]models.Vacancy{vacancies[0]}, passed)
+	assert.Len(embedder.texts, 3)
+
+	passed = filter.Filter(context.Background(), search, vacancies)
+	assert.Equal([]models.Vacancy{vacancies[0]}, passed)
+	assert.Len(embedder.texts, 3, "embeddings should be taken from the repository")
+}
+
+func Test_SimilarityFilter_ShouldNotCompareRejectedAgain(t *testing.T) {
+
+	assert := assert.New(t)
+
+	embedder := &stubEmbedder{vectors: map[string][]float32{"golang": {1, 0}, "Бухгалтер": {0.3, 1}}}
+	embeddings := &memoryEmbeddings{stored: map[string][]float32{}}
+	filter := NewSimilarityFilter(embedder, embeddings, 0.5)
+
+	search := models.JobSearch{ID: 1, UserWish: "golang"}
+	vacancies := []models.Vacancy{{ID: "1", Name: "Бухгалтер"}}
+
+	assert.Empty(filter.Filter(context.Background(), search, vacancies))
+	assert.Len(embeddings.rejections, 1)
+	gets := embeddings.gets
+
+	assert.Empty(filter.Filter(context.Background(), search, vacancies))
+	assert.Equal(gets, embeddings.gets, "rejected vacancy shouldn't be compared again")
+
+	lower := 0.2
+	search.SimilarityThreshold = &lower
+	assert.Equal(vacancies, filter.Filter(context.Background(), search, vacancies),
+		"stored similarity should be checked against current threshold")
+	assert.Equal(gets, embeddings.gets)
+}
+
+func Test_SimilarityFilter_ShouldUseSearchThreshold(t *testing.T) {
+
+	embedder := &stubEmbedder{vectors: map[string][]float32{"golang": {1, 0}}}
+	filter := NewSimilarityFilter(embedder, &memoryEmbeddings{stored: map[string][]float32{}}, 0.5)
+
+	disabled := 0.0
+	search := models.JobSearch{ID: 1, UserWish: "golang", SimilarityThreshold: &disabled}
+	vacancies := []models.Vacancy{{ID: "1", Name: "Бухгалтер"}}
+
+	assert.Equal(t, vacancies, filter.Filter(context.Background(), search, vacancies))
+	assert.Empty(t, embedder.texts)
+}
+
+func Test_SimilarityFilter_WhenEmbeddingFails_ShouldPassAll(t *testing.T) {
+
+	filter := NewSimilarityFilter(&stubEmbedder{err: errors.New("quota")},
+		&memoryEmbeddings{stored: map[string][]float32{}}, 0.5)
+
+	vacancies := []models.Vacancy{{ID: "1", Name: "Бухгалтер"}}
+	assert.Equal(t, vacancies, filter.Filter(context.Background(), models.JobSearch{UserWish: "golang"}, vacancies))
+}
+
+func Test_AnalyzeVacancy_WhenRejectedBySimilarity_ShouldNotCallAI(t *testing.T) {
+
+	aiClient := mockAiClient{}
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{},
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(t, err)
+
+	embedder := &stubEmbedder{vectors: map[string][]float32{"golang": {1, 0}}}
+	analyzer.WithSimilarityFilter(NewSimilarityFilter(embedder, &memoryEmbeddings{stored: map[string][]float32{}}, 0.5))
+
+	err = analyzer.analyzeVacancyWithAI(context.Background(), models.Vacancy{ID: "1", Name: "Бухгалтер"},
+		models.JobSearch{ID: 1, UserWish: "golang"})
+	assert.NoError(t, err)
+	aiClient.AssertNotCalled(t, "GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything)
+}
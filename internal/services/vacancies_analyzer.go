@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"errors"
 	"github.com/asaskevich/EventBus"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
 	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
 	events2 "github.com/maxaizer/hh-parser/internal/domain/events"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
@@ -12,17 +13,46 @@ import (
 	"github.com/maxaizer/hh-parser/internal/metrics"
 	log "github.com/sirupsen/logrus"
 	"regexp"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 )
 
+// feedbackDescriptionLength limits description of sent vacancy stored for feedback examples in prompts
+const feedbackDescriptionLength = 500
+
 type vacanciesAIService interface {
-	DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, vacancy models.Vacancy) (bool, error)
+	DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, prompt MatchPrompt,
+		vacancy models.Vacancy) (models.MatchVerdict, error)
+	DoVacanciesMatchSearch(ctx context.Context, search models.JobSearch, prompt MatchPrompt,
+		vacancies []models.Vacancy) (map[string]models.MatchVerdict, map[string]error)
+	Model() string
+	MatchPrompt(ctx context.Context, search models.JobSearch) MatchPrompt
+}
+
+type verdictCache interface {
+	Get(ctx context.Context, key models.VerdictCacheKey) (*models.MatchVerdict, error)
+	Save(ctx context.Context, key models.VerdictCacheKey, verdict models.MatchVerdict, ttl time.Duration) error
+}
+
+type similarityFilter interface {
+	Filter(ctx context.Context, search models.JobSearch, vacancies []models.Vacancy) []models.Vacancy
+}
+
+type userUsageRepository interface {
+	GetUserTokens(ctx context.Context, userID int64, day string) (int64, error)
+}
+
+type employerListRepository interface {
+	GetByUser(ctx context.Context, userID int64) (models.EmployerLists, error)
 }
 
 type vacanciesRetriever interface {
-	GetVacancies(search *models.JobSearch, dateFrom time.Time, page, pageSize int) ([]models.Vacancy, error)
-	GetVacancy(ID string) (*models.Vacancy, error)
+	SplitSearch(ctx context.Context, search *models.JobSearch, dateFrom time.Time) ([]SearchWindow, error)
+	GetVacancies(ctx context.Context, search *models.JobSearch, window SearchWindow, page, pageSize int) (WindowPage,
+		error)
+	GetVacancy(ctx context.Context, ID string) (*models.Vacancy, error)
 }
 
 type searchRepository interface {
@@ -33,8 +63,10 @@ type searchRepository interface {
 
 type vacancyRepository interface {
 	IsSentToUser(ctx context.Context, vacancy models.NotifiedVacancyID) (bool, error)
-	RecordAsSentToUser(ctx context.Context, vacancy models.NotifiedVacancyID) error
+	RecordAsSentToUser(ctx context.Context, vacancyID models.NotifiedVacancyID, vacancy models.Vacancy,
+		verdict models.MatchVerdict) error
 	AddFailedToAnalyze(ctx context.Context, searchID int, vacancyID string, error string) error
+	ParkForAnalysis(ctx context.Context, searchID int, vacancyID string, reason string, until time.Time) error
 	RemoveFailedToAnalyze(ctx context.Context, maxAttempts int, minUpdateTime time.Time) (int64, error)
 	GetFailedToAnalyze(ctx context.Context) ([]models.FailedVacancy, error)
 }
@@ -59,6 +91,14 @@ type VacanciesAnalyzer struct {
 	lastAnalysisTime         time.Time
 	analysisInterval         time.Duration
 	searchContexts           sync.Map
+	batchSize                int
+	batchMaxWait             time.Duration
+	verdictCache             verdictCache
+	verdictCacheTTL          time.Duration
+	usage                    userUsageRepository
+	userDailyTokenLimit      int64
+	similarity               similarityFilter
+	employerLists            employerListRepository
 	analysisCompleteCallback func()
 }
 
@@ -72,6 +112,8 @@ func NewVacanciesAnalyzer(bus EventBus.Bus, aiService vacanciesAIService, vacanc
 		retriever:        vacanciesRetriever,
 		aiService:        aiService,
 		analysisInterval: analysisInterval,
+		batchSize:        1,
+		batchMaxWait:     time.Second,
 	}
 
 	err := bus.Subscribe(events2.SearchDeletedTopic, func(event events2.SearchDeleted) {
@@ -95,6 +137,32 @@ func (v *VacanciesAnalyzer) WithAnalysisCompleteCallback(f func()) {
 	v.analysisCompleteCallback = f
 }
 
+func (v *VacanciesAnalyzer) WithBatching(size int, maxWait time.Duration) {
+	if size < 1 {
+		size = 1
+	}
+	v.batchSize = size
+	v.batchMaxWait = maxWait
+}
+
+func (v *VacanciesAnalyzer) WithVerdictCache(cache verdictCache, ttl time.Duration) {
+	v.verdictCache = cache
+	v.verdictCacheTTL = ttl
+}
+
+func (v *VacanciesAnalyzer) WithUserDailyTokenLimit(usage userUsageRepository, limit int64) {
+	v.usage = usage
+	v.userDailyTokenLimit = limit
+}
+
+func (v *VacanciesAnalyzer) WithSimilarityFilter(filter similarityFilter) {
+	v.similarity = filter
+}
+
+func (v *VacanciesAnalyzer) WithEmployerLists(lists employerListRepository) {
+	v.employerLists = lists
+}
+
 func (v *VacanciesAnalyzer) Run() {
 	for {
 		startTime := time.Now()
@@ -227,7 +295,7 @@ func (v *VacanciesAnalyzer) rerunAnalysisForFailedVacancies() {
 			searches[vacancyInfo.SearchID] = search
 		}
 
-		vacancy, err := v.retriever.GetVacancy(vacancyInfo.VacancyID)
+		vacancy, err := v.retriever.GetVacancy(context.Background(), vacancyInfo.VacancyID)
 		if err != nil {
 			log.Errorf("failed to get vacancy by id: %v", err)
 			continue
@@ -274,32 +342,66 @@ func (v *VacanciesAnalyzer) analyzeVacanciesForSearch(ctx context.Context, errCh
 		defer wg.Done()
 		v.analyzeVacancies(ctx, requestChan, errChan)
 	}()
+	//workers may still report errors, so they are waited on every exit before errChan is closed by caller
+	defer func() {
+		close(requestChan)
+		wg.Wait()
+	}()
 
-	for page := 0; ; page++ {
+	windows, err := v.retriever.SplitSearch(ctx, &search, dateFrom)
+	if errors.Is(err, context.Canceled) {
+		log.Infof("analysis canceled for search ID %v", search.ID)
+		return
+	}
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeHhApi).Errorf("failed to split search: %v", err)
+		return
+	}
 
-		select {
-		case <-ctx.Done():
-			log.Infof("analysis canceled for search ID %v", search.ID)
-			return
-		default:
-		}
+	for _, window := range windows {
+		for page := 0; page < windowPages(window, pageSize); page++ {
 
-		vacancies, err := v.retriever.GetVacancies(&search, dateFrom, page, pageSize)
-		if err != nil {
-			log.WithField(logger.ErrorTypeField, logger.ErrorTypeHhApi).Errorf("failed to get vacancies previews: %v", err)
-			return //to not update last checked vacancy
-		}
+			select {
+			case <-ctx.Done():
+				log.Infof("analysis canceled for search ID %v", search.ID)
+				return
+			default:
+			}
 
-		if len(vacancies) == 0 {
-			break
-		}
+			result, err := v.retriever.GetVacancies(ctx, &search, window, page, pageSize)
+			if errors.Is(err, context.Canceled) {
+				log.Infof("analysis canceled for search ID %v", search.ID)
+				return
+			}
+			if err != nil {
+				log.WithField(logger.ErrorTypeField, logger.ErrorTypeHhApi).Errorf("failed to get vacancies previews: %v", err)
+				return //to not update last checked vacancy
+			}
 
-		for i := 0; i < len(vacancies); i++ {
-			requestChan <- analysisRequest{search: &search, vacancy: &vacancies[i]}
-		}
+			//size of window which wasn't checked by split is known from its first page
+			window.Found = result.Found
+			vacancies := result.Vacancies
+
+			//failed vacancies are retried with other failed to analyze ones
+			for _, vacancyErr := range result.Failed {
+				errChan <- analysisError{vacancyID: vacancyErr.VacancyID, searchID: search.ID, error: vacancyErr}
+			}
 
-		if latestVacancy == nil {
-			latestVacancy = &vacancies[0]
+			if len(vacancies) == 0 {
+				//page may be empty when all its vacancies were removed, the next ones still have to be checked
+				log.Infof("no vacancies on page %d of window %v - %v for search with id %d", page,
+					window.DateFrom, window.DateTo, search.ID)
+				continue
+			}
+
+			fetchedTotal += len(vacancies)
+			for i := 0; i < len(vacancies); i++ {
+				requestChan <- analysisRequest{search: &search, vacancy: &vacancies[i]}
+			}
+
+			if latestVacancy == nil {
+				latestVacancy = &vacancies[0]
+			}
 		}
 	}
 
@@ -311,57 +413,161 @@ func (v *VacanciesAnalyzer) analyzeVacanciesForSearch(ctx context.Context, errCh
 		}
 	}
 
-	close(requestChan)
-	wg.Wait()
 	log.Infof("fetched total %v vacancies for search with id %v", fetchedTotal, search.ID)
 }
 
+// windowPages is a number of pages to fetch, hh.ru doesn't page deeper than MaxSearchResults,
+// windows over it are reported by retriever
+func windowPages(window SearchWindow, pageSize int) int {
+	if window.Found == unknownFound {
+		return 1
+	}
+	return min((window.Found+pageSize-1)/pageSize, hh.MaxSearchResults/pageSize)
+}
+
 func (v *VacanciesAnalyzer) analyzeVacancies(ctx context.Context, requestChan <-chan analysisRequest, errChan chan<- analysisError) {
 
 	wg := sync.WaitGroup{}
 
+	var batch []analysisRequest
+	batchTimer := time.NewTimer(v.batchMaxWait)
+	batchTimer.Stop()
+
+	flush := func() {
+		batchTimer.Stop()
+		for _, requests := range groupBySearch(batch) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v.analyzeBatch(ctx, requests, errChan)
+			}()
+		}
+		batch = nil
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-batchTimer.C:
+			flush()
 		case request, ok := <-requestChan:
 
 			if !ok {
+				flush()
 				wg.Wait()
 				return
 			}
 
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
+			batch = append(batch, request)
+			if len(batch) >= v.batchSize {
+				flush()
+			} else if len(batch) == 1 {
+				batchTimer.Reset(v.batchMaxWait)
+			}
+		}
+	}
+}
 
-				err := v.analyzeVacancyWithAI(ctx, *request.vacancy, *request.search)
-				if err != nil {
-					errChan <- analysisError{request.vacancy.ID, request.search.ID, err}
-				} else {
-					metrics.HandledVacanciesCounter.Inc()
-				}
-			}()
+func (v *VacanciesAnalyzer) analyzeBatch(ctx context.Context, requests []analysisRequest, errChan chan<- analysisError) {
+
+	if len(requests) == 1 {
+		err := v.analyzeVacancyWithAI(ctx, *requests[0].vacancy, *requests[0].search)
+		if err != nil {
+			errChan <- analysisError{requests[0].vacancy.ID, requests[0].search.ID, err}
+		} else {
+			metrics.HandledVacanciesCounter.Inc()
+		}
+		return
+	}
+
+	search := *requests[0].search
+	employers := v.getEmployerLists(ctx, search.UserID)
+	prompt := v.aiService.MatchPrompt(ctx, search)
+	var vacancies []models.Vacancy
+
+	for _, request := range requests {
+		vacancy := PrepareVacancy(*request.vacancy)
+		wasSent, err := v.wasSentToUser(ctx, vacancy, search)
+		if err != nil {
+			errChan <- analysisError{vacancy.ID, search.ID, err}
+			continue
+		}
+		if wasSent || !passesSearchRules(vacancy, search, employers) {
+			metrics.HandledVacanciesCounter.Inc()
+			continue
+		}
+		if verdict, ok := v.getCachedVerdict(ctx, vacancy, search, prompt.Version); ok {
+			if err = v.handleVerdict(ctx, vacancy, search, verdict); err != nil {
+				errChan <- analysisError{vacancy.ID, search.ID, err}
+			} else {
+				metrics.HandledVacanciesCounter.Inc()
+			}
+			continue
+		}
+		vacancies = append(vacancies, vacancy)
+	}
+
+	similar := v.filterBySimilarity(ctx, search, vacancies)
+	metrics.HandledVacanciesCounter.Add(float64(len(vacancies) - len(similar)))
+	vacancies = similar
+
+	if len(vacancies) == 0 {
+		return
+	}
+
+	if err := v.checkUserQuota(ctx, search.UserID); err != nil {
+		for _, vacancy := range vacancies {
+			errChan <- analysisError{vacancy.ID, search.ID, err}
 		}
+		return
+	}
+
+	verdicts, failures := v.aiService.DoVacanciesMatchSearch(ctx, search, prompt, vacancies)
+
+	for _, vacancy := range vacancies {
+		err, failed := failures[vacancy.ID]
+		if !failed {
+			v.cacheVerdict(ctx, vacancy, search, prompt.Version, verdicts[vacancy.ID])
+			err = v.handleVerdict(ctx, vacancy, search, verdicts[vacancy.ID])
+		}
+
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				errChan <- analysisError{vacancy.ID, search.ID, err}
+			}
+			continue
+		}
+		metrics.HandledVacanciesCounter.Inc()
 	}
 }
 
 func (v *VacanciesAnalyzer) analyzeVacancyWithAI(ctx context.Context, vacancy models.Vacancy, search models.JobSearch) error {
 
-	vacancy.Description = removeExtraSpaces(removeHtmlTags(vacancy.Description))
-	vacancyID := createIdForNotifiedVacancy(vacancy, search)
-	wasSent, err := v.vacancies.IsSentToUser(ctx, vacancyID)
+	vacancy = PrepareVacancy(vacancy)
+	wasSent, err := v.wasSentToUser(ctx, vacancy, search)
 	if err != nil {
-		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).
-			Errorf("failed to check if vacancy was sent to user: %v", err)
 		return err
 	}
 
-	if wasSent {
+	if wasSent || !passesSearchRules(vacancy, search, v.getEmployerLists(ctx, search.UserID)) {
 		return nil
 	}
 
-	matched, err := v.aiService.DoesVacancyMatchSearch(ctx, search, vacancy)
+	prompt := v.aiService.MatchPrompt(ctx, search)
+	if verdict, ok := v.getCachedVerdict(ctx, vacancy, search, prompt.Version); ok {
+		return v.handleVerdict(ctx, vacancy, search, verdict)
+	}
+
+	if len(v.filterBySimilarity(ctx, search, []models.Vacancy{vacancy})) == 0 {
+		return nil
+	}
+
+	if err = v.checkUserQuota(ctx, search.UserID); err != nil {
+		return err
+	}
+
+	verdict, err := v.aiService.DoesVacancyMatchSearch(ctx, search, prompt, vacancy)
 
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
@@ -370,8 +576,114 @@ func (v *VacanciesAnalyzer) analyzeVacancyWithAI(ctx context.Context, vacancy mo
 		return err
 	}
 
-	if matched {
-		if err = v.handleApproveByAI(ctx, vacancy, search); err != nil {
+	v.cacheVerdict(ctx, vacancy, search, prompt.Version, verdict)
+	return v.handleVerdict(ctx, vacancy, search, verdict)
+}
+
+func (v *VacanciesAnalyzer) filterBySimilarity(ctx context.Context, search models.JobSearch,
+	vacancies []models.Vacancy) []models.Vacancy {
+
+	if v.similarity == nil {
+		return vacancies
+	}
+	return v.similarity.Filter(ctx, search, vacancies)
+}
+
+func (v *VacanciesAnalyzer) getEmployerLists(ctx context.Context, userID int64) models.EmployerLists {
+
+	if v.employerLists == nil {
+		return models.EmployerLists{}
+	}
+
+	lists, err := v.employerLists.GetByUser(ctx, userID)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get employer lists of user %d: %v",
+			userID, err)
+		return models.EmployerLists{}
+	}
+	return lists
+}
+
+func (v *VacanciesAnalyzer) checkUserQuota(ctx context.Context, userID int64) error {
+
+	if v.usage == nil || v.userDailyTokenLimit <= 0 {
+		return nil
+	}
+
+	tokens, err := v.usage.GetUserTokens(ctx, userID, models.UsageDay(time.Now()))
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get AI usage of user %d: %v", userID, err)
+		return nil
+	}
+
+	if tokens >= v.userDailyTokenLimit {
+		metrics.AiUserQuotaExceededCounter.Inc()
+		return errs.UserQuotaExceeded
+	}
+	return nil
+}
+
+func (v *VacanciesAnalyzer) getCachedVerdict(ctx context.Context, vacancy models.Vacancy, search models.JobSearch,
+	promptVersion string) (models.MatchVerdict, bool) {
+
+	if v.verdictCache == nil {
+		return models.MatchVerdict{}, false
+	}
+
+	verdict, err := v.verdictCache.Get(ctx, v.verdictCacheKey(vacancy, search, promptVersion, v.aiService.Model()))
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to get cached verdict: %v", err)
+	}
+	if verdict == nil {
+		metrics.AiVerdictCacheMissesCounter.Inc()
+		return models.MatchVerdict{}, false
+	}
+
+	metrics.AiVerdictCacheHitsCounter.Inc()
+	return *verdict, true
+}
+
+func (v *VacanciesAnalyzer) cacheVerdict(ctx context.Context, vacancy models.Vacancy, search models.JobSearch,
+	promptVersion string, verdict models.MatchVerdict) {
+
+	if v.verdictCache == nil {
+		return
+	}
+
+	model := verdict.Model
+	if model == "" {
+		model = v.aiService.Model()
+	}
+
+	err := v.verdictCache.Save(ctx, v.verdictCacheKey(vacancy, search, promptVersion, model), verdict, v.verdictCacheTTL)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).Errorf("failed to cache verdict: %v", err)
+	}
+}
+
+func (v *VacanciesAnalyzer) verdictCacheKey(vacancy models.Vacancy, search models.JobSearch,
+	promptVersion, model string) models.VerdictCacheKey {
+	descriptionHash := sha256.Sum256([]byte(vacancy.Description))
+	return models.NewVerdictCacheKey(search.UserWish, descriptionHash[:], promptVersion, model)
+}
+
+func (v *VacanciesAnalyzer) wasSentToUser(ctx context.Context, vacancy models.Vacancy, search models.JobSearch) (bool, error) {
+
+	vacancyID := createIdForNotifiedVacancy(vacancy, search)
+	wasSent, err := v.vacancies.IsSentToUser(ctx, vacancyID)
+	if err != nil {
+		log.WithField(logger.ErrorTypeField, logger.ErrorTypeDb).
+			Errorf("failed to check if vacancy was sent to user: %v", err)
+		return false, err
+	}
+	return wasSent, nil
+}
+
+func (v *VacanciesAnalyzer) handleVerdict(ctx context.Context, vacancy models.Vacancy, search models.JobSearch,
+	verdict models.MatchVerdict) error {
+
+	if verdict.IsMatchFor(search.Strictness) {
+		if err := v.handleApproveByAI(ctx, vacancy, search, verdict); err != nil {
 			return err
 		}
 		metrics.ApprovedByAiVacanciesCounter.Inc()
@@ -381,10 +693,14 @@ func (v *VacanciesAnalyzer) analyzeVacancyWithAI(ctx context.Context, vacancy mo
 	return nil
 }
 
-func (v *VacanciesAnalyzer) handleApproveByAI(ctx context.Context, vacancy models.Vacancy, search models.JobSearch) error {
+func (v *VacanciesAnalyzer) handleApproveByAI(ctx context.Context, vacancy models.Vacancy, search models.JobSearch,
+	verdict models.MatchVerdict) error {
 
 	vacancyID := createIdForNotifiedVacancy(vacancy, search)
-	if err := v.vacancies.RecordAsSentToUser(ctx, vacancyID); err != nil {
+	//description is kept short since it's shown to AI as a feedback example
+	sent := vacancy
+	sent.Description = shortenText(vacancy.Description, feedbackDescriptionLength)
+	if err := v.vacancies.RecordAsSentToUser(ctx, vacancyID, sent, verdict); err != nil {
 		if errors.Is(err, errs.VacancyAlreadySentToUser) {
 			return nil
 		}
@@ -392,7 +708,7 @@ func (v *VacanciesAnalyzer) handleApproveByAI(ctx context.Context, vacancy model
 			Errorf("failed to record vacancy as send to user: %v", err)
 		return err
 	}
-	event := events2.VacancyFound{Search: search, Name: vacancy.Name, Url: vacancy.Url}
+	event := events2.VacancyFound{Search: search, Vacancy: vacancy, Verdict: verdict}
 	v.bus.Publish(events2.VacancyFoundTopic, event)
 	return nil
 }
@@ -414,6 +730,43 @@ func createIdForNotifiedVacancy(vacancy models.Vacancy, search models.JobSearch)
 	}
 }
 
+func passesSearchRules(vacancy models.Vacancy, search models.JobSearch, employers models.EmployerLists) bool {
+	violation := search.Rules.Check(vacancy)
+	if violation == models.NoRuleViolation {
+		violation = employers.Check(vacancy)
+	}
+	if violation == models.NoRuleViolation {
+		return true
+	}
+
+	log.Debugf("vacancy %v rejected by rule %v for search %v", vacancy.ID, violation, search.ID)
+	metrics.RejectedByRulesVacanciesCounter.WithLabelValues(string(violation)).Inc()
+	return false
+}
+
+func groupBySearch(requests []analysisRequest) [][]analysisRequest {
+
+	var groups [][]analysisRequest
+	indexes := make(map[int]int)
+
+	for _, request := range requests {
+		idx, ok := indexes[request.search.ID]
+		if !ok {
+			idx = len(groups)
+			indexes[request.search.ID] = idx
+			groups = append(groups, nil)
+		}
+		groups[idx] = append(groups[idx], request)
+	}
+	return groups
+}
+
+// PrepareVacancy cleans vacancy description before it's shown to AI
+func PrepareVacancy(vacancy models.Vacancy) models.Vacancy {
+	vacancy.Description = removeExtraSpaces(removeHtmlTags(vacancy.Description))
+	return vacancy
+}
+
 func removeHtmlTags(input string) string {
 	re := regexp.MustCompile("<[^>]*>")
 	return re.ReplaceAllString(input, "")
@@ -423,3 +776,17 @@ func removeExtraSpaces(input string) string {
 	re := regexp.MustCompile(`\s{2,}`)
 	return re.ReplaceAllString(input, " ")
 }
+
+// shortenText cuts text to maxRunes at the last space before the limit
+func shortenText(text string, maxRunes int) string {
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+
+	cut := runes[:maxRunes]
+	if idx := strings.LastIndexFunc(string(cut), unicode.IsSpace); idx > 0 {
+		return strings.TrimSpace(string(cut)[:idx]) + "…"
+	}
+	return string(cut) + "…"
+}
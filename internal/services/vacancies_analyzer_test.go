@@ -3,6 +3,9 @@ package services
 import (
 	"context"
 	"github.com/asaskevich/EventBus"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
+	"github.com/maxaizer/hh-parser/internal/domain/events"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -11,15 +14,24 @@ import (
 	"time"
 )
 
+const matchedVerdictResponse = `{"score": 90, "confidence": "high", "matched_criteria": ["golang"], ` +
+	`"missed_criteria": [], "rationale": "подходит"}`
+
 type mockVacanciesRetriever struct {
 	vacancies []models.Vacancy
 }
 
-func (m mockVacanciesRetriever) GetVacancies(search *models.JobSearch, dateFrom time.Time, page, pageSize int) ([]models.Vacancy, error) {
-	return m.vacancies, nil
+func (m mockVacanciesRetriever) SplitSearch(_ context.Context, search *models.JobSearch,
+	dateFrom time.Time) ([]SearchWindow, error) {
+	return []SearchWindow{{DateFrom: dateFrom, Found: len(m.vacancies)}}, nil
+}
+
+func (m mockVacanciesRetriever) GetVacancies(_ context.Context, search *models.JobSearch, window SearchWindow,
+	page, pageSize int) (WindowPage, error) {
+	return WindowPage{Vacancies: m.vacancies, Found: len(m.vacancies)}, nil
 }
 
-func (m mockVacanciesRetriever) GetVacancy(ID string) (*models.Vacancy, error) {
+func (m mockVacanciesRetriever) GetVacancy(_ context.Context, ID string) (*models.Vacancy, error) {
 	for _, vacancy := range m.vacancies {
 		if vacancy.ID == ID {
 			return &vacancy, nil
@@ -28,13 +40,47 @@ func (m mockVacanciesRetriever) GetVacancy(ID string) (*models.Vacancy, error) {
 	return nil, errors.New("not found")
 }
 
+// pagedVacanciesRetriever returns one page per call and records requested pages
+type pagedVacanciesRetriever struct {
+	mockVacanciesRetriever
+	pages          [][]models.Vacancy
+	lastPageErr    error //returned for the page after the last one
+	requestedPages []int
+}
+
+func (m *pagedVacanciesRetriever) SplitSearch(_ context.Context, search *models.JobSearch,
+	dateFrom time.Time) ([]SearchWindow, error) {
+	return []SearchWindow{{DateFrom: dateFrom, Found: unknownFound}}, nil
+}
+
+func (m *pagedVacanciesRetriever) GetVacancies(_ context.Context, search *models.JobSearch, window SearchWindow,
+	page, pageSize int) (WindowPage, error) {
+	m.requestedPages = append(m.requestedPages, page)
+	if page == len(m.pages) {
+		return WindowPage{}, m.lastPageErr
+	}
+
+	found := len(m.pages) * pageSize
+	if m.lastPageErr != nil {
+		found += pageSize
+	}
+	return WindowPage{Vacancies: m.pages[page], Found: found}, nil
+}
+
 type mockAiClient struct {
 	mock.Mock
 }
 
-func (m *mockAiClient) GenerateResponse(ctx context.Context, request string) (string, error) {
-	args := m.Called(ctx, request)
-	return args.String(0), args.Error(1)
+func (m *mockAiClient) Model() string {
+	return "mock-model"
+}
+
+func (m *mockAiClient) GenerateJSONResponse(ctx context.Context, request string, schema *ai.Schema) (ai.Response, error) {
+	args := m.Called(ctx, request, schema)
+	if response, ok := args.Get(0).(ai.Response); ok {
+		return response, args.Error(1)
+	}
+	return ai.Response{Text: args.String(0)}, args.Error(1)
 }
 
 type mockSearches struct {
@@ -67,14 +113,20 @@ func (m *mockVacancies) IsSentToUser(ctx context.Context, vacancy models.Notifie
 	return args.Bool(0), args.Error(1)
 }
 
-func (m *mockVacancies) RecordAsSentToUser(ctx context.Context, vacancy models.NotifiedVacancyID) error {
-	return m.Called(ctx, vacancy).Error(0)
+func (m *mockVacancies) RecordAsSentToUser(ctx context.Context, vacancyID models.NotifiedVacancyID,
+	vacancy models.Vacancy, verdict models.MatchVerdict) error {
+	return m.Called(ctx, vacancyID, vacancy, verdict).Error(0)
 }
 
 func (m *mockVacancies) AddFailedToAnalyze(ctx context.Context, searchID int, vacancyID string, error string) error {
 	return m.Called(ctx, searchID, vacancyID, error).Error(0)
 }
 
+func (m *mockVacancies) ParkForAnalysis(ctx context.Context, searchID int, vacancyID string, reason string,
+	until time.Time) error {
+	return m.Called(ctx, searchID, vacancyID, reason, until).Error(0)
+}
+
 func (m *mockVacancies) GetFailedToAnalyze(ctx context.Context) ([]models.FailedVacancy, error) {
 	args := m.Called(ctx)
 	failedVacancies, ok := args.Get(0).([]models.FailedVacancy)
@@ -91,9 +143,10 @@ func (m *mockVacancies) RemoveFailedToAnalyze(ctx context.Context, maxAttempts i
 
 func Test_AnalyzeVacancy_WhenAlreadySentToUser_ShouldIgnore(t *testing.T) {
 
-	ai := mockAiClient{}
-	ai.On("GenerateResponse", mock.Anything, mock.Anything).Return("да", nil).Once()
-	aiServiceMock := NewAIService(&ai)
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything).
+		Return(matchedVerdictResponse, nil).Once()
+	aiServiceMock := NewAIService(&aiClient, testPrompts(t))
 
 	retrieverMock := mockVacanciesRetriever{}
 
@@ -106,7 +159,7 @@ func Test_AnalyzeVacancy_WhenAlreadySentToUser_ShouldIgnore(t *testing.T) {
 		Return(func() (bool, error) {
 			return firstVacancyAnalyzed, nil
 		})
-	vacancies.On("RecordAsSentToUser", mock.Anything, mock.Anything).
+	vacancies.On("RecordAsSentToUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Run(func(args mock.Arguments) {
 			firstVacancyAnalyzed = true
 		}).
@@ -131,5 +184,315 @@ func Test_AnalyzeVacancy_WhenAlreadySentToUser_ShouldIgnore(t *testing.T) {
 	assert.NoError(t, err)
 	err = analyzer.analyzeVacancyWithAI(context.Background(), vacancy2, search)
 	assert.NoError(t, err)
-	ai.AssertExpectations(t)
+	aiClient.AssertExpectations(t)
+}
+
+func Test_AnalyzeVacancies_WhenBatchingEnabled_ShouldSendOneRequestPerBatch(t *testing.T) {
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, batchMatchVerdictSchema).
+		Return(`{"verdicts": [`+
+			`{"vacancy_id": "1", "score": 80, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""},`+
+			`{"vacancy_id": "2", "score": 20, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`+
+			`]}`, nil).Once()
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+	vacancies.On("RecordAsSentToUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+
+	notifications := 0
+	bus := EventBus.New()
+	_ = bus.Subscribe(events.VacancyFoundTopic, func(event events.VacancyFound) { notifications++ })
+
+	analyzer, err := NewVacanciesAnalyzer(bus, NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{}, &mockSearches{},
+		vacancies, time.Hour)
+	assert.NoError(t, err)
+	analyzer.WithBatching(2, time.Minute)
+
+	search := models.JobSearch{ID: 1}
+	requestChan := make(chan analysisRequest, 2)
+	errChan := make(chan analysisError, 2)
+	requestChan <- analysisRequest{search: &search, vacancy: &models.Vacancy{ID: "1", Description: "first"}}
+	requestChan <- analysisRequest{search: &search, vacancy: &models.Vacancy{ID: "2", Description: "second"}}
+	close(requestChan)
+
+	analyzer.analyzeVacancies(context.Background(), requestChan, errChan)
+	close(errChan)
+
+	assert.Empty(t, errChan)
+	assert.Equal(t, 1, notifications)
+	aiClient.AssertExpectations(t)
+	vacancies.AssertExpectations(t)
+}
+
+type memoryVerdictCache struct {
+	verdicts map[models.VerdictCacheKey]models.MatchVerdict
+}
+
+func (m *memoryVerdictCache) Get(_ context.Context, key models.VerdictCacheKey) (*models.MatchVerdict, error) {
+	if verdict, ok := m.verdicts[key]; ok {
+		return &verdict, nil
+	}
+	return nil, nil
+}
+
+func (m *memoryVerdictCache) Save(_ context.Context, key models.VerdictCacheKey, verdict models.MatchVerdict,
+	_ time.Duration) error {
+	m.verdicts[key] = verdict
+	return nil
+}
+
+func Test_AnalyzeVacancies_WhenBatchingEnabled_ShouldGetFeedbackOncePerBatch(t *testing.T) {
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, batchMatchVerdictSchema).
+		Return(`{"verdicts": [`+
+			`{"vacancy_id": "1", "score": 20, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""},`+
+			`{"vacancy_id": "2", "score": 20, "confidence": "high", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`+
+			`]}`, nil).Once()
+
+	feedback := &mockFeedbackExamples{}
+	feedback.On("GetRecentBySearch", mock.Anything, 1, 5).
+		Return([]models.VacancyFeedback{{VacancyID: "42", VacancyName: "Go разработчик", Positive: true}}, nil)
+
+	aiService := NewAIService(&aiClient, testPrompts(t))
+	aiService.WithFeedbackExamples(feedback, 5)
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), aiService, mockVacanciesRetriever{}, &mockSearches{},
+		vacancies, time.Hour)
+	assert.NoError(t, err)
+	analyzer.WithBatching(2, time.Minute)
+	cache := &memoryVerdictCache{verdicts: map[models.VerdictCacheKey]models.MatchVerdict{}}
+	analyzer.WithVerdictCache(cache, time.Hour)
+
+	search := models.JobSearch{ID: 1}
+	errChan := make(chan analysisError, 2)
+	analyzer.analyzeBatch(context.Background(), []analysisRequest{
+		{search: &search, vacancy: &models.Vacancy{ID: "1", Description: "first"}},
+		{search: &search, vacancy: &models.Vacancy{ID: "2", Description: "second"}},
+	}, errChan)
+	close(errChan)
+
+	assert.Empty(t, errChan)
+	assert.Len(t, cache.verdicts, 2)
+	feedback.AssertNumberOfCalls(t, "GetRecentBySearch", 1)
+	aiClient.AssertExpectations(t)
+}
+
+func Test_AnalyzeVacancy_WhenRejectedBySearchRules_ShouldNotCallAI(t *testing.T) {
+
+	aiClient := mockAiClient{}
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{},
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(t, err)
+
+	search := models.JobSearch{ID: 1, Rules: models.SearchRules{
+		NameExclude:       []string{"1с"},
+		MinSalary:         200000,
+		ExcludedEmployers: []string{"Рога и копыта"},
+	}}
+
+	rejected := []models.Vacancy{
+		{ID: "1", Name: "Программист 1С"},
+		{ID: "2", Name: "Golang developer", Salary: &models.Salary{From: 100000, Currency: models.CurrencyRUR}},
+		{ID: "3", Name: "Golang developer", Employer: models.Employer{ID: "42", Name: "рога и копыта"}},
+		{ID: "4", Name: "Golang developer", Archived: true},
+	}
+
+	for _, vacancy := range rejected {
+		err = analyzer.analyzeVacancyWithAI(context.Background(), vacancy, search)
+		assert.NoError(t, err)
+	}
+	aiClient.AssertNotCalled(t, "GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type mockEmployerLists struct {
+	mock.Mock
+}
+
+func (m *mockEmployerLists) GetByUser(ctx context.Context, userID int64) (models.EmployerLists, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(models.EmployerLists), args.Error(1)
+}
+
+func Test_AnalyzeVacancy_ShouldApplyUserEmployerLists(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything).Return(matchedVerdictResponse, nil)
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+	vacancies.On("RecordAsSentToUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	var found []string
+	bus := EventBus.New()
+	assert.NoError(bus.Subscribe(events.VacancyFoundTopic, func(event events.VacancyFound) {
+		found = append(found, event.Vacancy.ID)
+	}))
+
+	lists := &mockEmployerLists{}
+	lists.On("GetByUser", mock.Anything, int64(7)).Return(models.NewEmployerLists([]models.EmployerListEntry{
+		{UserID: 7, EmployerID: "1", Kind: models.EmployerBlocked},
+		{UserID: 7, EmployerID: "2", Kind: models.EmployerAllowed},
+	}), nil)
+	lists.On("GetByUser", mock.Anything, int64(8)).Return(models.NewEmployerLists([]models.EmployerListEntry{
+		{UserID: 8, EmployerID: "1", Kind: models.EmployerBlocked},
+	}), nil)
+
+	analyzer, err := NewVacanciesAnalyzer(bus, NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{},
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(err)
+	analyzer.WithEmployerLists(lists)
+
+	withAllowList := models.JobSearch{ID: 1, UserID: 7}
+	withBlockList := models.JobSearch{ID: 2, UserID: 8}
+	for _, vacancy := range []models.Vacancy{
+		{ID: "blocked", Employer: models.Employer{ID: "1"}},
+		{ID: "allowed", Employer: models.Employer{ID: "2"}},
+		{ID: "other", Employer: models.Employer{ID: "3"}},
+	} {
+		assert.NoError(analyzer.analyzeVacancyWithAI(context.Background(), vacancy, withAllowList))
+		assert.NoError(analyzer.analyzeVacancyWithAI(context.Background(), vacancy, withBlockList))
+	}
+
+	assert.Equal([]string{"allowed", "allowed", "other"}, found)
+}
+
+type mockUsage struct {
+	mock.Mock
+}
+
+func (m *mockUsage) GetUserTokens(ctx context.Context, userID int64, day string) (int64, error) {
+	args := m.Called(ctx, userID, day)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockUsage) Add(ctx context.Context, usage models.AIUsage) error {
+	return m.Called(ctx, usage).Error(0)
+}
+
+func Test_AnalyzeVacancy_WhenUserQuotaExceeded_ShouldNotCallAI(t *testing.T) {
+
+	aiClient := mockAiClient{}
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+
+	usage := &mockUsage{}
+	usage.On("GetUserTokens", mock.Anything, int64(7), models.UsageDay(time.Now())).Return(int64(1000), nil)
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{},
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(t, err)
+	analyzer.WithUserDailyTokenLimit(usage, 1000)
+
+	err = analyzer.analyzeVacancyWithAI(context.Background(), models.Vacancy{ID: "1"}, models.JobSearch{ID: 1, UserID: 7})
+	assert.ErrorIs(t, err, errs.UserQuotaExceeded)
+	aiClient.AssertNotCalled(t, "GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_AnalyzeVacancy_ShouldApplySearchStrictness(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything).
+		Return(`{"score": 60, "confidence": "medium", "matched_criteria": [], "missed_criteria": [], "rationale": ""}`, nil)
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(false, nil)
+	vacancies.On("RecordAsSentToUser", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	found := map[int]bool{}
+	bus := EventBus.New()
+	assert.NoError(bus.Subscribe(events.VacancyFoundTopic, func(event events.VacancyFound) {
+		found[event.Search.ID] = true
+	}))
+
+	analyzer, err := NewVacanciesAnalyzer(bus, NewAIService(&aiClient, testPrompts(t)), mockVacanciesRetriever{},
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(err)
+
+	vacancy := models.Vacancy{ID: "1", Name: "Golang developer", Description: "test description"}
+	searches := []models.JobSearch{
+		{ID: 1, Strictness: models.StrictnessStrict},
+		{ID: 2, Strictness: models.StrictnessNormal},
+		{ID: 3, Strictness: models.StrictnessLenient},
+	}
+	for _, search := range searches {
+		assert.NoError(analyzer.analyzeVacancyWithAI(context.Background(), vacancy, search))
+	}
+
+	assert.Equal(map[int]bool{2: true, 3: true}, found)
+}
+
+func Test_AnalyzeVacanciesForSearch_WhenPageIsEmpty_ShouldCheckNextPages(t *testing.T) {
+
+	retriever := &pagedVacanciesRetriever{pages: [][]models.Vacancy{
+		{},
+		{{ID: "2", Name: "Golang developer"}},
+		{{ID: "3", Name: "Golang developer"}},
+	}}
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(true, nil)
+
+	search := models.JobSearch{ID: 1}
+	searches := &mockSearches{}
+	searches.On("UpdateLastCheckedVacancy", mock.Anything, search.ID, retriever.pages[1][0]).Return(nil).Once()
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), NewAIService(&mockAiClient{}, testPrompts(t)), retriever,
+		searches, vacancies, time.Hour)
+	assert.NoError(t, err)
+
+	errChan := make(chan analysisError, 3)
+	analyzer.analyzeVacanciesForSearch(context.Background(), errChan, search, time.Now().Add(-time.Hour))
+	close(errChan)
+
+	assert.Empty(t, errChan)
+	assert.Equal(t, []int{0, 1, 2}, retriever.requestedPages)
+	searches.AssertExpectations(t)
+}
+
+func Test_AnalyzeVacanciesForSearch_WhenPageFailed_ShouldWaitForStartedAnalysis(t *testing.T) {
+
+	retriever := &pagedVacanciesRetriever{
+		pages:       [][]models.Vacancy{{{ID: "1", Name: "Golang developer"}}},
+		lastPageErr: errors.New("hh is down"),
+	}
+
+	vacancies := &mockVacancies{}
+	vacancies.On("IsSentToUser", mock.Anything, mock.Anything).Return(func() (bool, error) {
+		time.Sleep(50 * time.Millisecond)
+		return false, errors.New("db is locked")
+	})
+
+	analyzer, err := NewVacanciesAnalyzer(EventBus.New(), NewAIService(&mockAiClient{}, testPrompts(t)), retriever,
+		&mockSearches{}, vacancies, time.Hour)
+	assert.NoError(t, err)
+
+	errChan := make(chan analysisError, 3)
+	analyzer.analyzeVacanciesForSearch(context.Background(), errChan, models.JobSearch{ID: 1}, time.Now().Add(-time.Hour))
+	close(errChan)
+
+	assert.Len(t, errChan, 1)
+	assert.Equal(t, []int{0, 1}, retriever.requestedPages)
+}
+
+func Test_ShortenText_ShouldCutAtWordBoundary(t *testing.T) {
+
+	assert := assert.New(t)
+
+	assert.Equal("Пишем бэкенд", shortenText("Пишем бэкенд", 12))
+	assert.Equal("Пишем…", shortenText("Пишем бэкенд на Go", 10))
+	assert.Equal("Бэкенд…", shortenText("Бэкенд-разработка", 6))
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
 	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
@@ -12,8 +13,24 @@ type VacancyCleanupRepository interface {
 	RemoveOldVacancies(ctx context.Context, expirationTime time.Time) (int64, error)
 }
 
+type VerdictCleanupRepository interface {
+	RemoveExpired(ctx context.Context) (int64, error)
+}
+
+type EmployerCleanupRepository interface {
+	RemoveExpired(ctx context.Context) (int64, error)
+}
+
+type EmbeddingCleanupRepository interface {
+	RemoveOlderThan(ctx context.Context, kind models.EmbeddingKind, createdBefore time.Time) (int64, error)
+	RemoveRejectionsOlderThan(ctx context.Context, createdBefore time.Time) (int64, error)
+}
+
 type VacanciesCleaner struct {
 	vacancies            VacancyCleanupRepository
+	verdicts             VerdictCleanupRepository
+	embeddings           EmbeddingCleanupRepository
+	employers            EmployerCleanupRepository
 	cron                 *cron.Cron
 	expirationTimeInDays int
 }
@@ -40,6 +57,18 @@ func NewVacanciesCleaner(vacancies VacancyCleanupRepository, expirationInDays in
 	return vc, nil
 }
 
+func (vc *VacanciesCleaner) WithVerdictsCleanup(verdicts VerdictCleanupRepository) {
+	vc.verdicts = verdicts
+}
+
+func (vc *VacanciesCleaner) WithEmbeddingsCleanup(embeddings EmbeddingCleanupRepository) {
+	vc.embeddings = embeddings
+}
+
+func (vc *VacanciesCleaner) WithEmployersCleanup(employers EmployerCleanupRepository) {
+	vc.employers = employers
+}
+
 func (vc *VacanciesCleaner) Stop() {
 	vc.cron.Stop()
 }
@@ -52,4 +81,38 @@ func (vc *VacanciesCleaner) cleanOldVacancies() {
 	} else {
 		log.Infof("Old vacancies was cleaned at %v, affected rows: %v", time.Now(), rowsAffected)
 	}
+
+	if vc.verdicts != nil {
+		rowsAffected, err = vc.verdicts.RemoveExpired(context.Background())
+		if err != nil {
+			log.Errorf("Failed to clean expired verdicts: %v", err)
+		} else {
+			log.Infof("Expired verdicts was cleaned at %v, affected rows: %v", time.Now(), rowsAffected)
+		}
+	}
+
+	if vc.embeddings != nil {
+		rowsAffected, err = vc.embeddings.RemoveOlderThan(context.Background(), models.EmbeddingKindVacancy, expirationTime)
+		if err != nil {
+			log.Errorf("Failed to clean old vacancy embeddings: %v", err)
+		} else {
+			log.Infof("Old vacancy embeddings was cleaned at %v, affected rows: %v", time.Now(), rowsAffected)
+		}
+
+		rowsAffected, err = vc.embeddings.RemoveRejectionsOlderThan(context.Background(), expirationTime)
+		if err != nil {
+			log.Errorf("Failed to clean old similarity rejections: %v", err)
+		} else {
+			log.Infof("Old similarity rejections was cleaned at %v, affected rows: %v", time.Now(), rowsAffected)
+		}
+	}
+
+	if vc.employers != nil {
+		rowsAffected, err = vc.employers.RemoveExpired(context.Background())
+		if err != nil {
+			log.Errorf("Failed to clean expired employers: %v", err)
+		} else {
+			log.Infof("Expired employers was cleaned at %v, affected rows: %v", time.Now(), rowsAffected)
+		}
+	}
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const maxClarifyingQuestions = 3
+
+type clarifyWishPromptData struct {
+	Wish string
+}
+
+var clarifyWishPromptFields = []string{".Wish"}
+
+var clarifyWishSchema = &ai.Schema{
+	Type: ai.TypeObject,
+	Properties: map[string]*ai.Schema{
+		"is_clear": {
+			Type:        ai.TypeBoolean,
+			Description: "Достаточно ли пожелание конкретно для отбора вакансий",
+		},
+		"improved_wish": {
+			Type:        ai.TypeString,
+			Description: "Улучшенная формулировка пожелания",
+		},
+		"questions": {
+			Type:  ai.TypeArray,
+			Items: &ai.Schema{Type: ai.TypeString},
+		},
+	},
+	Required: []string{"is_clear", "improved_wish", "questions"},
+}
+
+type clarifyWishResponse struct {
+	IsClear      *bool    `json:"is_clear"`
+	ImprovedWish string   `json:"improved_wish"`
+	Questions    []string `json:"questions"`
+}
+
+func LoadClarifyWishPromptTemplate(path string) (*PromptTemplate, error) {
+	return LoadPromptTemplate(path, clarifyWishPromptFields)
+}
+
+func (a *AIService) WithWishClarification(prompt *PromptTemplate) {
+	a.clarifyWishPrompt = prompt
+}
+
+func (a *AIService) ClarifyWish(ctx context.Context, userID int64, wish string) (models.WishClarification, error) {
+
+	if a.clarifyWishPrompt == nil {
+		return models.WishClarification{}, errors.New("wish clarification prompt is not configured")
+	}
+
+	request, err := a.clarifyWishPrompt.Execute(clarifyWishPromptData{Wish: wish})
+	if err != nil {
+		return models.WishClarification{}, fmt.Errorf("can't build prompt: %w", err)
+	}
+
+	response, _, err := a.generate(ctx, models.JobSearch{UserID: userID}, request, clarifyWishSchema)
+	if err != nil {
+		return models.WishClarification{}, err
+	}
+
+	log.Infof("got wish clarification \"%v\" for user %v", response, userID)
+
+	clarification, err := parseWishClarification(response, wish)
+	if err != nil {
+		return models.WishClarification{}, fmt.Errorf("unexpected wish clarification response \"%v\": %w", response, err)
+	}
+	return clarification, nil
+}
+
+func parseWishClarification(response string, wish string) (models.WishClarification, error) {
+
+	var parsed clarifyWishResponse
+	if err := json.Unmarshal([]byte(response), &parsed); err != nil {
+		return models.WishClarification{}, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if parsed.IsClear == nil {
+		return models.WishClarification{}, errors.New("is_clear is missing")
+	}
+
+	improved := strings.TrimSpace(parsed.ImprovedWish)
+	if improved == strings.TrimSpace(wish) {
+		improved = ""
+	}
+
+	var questions []string
+	for _, question := range parsed.Questions {
+		if question = strings.TrimSpace(question); question != "" && len(questions) < maxClarifyingQuestions {
+			questions = append(questions, question)
+		}
+	}
+
+	// nothing to offer the user, so there is no point in asking
+	if *parsed.IsClear || (improved == "" && len(questions) == 0) {
+		return models.WishClarification{IsClear: true}, nil
+	}
+
+	return models.WishClarification{ImprovedWish: improved, Questions: questions}, nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"testing"
+)
+
+func testClarifyWishPrompt(t *testing.T) *PromptTemplate {
+	prompt, err := LoadClarifyWishPromptTemplate("../../configs/prompts/clarify_wish.tmpl")
+	if err != nil {
+		t.Fatalf("can't load prompt: %v", err)
+	}
+	return prompt
+}
+
+func Test_AIService_ClarifyWish_ShouldParseSuggestionAndRecordUsage(t *testing.T) {
+
+	assert := assert.New(t)
+
+	aiClient := mockAiClient{}
+	aiClient.On("GenerateJSONResponse", mock.Anything, mock.MatchedBy(func(request string) bool {
+		return strings.Contains(request, "норм работа")
+	}), clarifyWishSchema).
+		Return(ai.Response{Text: `{"is_clear": false, "improved_wish": " Go разработчик на удалёнке ", ` +
+			`"questions": ["Какой стек?", "", "Какая зарплата?", "Какой город?", "Какой график?"]}`,
+			Usage: ai.Usage{PromptTokens: 10, CandidateTokens: 5}}, nil).Once()
+
+	usage := &mockUsage{}
+	usage.On("Add", mock.Anything, mock.MatchedBy(func(u models.AIUsage) bool {
+		return u.UserID == 7 && u.SearchID == 0 && u.TotalTokens() == 15
+	})).Return(nil).Once()
+
+	service := NewAIService(&aiClient, testPrompts(t))
+	service.WithUsageRecorder(usage)
+	service.WithWishClarification(testClarifyWishPrompt(t))
+
+	clarification, err := service.ClarifyWish(context.Background(), 7, "норм работа")
+	assert.NoError(err)
+	assert.False(clarification.IsClear)
+	assert.Equal("Go разработчик на удалёнке", clarification.ImprovedWish)
+	assert.Equal([]string{"Какой стек?", "Какая зарплата?", "Какой город?"}, clarification.Questions)
+	usage.AssertExpectations(t)
+}
+
+func Test_ParseWishClarification_WhenNothingToSuggest_ShouldTreatAsClear(t *testing.T) {
+
+	assert := assert.New(t)
+
+	clarification, err := parseWishClarification(`{"is_clear": false, "improved_wish": "бэкенд на Go", "questions": []}`,
+		"бэкенд на Go")
+	assert.NoError(err)
+	assert.True(clarification.IsClear)
+
+	_, err = parseWishClarification(`{"improved_wish": "что-то"}`, "норм работа")
+	assert.Error(err)
+}
+
+func Test_AIService_ClarifyWish_WhenNotConfigured_ShouldReturnError(t *testing.T) {
+
+	aiClient := mockAiClient{}
+	_, err := NewAIService(&aiClient, testPrompts(t)).ClarifyWish(context.Background(), 1, "норм работа")
+	assert.Error(t, err)
+	aiClient.AssertNotCalled(t, "GenerateJSONResponse", mock.Anything, mock.Anything, mock.Anything)
+}
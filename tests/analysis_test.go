@@ -28,6 +28,15 @@ var vacancy = models.Vacancy{
 func clearDb() {
 	dbCtx.DB.Exec("DELETE from failed_vacancies WHERE TRUE")
 	dbCtx.DB.Exec("DELETE from notified_vacancies WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from cached_verdicts WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from vacancy_feedbacks WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from ai_usages WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from ai_quota_usages WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from embeddings WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from similarity_rejections WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from cached_employers WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from employer_list_entries WHERE TRUE")
+	dbCtx.DB.Exec("DELETE from dictionary_entries WHERE TRUE")
 }
 
 func Test_Analysis_DuplicatesByDescriptionAreIgnored(t *testing.T) {
@@ -199,6 +208,63 @@ func Test_Analysis_DuplicatesByIdAreIgnored(t *testing.T) {
 	assert.Equal(t, 1, notifications)
 }
 
+func Test_Analysis_WhenVacancyDetailsFailed_ShouldAnalyzeOthersAndRetryFailed(t *testing.T) {
+
+	defer clearDb()
+
+	aiServiceMock := mockAiService{
+		responsesQueue: []struct {
+			result bool
+			err    error
+		}{
+			{result: true, err: nil},
+			{result: true, err: nil},
+		},
+	}
+
+	notifications := 0
+	bus := EventBus.New()
+	bus.Subscribe(events.VacancyFoundTopic, func(found events.VacancyFound) {
+		notifications++
+	})
+
+	unavailable := vacancy
+	unavailable.ID = "11"
+	unavailable.Description = "раб за еду"
+
+	retrieverMock := mockVacanciesRetriever{
+		vacancies: []models.Vacancy{vacancy},
+		failed:    []services.VacancyError{{VacancyID: unavailable.ID, Err: errors.New("hh: server error")}},
+		details:   []models.Vacancy{unavailable},
+	}
+
+	searches := repositories.NewSearchRepository(dbCtx.DB)
+	vacancies := repositories.NewVacanciesRepository(dbCtx.DB)
+
+	analyzer, err := services.NewVacanciesAnalyzer(bus, &aiServiceMock, retrieverMock,
+		searches, vacancies, time.Hour)
+	assert.NoError(t, err)
+
+	analysisComplete := make(chan struct{})
+
+	analyzer.WithAnalysisCompleteCallback(func() {
+		analysisComplete <- struct{}{}
+	})
+
+	go analyzer.Run()
+
+	select {
+	case <-time.After(30 * time.Second):
+		assert.Fail(t, "timed out")
+	case <-analysisComplete:
+	}
+
+	failed, err := vacancies.GetFailedToAnalyze(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+	assert.Equal(t, 2, notifications)
+}
+
 func Test_RerunAnalysisForFailedVacancy_Success(t *testing.T) {
 
 	defer clearDb()
@@ -299,3 +365,57 @@ func Test_RerunAnalysisForFailedVacancy_FailedAgain(t *testing.T) {
 	assert.Equal(t, search.ID, failed[0].SearchID)
 	assert.Equal(t, 2, failed[0].Attempts)
 }
+
+func Test_Analysis_SameDescriptionIsTakenFromVerdictCache(t *testing.T) {
+
+	defer clearDb()
+
+	aiServiceMock := mockAiService{
+		responsesQueue: []struct {
+			result bool
+			err    error
+		}{
+			{result: false, err: nil},
+		},
+	}
+
+	//reposted under a new id, rejected vacancies are not recorded as sent
+	duplicate := vacancy
+	duplicate.ID = "10"
+
+	searches := repositories.NewSearchRepository(dbCtx.DB)
+	vacancies := repositories.NewVacanciesRepository(dbCtx.DB)
+	verdicts := repositories.NewVerdictsRepository(dbCtx.DB)
+
+	for _, analyzed := range []models.Vacancy{vacancy, duplicate} {
+
+		retrieverMock := mockVacanciesRetriever{
+			vacancies: []models.Vacancy{analyzed},
+		}
+
+		analyzer, err := services.NewVacanciesAnalyzer(EventBus.New(), &aiServiceMock, retrieverMock,
+			searches, vacancies, time.Hour)
+		assert.NoError(t, err)
+		analyzer.WithVerdictCache(verdicts, time.Hour)
+
+		analysisComplete := make(chan struct{})
+
+		analyzer.WithAnalysisCompleteCallback(func() {
+			analysisComplete <- struct{}{}
+		})
+
+		go analyzer.Run()
+
+		select {
+		case <-time.After(30 * time.Second):
+			assert.Fail(t, "timed out")
+		case <-analysisComplete:
+		}
+	}
+
+	assert.Empty(t, aiServiceMock.responsesQueue)
+
+	failed, err := vacancies.GetFailedToAnalyze(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+}
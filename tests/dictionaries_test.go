@@ -0,0 +1,35 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Dictionaries_ShouldReplaceKeepingOrder(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	dictionaries := repositories.NewDictionariesRepository(dbCtx.DB)
+
+	assert.NoError(dictionaries.Replace(ctx, models.Dictionaries{
+		models.ExperienceDictionary: {{ID: "noExperience", Name: "Нет опыта"}, {ID: "between1And3", Name: "От 1 года до 3 лет"}},
+		models.ScheduleDictionary:   {{ID: "remote", Name: "Удаленная работа"}},
+	}))
+	assert.NoError(dictionaries.Replace(ctx, models.Dictionaries{
+		models.WorkFormatDictionary: {{ID: "REMOTE", Name: "Удалённо"}, {ID: "ON_SITE", Name: "На месте работодателя"}},
+		models.ScheduleDictionary:   {{ID: "flexible", Name: "Гибкий график"}},
+	}))
+
+	stored, err := dictionaries.GetAll(ctx)
+	assert.NoError(err)
+	assert.Equal(models.Dictionaries{
+		models.ExperienceDictionary: {{ID: "noExperience", Name: "Нет опыта"}, {ID: "between1And3", Name: "От 1 года до 3 лет"}},
+		models.ScheduleDictionary:   {{ID: "flexible", Name: "Гибкий график"}},
+		models.WorkFormatDictionary: {{ID: "REMOTE", Name: "Удалённо"}, {ID: "ON_SITE", Name: "На месте работодателя"}},
+	}, stored)
+}
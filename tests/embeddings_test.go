@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Embeddings_ShouldStoreByKindAndModel(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	embeddings := repositories.NewEmbeddingsRepository(dbCtx.DB)
+
+	wishHash := models.EmbeddingTextHash(search.UserWish)
+	vacancyHash := models.EmbeddingTextHash(vacancy.Description)
+
+	assert.NoError(embeddings.Save(ctx, []models.Embedding{
+		{Kind: models.EmbeddingKindWish, TextHash: wishHash, Model: "model", Vector: []float32{1, 0}},
+		{Kind: models.EmbeddingKindVacancy, TextHash: vacancyHash, Model: "model", Vector: []float32{0.5, 0.5}},
+	}))
+	assert.NoError(embeddings.Save(ctx, []models.Embedding{
+		{Kind: models.EmbeddingKindWish, TextHash: wishHash, Model: "model", Vector: []float32{1, 0}},
+	}), "saving an existing embedding should be ignored")
+
+	stored, err := embeddings.Get(ctx, models.EmbeddingKindWish, "model", []string{wishHash, vacancyHash})
+	assert.NoError(err)
+	assert.Equal(map[string][]float32{wishHash: {1, 0}}, stored)
+
+	stored, err = embeddings.Get(ctx, models.EmbeddingKindVacancy, "other model", []string{vacancyHash})
+	assert.NoError(err)
+	assert.Empty(stored)
+
+	removed, err := embeddings.RemoveOlderThan(ctx, models.EmbeddingKindVacancy, time.Now().Add(time.Minute))
+	assert.NoError(err)
+	assert.Equal(int64(1), removed)
+}
+
+func Test_Embeddings_ShouldStoreSimilarityRejections(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	embeddings := repositories.NewEmbeddingsRepository(dbCtx.DB)
+
+	wishHash := models.EmbeddingTextHash(search.UserWish)
+	vacancyHash := models.EmbeddingTextHash(vacancy.Description)
+	rejection := models.SimilarityRejection{WishHash: wishHash, TextHash: vacancyHash, Model: "model",
+		VacancyID: vacancy.ID, Similarity: 0.2, Threshold: 0.5}
+
+	assert.NoError(embeddings.SaveRejections(ctx, []models.SimilarityRejection{rejection}))
+	rejection.Similarity = 0.3
+	assert.NoError(embeddings.SaveRejections(ctx, []models.SimilarityRejection{rejection}),
+		"saving an existing rejection should update it")
+
+	stored, err := embeddings.GetRejections(ctx, "model", wishHash, []string{vacancyHash, "other"})
+	assert.NoError(err)
+	assert.Equal(map[string]float64{vacancyHash: 0.3}, stored)
+
+	stored, err = embeddings.GetRejections(ctx, "model", "other wish", []string{vacancyHash})
+	assert.NoError(err)
+	assert.Empty(stored)
+
+	removed, err := embeddings.RemoveRejectionsOlderThan(ctx, time.Now().Add(time.Minute))
+	assert.NoError(err)
+	assert.Equal(int64(1), removed)
+}
@@ -0,0 +1,73 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Employers_ShouldReturnCachedUntilExpired(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	employers := repositories.NewEmployersRepository(dbCtx.DB)
+
+	employer := models.Employer{ID: "370421", Name: "Роболайн", Trusted: true, SiteUrl: "https://roboline.ru",
+		Industries: []models.DictionaryItem{{ID: "7.540", Name: "Разработка программного обеспечения"}}, OpenVacancies: 12}
+	assert.NoError(employers.Save(ctx, employer, time.Hour))
+
+	cached, err := employers.Get(ctx, employer.ID)
+	assert.NoError(err)
+	assert.Equal(&employer, cached)
+
+	employer.OpenVacancies = 0
+	assert.NoError(employers.Save(ctx, employer, -time.Hour))
+
+	cached, err = employers.Get(ctx, employer.ID)
+	assert.NoError(err)
+	assert.Nil(cached)
+
+	removed, err := employers.RemoveExpired(ctx)
+	assert.NoError(err)
+	assert.Equal(int64(1), removed)
+}
+
+func Test_EmployerLists_ShouldKeepEmployerInOneList(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	lists := repositories.NewEmployerListsRepository(dbCtx.DB)
+
+	assert.NoError(lists.Save(ctx, models.EmployerListEntry{UserID: 1, EmployerID: "1", Kind: models.EmployerBlocked}))
+	assert.NoError(lists.Save(ctx, models.EmployerListEntry{UserID: 1, EmployerID: "2", EmployerName: "Яндекс",
+		Kind: models.EmployerBlocked}))
+	assert.NoError(lists.Save(ctx, models.EmployerListEntry{UserID: 1, EmployerID: "2", EmployerName: "Яндекс",
+		Kind: models.EmployerAllowed}))
+	assert.NoError(lists.Save(ctx, models.EmployerListEntry{UserID: 2, EmployerID: "3", Kind: models.EmployerBlocked}))
+
+	userLists, err := lists.GetByUser(ctx, 1)
+	assert.NoError(err)
+	assert.Len(userLists.Blocked, 1)
+	assert.Equal("1", userLists.Blocked[0].EmployerID)
+	assert.Len(userLists.Allowed, 1)
+	assert.Equal("Яндекс", userLists.Allowed[0].EmployerName)
+
+	removed, err := lists.Remove(ctx, 1, "2")
+	assert.NoError(err)
+	assert.True(removed)
+
+	removed, err = lists.Remove(ctx, 1, "3")
+	assert.NoError(err)
+	assert.False(removed)
+
+	userLists, err = lists.GetByUser(ctx, 1)
+	assert.NoError(err)
+	assert.Empty(userLists.Allowed)
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"context"
+	errs "github.com/maxaizer/hh-parser/internal/domain/errors"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Feedback_ShouldKeepLatestMarkWithVacancySnapshot(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	vacancies := repositories.NewVacanciesRepository(dbCtx.DB)
+	feedback := repositories.NewFeedbackRepository(dbCtx.DB)
+
+	verdict := models.MatchVerdict{Score: 80, Confidence: models.ConfidenceHigh, Rationale: "есть питсы",
+		Summary: models.VacancySummary{Salary: "от 100 000 ₽", Stack: []string{"Go"}, WorkFormat: models.WorkFormatRemote}}
+	notified := models.NotifiedVacancyID{UserID: search.UserID, VacancyID: vacancy.ID, DescriptionHash: []byte("hash")}
+	sent := vacancy
+	sent.KeySkills = []string{"Go", "PostgreSQL"}
+	sent.Description = "Разрабатываем бэкенд…"
+	assert.NoError(vacancies.RecordAsSentToUser(ctx, notified, sent, verdict))
+
+	assert.NoError(feedback.Save(ctx, search.UserID, search.ID, vacancy.ID, true))
+	assert.NoError(feedback.Save(ctx, search.UserID, search.ID, vacancy.ID, false))
+
+	examples, err := feedback.GetRecentBySearch(ctx, search.ID, 5)
+	assert.NoError(err)
+	assert.Len(examples, 1)
+	assert.False(examples[0].Positive)
+	assert.Equal(vacancy.Name, examples[0].VacancyName)
+	assert.Equal(sent.KeySkills, examples[0].VacancySkills)
+	assert.Equal(sent.Description, examples[0].VacancyDescription)
+	assert.Equal(verdict.Score, examples[0].Verdict.Score)
+	assert.Equal(verdict.Rationale, examples[0].Verdict.Rationale)
+	assert.Equal(verdict.Summary, examples[0].Verdict.Summary)
+
+	removed, err := feedback.RemoveByUser(ctx, search.UserID)
+	assert.NoError(err)
+	assert.Equal(int64(1), removed)
+}
+
+func Test_Feedback_WhenVacancyWasNotSent_ShouldReturnError(t *testing.T) {
+
+	feedback := repositories.NewFeedbackRepository(dbCtx.DB)
+	err := feedback.Save(context.Background(), search.UserID, search.ID, "unknown", true)
+	assert.ErrorIs(t, err, errs.NotifiedVacancyNotFound)
+}
@@ -4,20 +4,30 @@ import (
 	"context"
 	"errors"
 	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/services"
 	"sync"
 	"time"
 )
 
 type mockVacanciesRetriever struct {
 	vacancies []models.Vacancy
+	failed    []services.VacancyError
+	//details are returned only by id, as for vacancies failed during search
+	details []models.Vacancy
 }
 
-func (m mockVacanciesRetriever) GetVacancies(search *models.JobSearch, dateFrom time.Time, page, pageSize int) ([]models.Vacancy, error) {
+func (m mockVacanciesRetriever) SplitSearch(_ context.Context, search *models.JobSearch,
+	dateFrom time.Time) ([]services.SearchWindow, error) {
+	return []services.SearchWindow{{DateFrom: dateFrom, Found: len(m.vacancies)}}, nil
+}
+
+func (m mockVacanciesRetriever) GetVacancies(_ context.Context, search *models.JobSearch, window services.SearchWindow,
+	page, pageSize int) (services.WindowPage, error) {
 	total := len(m.vacancies)
 
 	start := page * pageSize
 	if start >= total {
-		return []models.Vacancy{}, nil
+		return services.WindowPage{Found: total}, nil
 	}
 
 	end := start + pageSize
@@ -25,11 +35,15 @@ func (m mockVacanciesRetriever) GetVacancies(search *models.JobSearch, dateFrom
 		end = total
 	}
 
-	return m.vacancies[start:end], nil
+	var failed []services.VacancyError
+	if page == 0 {
+		failed = m.failed
+	}
+	return services.WindowPage{Vacancies: m.vacancies[start:end], Failed: failed, Found: total}, nil
 }
 
-func (m mockVacanciesRetriever) GetVacancy(ID string) (*models.Vacancy, error) {
-	for _, vacancy := range m.vacancies {
+func (m mockVacanciesRetriever) GetVacancy(_ context.Context, ID string) (*models.Vacancy, error) {
+	for _, vacancy := range append(m.vacancies, m.details...) {
 		if vacancy.ID == ID {
 			return &vacancy, nil
 		}
@@ -46,12 +60,44 @@ type mockAiService struct {
 	}
 }
 
-func (m *mockAiService) DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, vacancy models.Vacancy) (bool, error) {
+func (m *mockAiService) DoesVacancyMatchSearch(ctx context.Context, search models.JobSearch, prompt services.MatchPrompt,
+	vacancy models.Vacancy) (models.MatchVerdict, error) {
 	time.Sleep(m.responseTime)
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	res := m.responsesQueue[0]
 	m.responsesQueue = m.responsesQueue[1:]
-	return res.result, res.err
+	return verdictFrom(res.result), res.err
+}
+
+func (m *mockAiService) DoVacanciesMatchSearch(ctx context.Context, search models.JobSearch, prompt services.MatchPrompt,
+	vacancies []models.Vacancy) (map[string]models.MatchVerdict, map[string]error) {
+
+	verdicts := make(map[string]models.MatchVerdict)
+	failures := make(map[string]error)
+	for _, vacancy := range vacancies {
+		verdict, err := m.DoesVacancyMatchSearch(ctx, search, prompt, vacancy)
+		if err != nil {
+			failures[vacancy.ID] = err
+			continue
+		}
+		verdicts[vacancy.ID] = verdict
+	}
+	return verdicts, failures
+}
+
+func (m *mockAiService) Model() string {
+	return "mock-model"
+}
+
+func (m *mockAiService) MatchPrompt(ctx context.Context, search models.JobSearch) services.MatchPrompt {
+	return services.MatchPrompt{Version: "mock-prompt"}
+}
+
+func verdictFrom(matched bool) models.MatchVerdict {
+	if matched {
+		return models.MatchVerdict{Score: 100, Confidence: models.ConfidenceHigh}
+	}
+	return models.MatchVerdict{Score: 0, Confidence: models.ConfidenceHigh}
 }
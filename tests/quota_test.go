@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/clients/ai"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_AIQuota_ShouldSurviveRestart(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	store := repositories.NewAIQuotaRepository(dbCtx.DB)
+
+	quota := ai.NewDayQuota("gemini", "gemini/flash", 2, store, time.UTC)
+	assert.NoError(quota.Acquire(context.Background()))
+	assert.NoError(quota.Acquire(context.Background()))
+
+	restarted := ai.NewDayQuota("gemini", "gemini/flash", 2, store, time.UTC)
+	assert.ErrorIs(restarted.Acquire(context.Background()), ai.ErrQuotaExhausted)
+
+	acquired, err := store.TryAcquire(context.Background(), "gemini/flash", "2000-01-01", 2)
+	assert.NoError(err)
+	assert.True(acquired)
+}
+
+func Test_ParkedVacancy_ShouldBeSkippedUntilNextWindow(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	vacancies := repositories.NewVacanciesRepository(dbCtx.DB)
+
+	assert.NoError(vacancies.ParkForAnalysis(ctx, search.ID, "1", "quota", time.Now().Add(time.Hour)))
+	assert.NoError(vacancies.ParkForAnalysis(ctx, search.ID, "2", "quota", time.Now().Add(-time.Minute)))
+
+	failed, err := vacancies.GetFailedToAnalyze(ctx)
+	assert.NoError(err)
+	assert.Len(failed, 1)
+	assert.Equal("2", failed[0].VacancyID)
+	assert.Equal(0, failed[0].Attempts)
+
+	removed, err := vacancies.RemoveFailedToAnalyze(ctx, 3, time.Now().Add(time.Second))
+	assert.NoError(err)
+	assert.Equal(int64(1), removed)
+
+	assert.NoError(vacancies.ParkForAnalysis(ctx, search.ID, "1", "quota", time.Now().Add(-time.Minute)))
+	failed, err = vacancies.GetFailedToAnalyze(ctx)
+	assert.NoError(err)
+	assert.Len(failed, 1)
+	assert.Equal("1", failed[0].VacancyID)
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_Regions_Search_ShouldFindByPrefixAndTypos(t *testing.T) {
+
+	assert := assert.New(t)
+
+	added := []models.Region{
+		{ID: "9001", ParentID: "113", Name: "Новосибирская область", Level: 1},
+		{ID: "9002", ParentID: "9001", Name: "Новосибирск", Level: 2},
+		{ID: "9003", ParentID: "9001", Name: "Бердск", Level: 2},
+	}
+	for i := range added {
+		added[i].NormalizedName = models.NormalizeRegionName(added[i].Name)
+	}
+	assert.NoError(dbCtx.DB.Create(&added).Error)
+	defer dbCtx.DB.Delete(&models.Region{}, "id IN ?", lo.Map(added, func(r models.Region, _ int) string { return r.ID }))
+
+	ctx := context.Background()
+	regions := repositories.NewRegionsRepository(dbCtx.DB)
+
+	found, err := regions.Search(ctx, "Новосиб", 5)
+	assert.NoError(err)
+	assert.Equal([]string{"Новосибирская область, Россия", "Россия", "Новосибирск, Новосибирская область"},
+		lo.Map(found, func(r models.Region, _ int) string { return r.FullName() }))
+
+	found, err = regions.Search(ctx, "Новасибирск", 5)
+	assert.NoError(err)
+	assert.NotEmpty(found)
+	assert.Equal("9002", found[0].ID)
+	assert.Equal("9001", found[1].ID, "area containing the best match should be suggested")
+
+	found, err = regions.Search(ctx, "Москва", 1)
+	assert.NoError(err)
+	assert.Len(found, 1)
+	assert.Equal("1", found[0].ID)
+
+	found, err = regions.Search(ctx, "Лондон", 5)
+	assert.NoError(err)
+	assert.Empty(found)
+
+	region, err := regions.GetByID(ctx, "9002")
+	assert.NoError(err)
+	if assert.NotNil(region) {
+		assert.Equal("Новосибирск, Новосибирская область", region.FullName())
+	}
+
+	region, err = regions.GetByID(ctx, "0")
+	assert.NoError(err)
+	assert.Nil(region)
+
+	cached := repositories.NewCachedRegions(regions)
+	found, err = cached.Search(ctx, "Новосиб", 5)
+	assert.NoError(err)
+	cachedFound, err := cached.Search(ctx, "новосиб", 5)
+	assert.NoError(err)
+	assert.Equal(found, cachedFound)
+}
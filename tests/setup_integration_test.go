@@ -2,6 +2,7 @@ package tests
 
 import (
 	"context"
+	"github.com/maxaizer/hh-parser/internal/clients/hh"
 	"github.com/maxaizer/hh-parser/internal/config"
 	"github.com/maxaizer/hh-parser/internal/repositories"
 	log "github.com/sirupsen/logrus"
@@ -14,6 +15,7 @@ var dbCtx *repositories.DbContext
 func upEnvironment() {
 
 	os.Setenv("DB_CONNECTION_STRING", "testdatabase.db")
+	os.Setenv("HH_USER_AGENT", "hh-parser-test/1.0 (test@example.com)")
 	cfg := config.Get()
 
 	var err error
@@ -22,7 +24,9 @@ func upEnvironment() {
 		log.Fatalf("could not create db context: %s", err)
 	}
 
-	err = dbCtx.Migrate()
+	hhClient := hh.NewClient()
+	hhClient.SetUserAgent(cfg.HhUserAgent)
+	err = dbCtx.Migrate(hhClient)
 	if err != nil {
 		log.Fatalf("could not migrate db: %s", err)
 	}
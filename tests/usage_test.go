@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"context"
+	"github.com/maxaizer/hh-parser/internal/domain/models"
+	"github.com/maxaizer/hh-parser/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_Usage_ShouldAccumulatePerDayUserAndSearch(t *testing.T) {
+
+	assert := assert.New(t)
+	defer clearDb()
+
+	ctx := context.Background()
+	usage := repositories.NewUsageRepository(dbCtx.DB)
+
+	today := models.UsageDay(time.Now())
+	yesterday := models.UsageDay(time.Now().AddDate(0, 0, -1))
+
+	assert.NoError(usage.Add(ctx, models.AIUsage{Day: today, UserID: 1, SearchID: 1, Requests: 1, PromptTokens: 100, CandidateTokens: 10}))
+	assert.NoError(usage.Add(ctx, models.AIUsage{Day: today, UserID: 1, SearchID: 1, Requests: 1, PromptTokens: 50, CandidateTokens: 5}))
+	assert.NoError(usage.Add(ctx, models.AIUsage{Day: today, UserID: 1, SearchID: 2, Requests: 1, PromptTokens: 20, CandidateTokens: 2}))
+	assert.NoError(usage.Add(ctx, models.AIUsage{Day: yesterday, UserID: 1, SearchID: 1, Requests: 1, PromptTokens: 1000}))
+	assert.NoError(usage.Add(ctx, models.AIUsage{Day: today, UserID: 2, SearchID: 3, Requests: 1, PromptTokens: 7}))
+
+	tokens, err := usage.GetUserTokens(ctx, 1, today)
+	assert.NoError(err)
+	assert.Equal(int64(187), tokens)
+
+	tokens, err = usage.GetUserTokens(ctx, 3, today)
+	assert.NoError(err)
+	assert.Zero(tokens)
+
+	report, err := usage.GetReport(ctx, today)
+	assert.NoError(err)
+	assert.Len(report, 3)
+	assert.Equal(int64(2), report[0].Requests)
+	assert.Equal(int64(165), report[0].TotalTokens())
+	assert.Equal(2, report[1].SearchID)
+	assert.Equal(int64(2), report[2].UserID)
+
+	report, err = usage.GetReport(ctx, yesterday)
+	assert.NoError(err)
+	assert.Equal(int64(1165), report[0].TotalTokens())
+}